@@ -22,6 +22,8 @@ var (
 		manifestsDir   string
 		destinationDir string
 		pullSecretFile string
+		// containerRuntimeDestDir is optional; when set the container runtime machineconfigs are also written there.
+		containerRuntimeDestDir string
 	}
 )
 
@@ -30,6 +32,7 @@ func init() {
 	bootstrapCmd.PersistentFlags().StringVar(&bootstrapOpts.destinationDir, "dest-dir", "", "The destination dir where MCC writes the generated machineconfigs and machineconfigpools.")
 	bootstrapCmd.PersistentFlags().StringVar(&bootstrapOpts.manifestsDir, "manifest-dir", "", "The dir where MCC reads the controllerconfig, machineconfigpools and user-defined machineconfigs.")
 	bootstrapCmd.PersistentFlags().StringVar(&bootstrapOpts.pullSecretFile, "pull-secret", "", "The pull secret file.")
+	bootstrapCmd.PersistentFlags().StringVar(&bootstrapOpts.containerRuntimeDestDir, "container-runtime-dest-dir", "", "Optional dir where MCC also writes the machineconfigs rendered from image configs and containerruntimeconfigs, for offline validation.")
 }
 
 func runbootstrapCmd(_ *cobra.Command, _ []string) {
@@ -43,7 +46,9 @@ func runbootstrapCmd(_ *cobra.Command, _ []string) {
 		klog.Fatalf("--dest-dir or --manifest-dir not set")
 	}
 
-	if err := bootstrap.New(rootOpts.templates, bootstrapOpts.manifestsDir, bootstrapOpts.pullSecretFile).Run(bootstrapOpts.destinationDir); err != nil {
+	if err := bootstrap.New(rootOpts.templates, bootstrapOpts.manifestsDir, bootstrapOpts.pullSecretFile).
+		WithContainerRuntimeDestDir(bootstrapOpts.containerRuntimeDestDir).
+		Run(bootstrapOpts.destinationDir); err != nil {
 		klog.Fatalf("error running MCC[BOOTSTRAP]: %v", err)
 	}
 }
@@ -39,6 +39,8 @@ type Bootstrap struct {
 	manifestDir string
 	// pull secret file
 	pullSecretFile string
+	// optional dir where the container runtime machineconfigs are written for offline validation.
+	containerRuntimeDestDir string
 }
 
 // New returns controller for bootstrap
@@ -50,6 +52,13 @@ func New(templatesDir, manifestDir, pullSecretFile string) *Bootstrap {
 	}
 }
 
+// WithContainerRuntimeDestDir sets the dir where the MachineConfigs rendered from the image configs and the
+// ContainerRuntimeConfigs are written, so they can be validated offline. Nothing extra is written when dir is empty.
+func (b *Bootstrap) WithContainerRuntimeDestDir(dir string) *Bootstrap {
+	b.containerRuntimeDestDir = dir
+	return b
+}
+
 // Run runs boostrap for Machine Config Controller
 // It writes all the assets to destDir
 // nolint:gocyclo
@@ -74,8 +83,9 @@ func (b *Bootstrap) Run(destDir string) error {
 	apioperatorsv1alpha1.Install(scheme)
 	apicfgv1.Install(scheme)
 	apicfgv1alpha1.Install(scheme)
+	corev1.AddToScheme(scheme)
 	codecFactory := serializer.NewCodecFactory(scheme)
-	decoder := codecFactory.UniversalDecoder(mcfgv1.GroupVersion, apioperatorsv1alpha1.GroupVersion, apicfgv1.GroupVersion, apicfgv1alpha1.GroupVersion)
+	decoder := codecFactory.UniversalDecoder(mcfgv1.GroupVersion, apioperatorsv1alpha1.GroupVersion, apicfgv1.GroupVersion, apicfgv1alpha1.GroupVersion, corev1.SchemeGroupVersion)
 
 	var (
 		cconfig              *mcfgv1.ControllerConfig
@@ -92,6 +102,7 @@ func (b *Bootstrap) Run(destDir string) error {
 		imagePolicies        []*apicfgv1alpha1.ImagePolicy
 		imgCfg               *apicfgv1.Image
 		apiServer            *apicfgv1.APIServer
+		installConfig        *corev1.ConfigMap
 	)
 	for _, info := range infos {
 		if info.IsDir() {
@@ -155,6 +166,10 @@ func (b *Bootstrap) Run(destDir string) error {
 				if obj.GetName() == ctrlcommon.APIServerInstanceName {
 					apiServer = obj
 				}
+			case *corev1.ConfigMap:
+				if obj.GetNamespace() == ctrlcommon.InstallConfigConfigMapNamespace && obj.GetName() == ctrlcommon.InstallConfigConfigMapName {
+					installConfig = obj
+				}
 			default:
 				klog.Infof("skipping %q [%d] manifest because of unhandled %T", file.Name(), idx+1, obji)
 			}
@@ -183,7 +198,15 @@ func (b *Bootstrap) Run(destDir string) error {
 
 	configs = append(configs, iconfigs...)
 
-	rconfigs, err := containerruntimeconfig.RunImageBootstrap(b.templatesDir, cconfig, pools, icspRules, idmsRules, itmsRules, imgCfg, clusterImagePolicies, imagePolicies, fgAccess)
+	// The nodes do not exist yet, the architecture of their pools comes from the install config
+	var poolArchs map[string]string
+	if installConfig != nil {
+		poolArchs, err = containerruntimeconfig.GetInstallConfigPoolArchitectures(installConfig)
+		if err != nil {
+			return err
+		}
+	}
+	rconfigs, err := containerruntimeconfig.RunImageBootstrap(b.templatesDir, cconfig, pools, poolArchs, icspRules, idmsRules, itmsRules, imgCfg, clusterImagePolicies, imagePolicies, fgAccess)
 	if err != nil {
 		return err
 	}
@@ -191,8 +214,9 @@ func (b *Bootstrap) Run(destDir string) error {
 
 	configs = append(configs, rconfigs...)
 
+	var containerRuntimeConfigs []*mcfgv1.MachineConfig
 	if len(crconfigs) > 0 {
-		containerRuntimeConfigs, err := containerruntimeconfig.RunContainerRuntimeBootstrap(b.templatesDir, crconfigs, cconfig, pools)
+		containerRuntimeConfigs, err = containerruntimeconfig.RunContainerRuntimeBootstrap(b.templatesDir, crconfigs, cconfig, pools)
 		if err != nil {
 			return err
 		}
@@ -200,6 +224,14 @@ func (b *Bootstrap) Run(destDir string) error {
 	}
 	klog.Infof("Successfully generated MachineConfigs from containerruntime.")
 
+	if b.containerRuntimeDestDir != "" {
+		crMCs := append(append([]*mcfgv1.MachineConfig{}, rconfigs...), containerRuntimeConfigs...)
+		if err := containerruntimeconfig.WriteBootstrapMachineConfigs(b.containerRuntimeDestDir, crMCs); err != nil {
+			return fmt.Errorf("could not write container runtime MachineConfigs to %s: %w", b.containerRuntimeDestDir, err)
+		}
+		klog.Infof("Wrote container runtime MachineConfigs to %s.", b.containerRuntimeDestDir)
+	}
+
 	if featureGate != nil {
 		featureConfigs, err := kubeletconfig.RunFeatureGateBootstrap(b.templatesDir, fgAccess, nodeConfig, cconfig, pools, apiServer)
 		if err != nil {
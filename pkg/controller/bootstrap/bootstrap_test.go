@@ -162,10 +162,21 @@ func TestBootstrapRun(t *testing.T) {
 	require.NoError(t, err)
 	defer os.RemoveAll(destDir)
 
-	bootstrap := New("../../../templates", "testdata/bootstrap", "testdata/bootstrap/machineconfigcontroller-pull-secret")
+	containerRuntimeDestDir := filepath.Join(destDir, "container-runtime")
+	bootstrap := New("../../../templates", "testdata/bootstrap", "testdata/bootstrap/machineconfigcontroller-pull-secret").
+		WithContainerRuntimeDestDir(containerRuntimeDestDir)
 	err = bootstrap.Run(destDir)
 	require.NoError(t, err)
 
+	// The registries MachineConfigs rendered from the testdata ImageContentSourcePolicy are written for offline validation
+	for _, name := range []string{"99-master-generated-registries", "99-worker-generated-registries"} {
+		mcBytes, err := os.ReadFile(filepath.Join(containerRuntimeDestDir, name+".yaml"))
+		require.NoError(t, err)
+		mc, err := mcoResourceRead.ReadMachineConfigV1(mcBytes)
+		require.NoError(t, err)
+		assert.Equal(t, name, mc.Name)
+	}
+
 	for _, poolName := range []string{"master", "worker"} {
 		t.Run(poolName, func(t *testing.T) {
 			paths, err := filepath.Glob(filepath.Join(destDir, "machine-configs", fmt.Sprintf("rendered-%s-*.yaml", poolName)))
@@ -197,6 +208,12 @@ func TestBootstrapRun(t *testing.T) {
 			assert.Contains(t, string(contents), "insecure-reg-2.io")
 			assert.Contains(t, string(contents), "blocked-reg.io")
 			assert.NotContains(t, string(contents), "release-registry.product.example.org")
+			// The architecture of the pools comes from the testdata install config, only the worker nodes run on arm64
+			if poolName == "worker" {
+				assert.Contains(t, string(contents), "arm64-mirror.example.com/ocp")
+			} else {
+				assert.NotContains(t, string(contents), "arm64-mirror.example.com/ocp")
+			}
 		})
 	}
 }
@@ -31,6 +31,34 @@ const (
 	// MCNameSuffixAnnotationKey is used to keep track of the machine config name associated with a CR
 	MCNameSuffixAnnotationKey = "machineconfiguration.openshift.io/mc-name-suffix"
 
+	// ContainerRuntimeConfigDryRunAnnotationKey is set to "true" on a ContainerRuntimeConfig to preview the MachineConfigs it renders without applying them
+	ContainerRuntimeConfigDryRunAnnotationKey = "machineconfiguration.openshift.io/ctrcfg-dry-run"
+
+	// ContainerRuntimeConfigAllowMasterChangesAnnotationKey must be set to "true" on a ContainerRuntimeConfig selecting the master pool to acknowledge that it changes the container runtime of the control plane nodes
+	ContainerRuntimeConfigAllowMasterChangesAnnotationKey = "machineconfiguration.openshift.io/ctrcfg-allow-master-changes"
+
+	// ContainerRuntimeConfigGenerationAnnotationKey is set on the MachineConfig generated for a ContainerRuntimeConfig on the master pool to the generation it was rendered from, the ContainerRuntimeConfig keeps being applied without the acknowledgement until its generation changes
+	ContainerRuntimeConfigGenerationAnnotationKey = "machineconfiguration.openshift.io/ctrcfg-generation"
+
+	// ContainerRuntimeConfigInputsAnnotationKey is set on the MachineConfigs generated for a ContainerRuntimeConfig to the hash of their inputs that the generation of the ContainerRuntimeConfig does not track
+	ContainerRuntimeConfigInputsAnnotationKey = "machineconfiguration.openshift.io/ctrcfg-inputs-hash"
+
+	// ContainerRuntimeConfigForceRegenerateAnnotationKey is set on a ContainerRuntimeConfig to regenerate its MachineConfigs, a new value forces a new regeneration
+	ContainerRuntimeConfigForceRegenerateAnnotationKey = "machineconfiguration.openshift.io/ctrcfg-force-regenerate"
+
+	// MachineConfigPoolExternalLogRotationAnnotationKey is set to "true" on a MachineConfigPool whose nodes rotate the container logs with an external tool
+	MachineConfigPoolExternalLogRotationAnnotationKey = "machineconfiguration.openshift.io/external-log-rotation"
+
+	// InstallConfigConfigMapNamespace and InstallConfigConfigMapName identify the ConfigMap holding the install config of the cluster
+	InstallConfigConfigMapNamespace = "kube-system"
+	InstallConfigConfigMapName      = "cluster-config-v1"
+
+	// MirrorSetArchitectureAnnotationKey is set on an ImageContentSourcePolicy, ImageDigestMirrorSet or ImageTagMirrorSet to only configure its mirrors on the pools of that architecture, e.g. arm64
+	MirrorSetArchitectureAnnotationKey = "machineconfiguration.openshift.io/mirror-architecture"
+
+	// ContainerRuntimeConfigSingleCRIODropinAnnotationKey is set to "true" on a ContainerRuntimeConfig to render all of its CRI-O settings in a single drop-in file instead of one file per setting
+	ContainerRuntimeConfigSingleCRIODropinAnnotationKey = "machineconfiguration.openshift.io/ctrcfg-single-crio-dropin"
+
 	// MaxMCNameSuffix is the maximum value of the name suffix of the machine config associated with kubeletconfig and containerruntime objects
 	MaxMCNameSuffix int = 9
 
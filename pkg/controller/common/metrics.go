@@ -44,6 +44,36 @@ var (
 			Name: "mcc_sub_controller_state",
 			Help: "state of sub-controllers in the MCC",
 		}, []string{"subcontroller", "state", "object"})
+	// MCCContainerRuntimeConfigUnreconciled logs since when the latest generation of a ContainerRuntimeConfig has been waiting to be reconciled
+	MCCContainerRuntimeConfigUnreconciled = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "mcc_ctrcfg_unreconciled_since",
+			Help: "unix timestamp since when the latest generation of a ContainerRuntimeConfig has not been reconciled, 0 if it is reconciled",
+		}, []string{"ctrcfg"})
+	// MCCContainerRuntimeConfigSyncFailures counts the times a ContainerRuntimeConfig was dropped out of the queue after failing to sync maxRetries times
+	MCCContainerRuntimeConfigSyncFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mcc_ctrcfg_sync_failures_total",
+			Help: "number of times a ContainerRuntimeConfig was dropped out of the queue after repeatedly failing to sync",
+		}, []string{"ctrcfg"})
+	// MCCContainerRuntimeConfigFailing logs whether the last sync of a ContainerRuntimeConfig failed
+	MCCContainerRuntimeConfigFailing = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "mcc_ctrcfg_failing",
+			Help: "1 if the last sync of a ContainerRuntimeConfig failed, 0 otherwise",
+		}, []string{"ctrcfg"})
+	// MCCContainerRuntimeConfigSyncs counts the successful syncs of a ContainerRuntimeConfig, by whether they wrote its MachineConfigs or found them up to date
+	MCCContainerRuntimeConfigSyncs = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mcc_ctrcfg_syncs_total",
+			Help: "number of syncs of a ContainerRuntimeConfig, result is applied when its MachineConfigs were written and noop when they were already up to date",
+		}, []string{"ctrcfg", "result"})
+	// MCCImageConfigSyncs counts the successful syncs of the cluster Image config, by whether they wrote registries MachineConfigs or found them up to date
+	MCCImageConfigSyncs = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mcc_image_config_syncs_total",
+			Help: "number of syncs of the cluster Image config, result is applied when registries MachineConfigs were written and noop when they were already up to date",
+		}, []string{"result"})
 )
 
 func RegisterMCCMetrics() error {
@@ -52,6 +82,11 @@ func RegisterMCCMetrics() error {
 		MCCDrainErr,
 		MCCPoolAlert,
 		MCCSubControllerState,
+		MCCContainerRuntimeConfigUnreconciled,
+		MCCContainerRuntimeConfigSyncFailures,
+		MCCContainerRuntimeConfigFailing,
+		MCCContainerRuntimeConfigSyncs,
+		MCCImageConfigSyncs,
 	})
 
 	if err != nil {
@@ -64,6 +99,11 @@ func RegisterMCCMetrics() error {
 	MCCDrainErr.WithLabelValues("initialize").Set(0)
 	MCCPoolAlert.WithLabelValues("initialize").Set(0)
 	MCCSubControllerState.WithLabelValues("initialize", "initialize", "initialize").Set(0)
+	MCCContainerRuntimeConfigUnreconciled.WithLabelValues("initialize").Set(0)
+	MCCContainerRuntimeConfigSyncFailures.WithLabelValues("initialize").Add(0)
+	MCCContainerRuntimeConfigFailing.WithLabelValues("initialize").Set(0)
+	MCCContainerRuntimeConfigSyncs.WithLabelValues("initialize", "initialize").Add(0)
+	MCCImageConfigSyncs.WithLabelValues("initialize").Add(0)
 
 	return nil
 }
@@ -1,74 +1,85 @@
 package containerruntimeconfig
 
 import (
+	"bytes"
 	"fmt"
+	"os"
+	"path/filepath"
 
+	ign3types "github.com/coreos/ignition/v2/config/v3_4/types"
 	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
 	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
 	"github.com/openshift/machine-config-operator/pkg/version"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
-	"k8s.io/klog/v2"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/runtime/serializer/json"
+	"sigs.k8s.io/yaml"
 )
 
 // RunContainerRuntimeBootstrap generates ignition configs at bootstrap
 func RunContainerRuntimeBootstrap(templateDir string, crconfigs []*mcfgv1.ContainerRuntimeConfig, controllerConfig *mcfgv1.ControllerConfig, mcpPools []*mcfgv1.MachineConfigPool) ([]*mcfgv1.MachineConfig, error) {
 	var res []*mcfgv1.MachineConfig
 	managedKeyExist := make(map[string]bool)
+	// The default configs of a role are rendered once for all the ContainerRuntimeConfigs
+	originalStorageIgns := make(map[string]*ign3types.File)
+	templateCRIOConfigs := make(map[string]*templateCRIOConfig)
 	for _, cfg := range crconfigs {
 		if err := validateUserContainerRuntimeConfig(cfg); err != nil {
 			return nil, err
 		}
-		// use selector since label matching part of a ContaineRuntimeConfig is not handled during the bootstrap
-		selector, err := metav1.LabelSelectorAsSelector(cfg.Spec.MachineConfigPoolSelector)
-		if err != nil {
-			return nil, fmt.Errorf("invalid label selector: %w", err)
-		}
 		for _, pool := range mcpPools {
-			// If a pool with a nil or empty selector creeps in, it should match nothing, not everything.
-			// skip the pool if no matched label for containerruntime config
-			if selector.Empty() || !selector.Matches(labels.Set(pool.Labels)) {
+			// skip the pool if the containerruntime config does not apply to it
+			selected, err := selectsPool(cfg, pool)
+			if err != nil {
+				return nil, err
+			}
+			if !selected {
 				continue
 			}
 			role := pool.Name
-			// Generate the original ContainerRuntimeConfig
-			originalStorageIgn, _, _, err := generateOriginalContainerRuntimeConfigs(templateDir, controllerConfig, role)
-			if err != nil {
-				return nil, fmt.Errorf("could not generate origin ContainerRuntime Configs: %w", err)
-			}
-
-			var configFileList []generatedConfigFile
-			ctrcfg := cfg.Spec.ContainerRuntimeConfig
-			if ctrcfg.OverlaySize != nil && !ctrcfg.OverlaySize.IsZero() {
-				storageTOML, err := mergeConfigChanges(originalStorageIgn, cfg, updateStorageConfig)
+			templateCRIOConfig, ok := templateCRIOConfigs[role]
+			if !ok {
+				originalStorageIgn, crioConfig, err := generateOriginalStorageAndCRIOConfigs(templateDir, controllerConfig, role)
 				if err != nil {
-					klog.V(2).Infoln(cfg, err, "error merging user changes to storage.conf: %v", err)
-				} else {
-					configFileList = append(configFileList, generatedConfigFile{filePath: storageConfigPath, data: storageTOML})
+					return nil, fmt.Errorf("could not generate origin ContainerRuntime Configs: %w", err)
 				}
+				originalStorageIgns[role] = originalStorageIgn
+				templateCRIOConfigs[role] = crioConfig
+				templateCRIOConfig = crioConfig
+			}
+			if err := validateDefaultRuntimeForRole(templateCRIOConfig, role, cfg); err != nil {
+				return nil, err
 			}
-			// Create the cri-o drop-in files
-			if ctrcfg.LogLevel != "" || ctrcfg.PidsLimit != nil || (ctrcfg.LogSizeMax != nil && !ctrcfg.LogSizeMax.IsZero()) || ctrcfg.DefaultRuntime != mcfgv1.ContainerRuntimeDefaultRuntimeEmpty {
-				crioFileConfigs := createCRIODropinFiles(cfg)
-				configFileList = append(configFileList, crioFileConfigs...)
+			// Every ContainerRuntimeConfig setting the overlaySize of the pool renders the value of the most specific one
+			overlaySizeCfg, _, err := selectOverlaySizeConfig(pool, mcpPools, crconfigs)
+			if err != nil {
+				return nil, err
+			}
+			configFileList, err := generateContainerRuntimeConfigFiles(originalStorageIgns[role], cfg, overlaySizeCfg)
+			if err != nil {
+				return nil, fmt.Errorf("could not generate ContainerRuntime config files: %w", err)
 			}
 
-			ctrRuntimeConfigIgn := createNewIgnition(configFileList)
+			ctrRuntimeConfigIgn, err := createNewIgnition(configFileList)
+			if err != nil {
+				return nil, fmt.Errorf("could not create container runtime Ignition config: %w", err)
+			}
 			managedKey, err := generateBootstrapManagedKeyContainerConfig(pool, managedKeyExist)
 			if err != nil {
 				return nil, fmt.Errorf("could not marshal container runtime ignition: %w", err)
 			}
 			// the first managed key value 99-poolname-generated-containerruntime does not have a suffix
 			// set "" as suffix annotation to the containerruntime config object
-			cfg.SetAnnotations(map[string]string{
-				ctrlcommon.MCNameSuffixAnnotationKey: "",
-			})
+			metav1.SetMetaDataAnnotation(&cfg.ObjectMeta, ctrlcommon.MCNameSuffixAnnotationKey, "")
 			mc, err := ctrlcommon.MachineConfigFromIgnConfig(role, managedKey, ctrRuntimeConfigIgn)
 			if err != nil {
 				return nil, fmt.Errorf("could not create MachineConfig from new Ignition config: %w", err)
 			}
 			mc.SetAnnotations(map[string]string{
 				ctrlcommon.GeneratedByControllerVersionAnnotationKey: version.Hash,
+				ctrlcommon.ContainerRuntimeConfigInputsAnnotationKey: getContainerRuntimeConfigInputsHash(controllerConfig, overlaySizeCfg, mc.Spec.Config.Raw),
 			})
 			oref := metav1.OwnerReference{
 				APIVersion: controllerKind.GroupVersion().String(),
@@ -98,3 +109,72 @@ func generateBootstrapManagedKeyContainerConfig(pool *mcfgv1.MachineConfigPool,
 	managedKeyExist[pool.Name] = true
 	return managedKey, nil
 }
+
+// WriteBootstrapMachineConfigs writes the MachineConfigs generated by RunImageBootstrap and RunContainerRuntimeBootstrap
+// to destDir, one <name>.yaml file each, serialized like the MachineConfigs written by the bootstrap controller. This
+// lets the rendered configs be validated offline, before they are merged into the rendered MachineConfigs.
+func WriteBootstrapMachineConfigs(destDir string, mcs []*mcfgv1.MachineConfig) error {
+	scheme := runtime.NewScheme()
+	if err := mcfgv1.Install(scheme); err != nil {
+		return err
+	}
+	yamlSerializer := json.NewYAMLSerializer(json.DefaultMetaFactory, scheme, scheme)
+	encoder := serializer.NewCodecFactory(scheme).EncoderForVersion(yamlSerializer, mcfgv1.GroupVersion)
+
+	if err := os.MkdirAll(destDir, 0o764); err != nil {
+		return err
+	}
+	for _, mc := range mcs {
+		buf := bytes.Buffer{}
+		if err := encoder.Encode(mc, &buf); err != nil {
+			return fmt.Errorf("could not encode MachineConfig %s: %w", mc.Name, err)
+		}
+		// Disable gosec here to avoid throwing
+		// G306: Expect WriteFile permissions to be 0600 or less
+		// #nosec
+		if err := os.WriteFile(filepath.Join(destDir, fmt.Sprintf("%s.yaml", mc.Name)), buf.Bytes(), 0o664); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// installConfigKey is the key of the install config in the ConfigMap the installer stores it in
+const installConfigKey = "install-config"
+
+// installConfigMachinePools is the part of the install config describing the nodes of the control plane and compute
+// pools
+type installConfigMachinePools struct {
+	ControlPlane *installConfigMachinePool  `json:"controlPlane,omitempty"`
+	Compute      []installConfigMachinePool `json:"compute,omitempty"`
+}
+
+// installConfigMachinePool is a machine pool of the install config. Its architecture has the GOARCH format the nodes
+// report in their status.
+type installConfigMachinePool struct {
+	Name         string `json:"name"`
+	Architecture string `json:"architecture,omitempty"`
+}
+
+// GetInstallConfigPoolArchitectures returns the architecture of the nodes of the master and worker pools, as set in the
+// install config held by the installConfig ConfigMap. The pools whose architecture is not set are left out.
+func GetInstallConfigPoolArchitectures(installConfig *corev1.ConfigMap) (map[string]string, error) {
+	data, ok := installConfig.Data[installConfigKey]
+	if !ok {
+		return nil, fmt.Errorf("ConfigMap %s/%s has no %s key", installConfig.Namespace, installConfig.Name, installConfigKey)
+	}
+	var pools installConfigMachinePools
+	if err := yaml.Unmarshal([]byte(data), &pools); err != nil {
+		return nil, fmt.Errorf("could not parse the install config of ConfigMap %s/%s: %w", installConfig.Namespace, installConfig.Name, err)
+	}
+	poolArchs := make(map[string]string)
+	if pools.ControlPlane != nil && pools.ControlPlane.Architecture != "" {
+		poolArchs[ctrlcommon.MachineConfigPoolMaster] = pools.ControlPlane.Architecture
+	}
+	for _, pool := range pools.Compute {
+		if pool.Name == ctrlcommon.MachineConfigPoolWorker && pool.Architecture != "" {
+			poolArchs[ctrlcommon.MachineConfigPoolWorker] = pool.Architecture
+		}
+	}
+	return poolArchs, nil
+}
@@ -1,13 +1,20 @@
 package containerruntimeconfig
 
 import (
+	"context"
+	"os"
+	"path/filepath"
 	"testing"
 
 	apicfgv1 "github.com/openshift/api/config/v1"
 	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
+	mcoResourceRead "github.com/openshift/machine-config-operator/lib/resourceread"
 	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
 	"github.com/openshift/machine-config-operator/test/helpers"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -26,6 +33,7 @@ func TestAddKubeletCfgAfterBootstrapKubeletCfg(t *testing.T) {
 
 			f.ccLister = append(f.ccLister, cc)
 			f.mcpLister = append(f.mcpLister, pools[0])
+			acknowledgeMasterChanges(ctrcfg)
 			f.mccrLister = append(f.mccrLister, ctrcfg)
 			f.objects = append(f.objects, ctrcfg)
 
@@ -36,6 +44,7 @@ func TestAddKubeletCfgAfterBootstrapKubeletCfg(t *testing.T) {
 			// add ctrcfg1 after bootstrap
 			ctrcfg1 := newContainerRuntimeConfig("log-level-master", &mcfgv1.ContainerRuntimeConfiguration{LogLevel: "debug"}, metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/master", ""))
 
+			acknowledgeMasterChanges(ctrcfg1)
 			f.mccrLister = append(f.mccrLister, ctrcfg1)
 			f.objects = append(f.objects, ctrcfg1)
 			c := f.newController()
@@ -55,3 +64,136 @@ func TestAddKubeletCfgAfterBootstrapKubeletCfg(t *testing.T) {
 		})
 	}
 }
+
+// TestRunContainerRuntimeBootstrapMatchesSync ensures that a ContainerRuntimeConfig provided at install time renders the
+// same MachineConfig at bootstrap as the controller does once the cluster is up
+func TestRunContainerRuntimeBootstrapMatchesSync(t *testing.T) {
+	pidsLimit := int64(2048)
+	overlaySize := resource.MustParse("9G")
+
+	f := newFixture(t)
+	f.skipActionsValidation = true
+
+	cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+	pools := []*mcfgv1.MachineConfigPool{
+		helpers.NewMachineConfigPool("master", nil, helpers.MasterSelector, "v0"),
+		helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "v0"),
+	}
+	ctrcfg := newContainerRuntimeConfig("set-config", &mcfgv1.ContainerRuntimeConfiguration{PidsLimit: &pidsLimit, OverlaySize: &overlaySize},
+		metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/worker", ""))
+
+	mcs, err := RunContainerRuntimeBootstrap("../../../templates", []*mcfgv1.ContainerRuntimeConfig{ctrcfg.DeepCopy()}, cc, pools)
+	require.NoError(t, err)
+	require.Len(t, mcs, 1)
+
+	ignCfg, err := ctrlcommon.ParseAndConvertConfig(mcs[0].Spec.Config.Raw)
+	require.NoError(t, err)
+	paths := make([]string, 0, len(ignCfg.Storage.Files))
+	for _, file := range ignCfg.Storage.Files {
+		paths = append(paths, file.Path)
+	}
+	assert.ElementsMatch(t, []string{storageConfigPath, crioDropInFilePathPidsLimit}, paths)
+
+	f.ccLister = append(f.ccLister, cc)
+	f.mcpLister = append(f.mcpLister, pools...)
+	f.mccrLister = append(f.mccrLister, ctrcfg)
+	f.objects = append(f.objects, ctrcfg)
+	c := f.newController()
+	require.NoError(t, c.syncHandler(getKey(ctrcfg, t)))
+
+	synced, err := c.client.MachineconfigurationV1().MachineConfigs().Get(context.TODO(), mcs[0].Name, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, synced.Labels, mcs[0].Labels)
+	assert.Equal(t, synced.Annotations, mcs[0].Annotations)
+	assert.Equal(t, synced.Spec, mcs[0].Spec)
+}
+
+// TestWriteBootstrapMachineConfigs ensures that the MachineConfigs rendered at bootstrap are written to the destination
+// dir and read back unchanged
+func TestWriteBootstrapMachineConfigs(t *testing.T) {
+	pidsLimit := int64(2048)
+
+	cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+	pools := []*mcfgv1.MachineConfigPool{
+		helpers.NewMachineConfigPool("master", nil, helpers.MasterSelector, "v0"),
+		helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "v0"),
+	}
+	ctrcfg := newContainerRuntimeConfig("set-config", &mcfgv1.ContainerRuntimeConfiguration{PidsLimit: &pidsLimit},
+		metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/worker", ""))
+
+	mcs, err := RunContainerRuntimeBootstrap("../../../templates", []*mcfgv1.ContainerRuntimeConfig{ctrcfg}, cc, pools)
+	require.NoError(t, err)
+	require.Len(t, mcs, 1)
+
+	destDir := filepath.Join(t.TempDir(), "container-runtime")
+	require.NoError(t, WriteBootstrapMachineConfigs(destDir, mcs))
+
+	mcBytes, err := os.ReadFile(filepath.Join(destDir, mcs[0].Name+".yaml"))
+	require.NoError(t, err)
+	mc, err := mcoResourceRead.ReadMachineConfigV1(mcBytes)
+	require.NoError(t, err)
+	assert.Equal(t, mcs[0].Name, mc.Name)
+	assert.Equal(t, mcs[0].Labels, mc.Labels)
+	assert.Equal(t, mcs[0].Annotations, mc.Annotations)
+	assert.JSONEq(t, string(mcs[0].Spec.Config.Raw), string(mc.Spec.Config.Raw))
+}
+
+// TestGetInstallConfigPoolArchitectures ensures that the architecture of the master and worker pools is read from the
+// install config, and that the pools without an architecture are left out
+func TestGetInstallConfigPoolArchitectures(t *testing.T) {
+	tests := []struct {
+		name          string
+		installConfig string
+		expected      map[string]string
+		expectErr     bool
+	}{
+		{
+			name: "control plane and compute architectures",
+			installConfig: `apiVersion: v1
+controlPlane:
+  name: master
+  architecture: amd64
+compute:
+- name: worker
+  architecture: arm64
+- name: edge
+  architecture: s390x
+`,
+			expected: map[string]string{"master": "amd64", "worker": "arm64"},
+		},
+		{
+			name: "no architecture",
+			installConfig: `apiVersion: v1
+controlPlane:
+  name: master
+compute:
+- name: worker
+`,
+			expected: map[string]string{},
+		},
+		{
+			name:          "invalid install config",
+			installConfig: "compute: {",
+			expectErr:     true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cm := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: ctrlcommon.InstallConfigConfigMapNamespace, Name: ctrlcommon.InstallConfigConfigMapName},
+				Data:       map[string]string{installConfigKey: test.installConfig},
+			}
+			poolArchs, err := GetInstallConfigPoolArchitectures(cm)
+			if test.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, poolArchs)
+		})
+	}
+
+	_, err := GetInstallConfigPoolArchitectures(&corev1.ConfigMap{})
+	assert.Error(t, err, "a ConfigMap without an install config is rejected")
+}
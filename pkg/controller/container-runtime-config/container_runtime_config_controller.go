@@ -1,13 +1,19 @@
 package containerruntimeconfig
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"maps"
 	"reflect"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/BurntSushi/toml"
 	"github.com/clarketm/json"
 	signature "github.com/containers/image/v5/signature"
 	ign3types "github.com/coreos/ignition/v2/config/v3_4/types"
@@ -21,23 +27,28 @@ import (
 	cligolistersv1 "github.com/openshift/client-go/config/listers/config/v1"
 	cligolistersv1alpha1 "github.com/openshift/client-go/config/listers/config/v1alpha1"
 	runtimeutils "github.com/openshift/runtime-utils/pkg/registries"
+	"github.com/prometheus/client_golang/prometheus"
 
 	operatorinformersv1alpha1 "github.com/openshift/client-go/operator/informers/externalversions/operator/v1alpha1"
 
 	operatorlistersv1alpha1 "github.com/openshift/client-go/operator/listers/operator/v1alpha1"
 	"github.com/openshift/library-go/pkg/operator/configobserver/featuregates"
+	"golang.org/x/time/rate"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	kubeErrs "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/jsonmergepatch"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
+	coreinformersv1 "k8s.io/client-go/informers/core/v1"
 	clientset "k8s.io/client-go/kubernetes"
 	coreclientsetv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	corelisterv1 "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
@@ -56,14 +67,44 @@ import (
 )
 
 const (
-	// maxRetries is the number of times a containerruntimeconfig pool will be retried before it is dropped out of the queue.
+	// defaultMaxRetries is the number of times a containerruntimeconfig pool will be retried before it is dropped out of the queue.
 	// With the current rate-limiter in use (5ms*2^(maxRetries-1)) the following numbers represent the times
 	// a machineconfig pool is going to be requeued:
 	//
 	// 5ms, 10ms, 20ms, 40ms, 80ms, 160ms, 320ms, 640ms, 1.3s, 2.6s, 5.1s, 10.2s, 20.4s, 41s, 82s
-	maxRetries = 15
+	defaultMaxRetries = 15
+
+	// defaultRetryBaseDelay and defaultRetryMaxDelay are the delays of the first requeue of a failed key and the
+	// maximum delay it grows to, the same as the client-go default controller rate limiter
+	defaultRetryBaseDelay = 5 * time.Millisecond
+	defaultRetryMaxDelay  = 1000 * time.Second
 
 	builtInLabelKey = "machineconfiguration.openshift.io/mco-built-in"
+
+	// imageConfigDeletedKey is queued when the cluster Image config is deleted, to clean up the registries MachineConfigs
+	imageConfigDeletedKey = "openshift-config-deleted"
+
+	// releaseImagePendingRequeueDelay is how long the image config sync waits before checking again whether the
+	// ClusterVersion desired release image is set
+	releaseImagePendingRequeueDelay = 10 * time.Second
+
+	// throttledRequeueDelay is the minimum time a sync that failed because the API server is throttling requests
+	// waits before being retried, instead of the few milliseconds of the rate limiter for the first failures
+	throttledRequeueDelay = 30 * time.Second
+
+	// duplicatedMCRequeueDelay is how long a ContainerRuntimeConfig sync waits before retrying the clean up of the
+	// MachineConfigs generated by an older controller that were kept because a pool referenced them
+	duplicatedMCRequeueDelay = 1 * time.Minute
+
+	// syncResultApplied and syncResultNoop are the result label values of the sync counters, for syncs that wrote
+	// MachineConfigs and syncs that found them already up to date
+	syncResultApplied = "applied"
+	syncResultNoop    = "noop"
+
+	// ContainerRuntimeConfigPending designates a ContainerRuntimeConfig whose MachineConfigs have been applied but are
+	// not rolled out by all of the selected MachineConfigPools yet. The condition type field is a free-form string,
+	// so this does not need a matching constant in the API.
+	ContainerRuntimeConfigPending mcfgv1.ContainerRuntimeConfigStatusConditionType = "Pending"
 )
 
 var (
@@ -71,16 +112,48 @@ var (
 	controllerKind = mcfgv1.SchemeGroupVersion.WithKind("ContainerRuntimeConfig")
 )
 
-var updateBackoff = wait.Backoff{
+var defaultUpdateBackoff = wait.Backoff{
 	Steps:    5,
 	Duration: 100 * time.Millisecond,
 	Jitter:   1.0,
 }
 
+// RetryConfig holds the retry and backoff parameters used by the controller queues
+// and by the conflict retries on API updates. Zero values fall back to the defaults.
+type RetryConfig struct {
+	// MaxRetries is the number of times a key is requeued with rate limiting before it is dropped out of the queue
+	MaxRetries int
+	// BaseDelay is the delay before a failed key is requeued the first time, it doubles with every failure
+	BaseDelay time.Duration
+	// MaxDelay is the maximum delay before a failed key is requeued
+	MaxDelay time.Duration
+	// UpdateBackoff is the backoff used when retrying API updates on conflict
+	UpdateBackoff wait.Backoff
+}
+
+// newRetryRateLimiter returns the rate limiter of the controller queues: failed keys are requeued after a delay
+// growing exponentially from the base delay to the max delay of retryConfig, and all the requeues are limited to 10
+// per second with a burst of 100 like the client-go default controller rate limiter
+func newRetryRateLimiter(retryConfig RetryConfig) workqueue.TypedRateLimiter[string] {
+	baseDelay := defaultRetryBaseDelay
+	if retryConfig.BaseDelay > 0 {
+		baseDelay = retryConfig.BaseDelay
+	}
+	maxDelay := defaultRetryMaxDelay
+	if retryConfig.MaxDelay > 0 {
+		maxDelay = retryConfig.MaxDelay
+	}
+	return workqueue.NewTypedMaxOfRateLimiter(
+		workqueue.NewTypedItemExponentialFailureRateLimiter[string](baseDelay, maxDelay),
+		&workqueue.TypedBucketRateLimiter[string]{Limiter: rate.NewLimiter(rate.Limit(10), 100)},
+	)
+}
+
 // Controller defines the container runtime config controller.
 type Controller struct {
 	templatesDir string
 
+	kubeClient    clientset.Interface
 	client        mcfgclientset.Interface
 	configClient  configclientset.Interface
 	eventRecorder record.EventRecorder
@@ -121,10 +194,38 @@ type Controller struct {
 	clusterVersionLister       cligolistersv1.ClusterVersionLister
 	clusterVersionListerSynced cache.InformerSynced
 
+	nodeLister       corelisterv1.NodeLister
+	nodeListerSynced cache.InformerSynced
+
 	featureGateAccess featuregates.FeatureGateAccess
 
+	maxRetries    int
+	updateBackoff wait.Backoff
+
+	// marshalIgnition encodes the Ignition configs of the generated MachineConfigs, it is only replaced in tests
+	marshalIgnition func(interface{}) ([]byte, error)
+
 	queue    workqueue.TypedRateLimitingInterface[string]
 	imgQueue workqueue.TypedRateLimitingInterface[string]
+
+	// unreconciledSince tracks since when the latest generation of each ContainerRuntimeConfig
+	// has been waiting to be successfully reconciled
+	unreconciledSince     map[string]time.Time
+	unreconciledSinceLock sync.Mutex
+
+	// lastObservedImageSync records the last successful Image config sync
+	lastObservedImageSync     imageConfigSyncState
+	lastObservedImageSyncLock sync.Mutex
+}
+
+// imageConfigSyncState is what an Image config sync rendered the registries MachineConfigs from, and what it wrote
+type imageConfigSyncState struct {
+	// generation is the generation of the Image config
+	generation int64
+	// inputs is a summary of the other objects the registries were rendered from, see getImageConfigSyncInputs
+	inputs string
+	// mcHashes holds the hash of the Ignition config of each registries MachineConfig, by name
+	mcHashes map[string]string
 }
 
 // New returns a new container runtime config controller
@@ -139,10 +240,12 @@ func New(
 	configInformerFactory configinformers.SharedInformerFactory,
 	icspInformer operatorinformersv1alpha1.ImageContentSourcePolicyInformer,
 	clusterVersionInformer cligoinformersv1.ClusterVersionInformer,
+	nodeInformer coreinformersv1.NodeInformer,
 	kubeClient clientset.Interface,
 	mcfgClient mcfgclientset.Interface,
 	configClient configclientset.Interface,
 	featureGateAccess featuregates.FeatureGateAccess,
+	retryConfig RetryConfig,
 ) *Controller {
 	eventBroadcaster := record.NewBroadcaster()
 	eventBroadcaster.StartLogging(klog.Infof)
@@ -150,13 +253,24 @@ func New(
 
 	ctrl := &Controller{
 		templatesDir:  templatesDir,
+		kubeClient:    kubeClient,
 		client:        mcfgClient,
 		configClient:  configClient,
 		eventRecorder: ctrlcommon.NamespacedEventRecorder(eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "machineconfigcontroller-containerruntimeconfigcontroller"})),
 		queue: workqueue.NewTypedRateLimitingQueueWithConfig(
-			workqueue.DefaultTypedControllerRateLimiter[string](),
+			newRetryRateLimiter(retryConfig),
 			workqueue.TypedRateLimitingQueueConfig[string]{Name: "machineconfigcontroller-containerruntimeconfigcontroller"}),
-		imgQueue: workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[string]()),
+		imgQueue:          workqueue.NewTypedRateLimitingQueue(newRetryRateLimiter(retryConfig)),
+		unreconciledSince: make(map[string]time.Time),
+		maxRetries:        defaultMaxRetries,
+		updateBackoff:     defaultUpdateBackoff,
+		marshalIgnition:   json.Marshal,
+	}
+	if retryConfig.MaxRetries > 0 {
+		ctrl.maxRetries = retryConfig.MaxRetries
+	}
+	if retryConfig.UpdateBackoff.Steps > 0 {
+		ctrl.updateBackoff = retryConfig.UpdateBackoff
 	}
 
 	mcrInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
@@ -165,6 +279,14 @@ func New(
 		DeleteFunc: ctrl.deleteContainerRuntimeConfig,
 	})
 
+	mcpInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: ctrl.updateMachineConfigPool,
+	})
+
+	ccInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: ctrl.controllerConfigUpdated,
+	})
+
 	imgInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc:    ctrl.imageConfAdded,
 		UpdateFunc: ctrl.imageConfUpdated,
@@ -189,6 +311,12 @@ func New(
 		DeleteFunc: ctrl.itmsConfDeleted,
 	})
 
+	nodeInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    ctrl.nodeAdded,
+		UpdateFunc: ctrl.nodeUpdated,
+		DeleteFunc: ctrl.nodeDeleted,
+	})
+
 	ctrl.syncHandler = ctrl.syncContainerRuntimeConfig
 	ctrl.syncImgHandler = ctrl.syncImageConfig
 	ctrl.enqueueContainerRuntimeConfig = ctrl.enqueue
@@ -217,6 +345,9 @@ func New(
 	ctrl.clusterVersionLister = clusterVersionInformer.Lister()
 	ctrl.clusterVersionListerSynced = clusterVersionInformer.Informer().HasSynced
 
+	ctrl.nodeLister = nodeInformer.Lister()
+	ctrl.nodeListerSynced = nodeInformer.Informer().HasSynced
+
 	ctrl.featureGateAccess = featureGateAccess
 
 	ctrl.configInformerFactory = configInformerFactory
@@ -230,7 +361,8 @@ func (ctrl *Controller) Run(workers int, stopCh <-chan struct{}) {
 	defer ctrl.queue.ShutDown()
 	defer ctrl.imgQueue.ShutDown()
 	listerCaches := []cache.InformerSynced{ctrl.mcpListerSynced, ctrl.mccrListerSynced, ctrl.ccListerSynced,
-		ctrl.imgListerSynced, ctrl.icspListerSynced, ctrl.idmsListerSynced, ctrl.itmsListerSynced, ctrl.clusterVersionListerSynced}
+		ctrl.imgListerSynced, ctrl.icspListerSynced, ctrl.idmsListerSynced, ctrl.itmsListerSynced, ctrl.clusterVersionListerSynced,
+		ctrl.nodeListerSynced}
 
 	if ctrl.sigstoreAPIEnabled() {
 		ctrl.addImagePolicyObservers()
@@ -264,9 +396,32 @@ func ctrConfigTriggerObjectChange(old, new *mcfgv1.ContainerRuntimeConfig) bool
 	if !reflect.DeepEqual(old.Spec, new.Spec) {
 		return true
 	}
+	if isDryRun(old) != isDryRun(new) {
+		return true
+	}
+	if wantsSingleCRIODropin(old) != wantsSingleCRIODropin(new) {
+		return true
+	}
+	if old.GetAnnotations()[ctrlcommon.ContainerRuntimeConfigAllowMasterChangesAnnotationKey] != new.GetAnnotations()[ctrlcommon.ContainerRuntimeConfigAllowMasterChangesAnnotationKey] {
+		return true
+	}
+	if old.GetAnnotations()[ctrlcommon.ContainerRuntimeConfigForceRegenerateAnnotationKey] != new.GetAnnotations()[ctrlcommon.ContainerRuntimeConfigForceRegenerateAnnotationKey] {
+		return true
+	}
 	return false
 }
 
+// controllerConfigUpdated queues an Image config sync when the release image changes, e.g. on upgrades, as the
+// registry of the release payload must never be blocked in registries.conf and policy.json
+func (ctrl *Controller) controllerConfigUpdated(old, cur interface{}) {
+	oldCC := old.(*mcfgv1.ControllerConfig)
+	curCC := cur.(*mcfgv1.ControllerConfig)
+	if oldCC.Spec.ReleaseImage != curCC.Spec.ReleaseImage {
+		klog.V(4).Infof("Release image changed from %s to %s, syncing the Image config", oldCC.Spec.ReleaseImage, curCC.Spec.ReleaseImage)
+		ctrl.imgQueue.Add("openshift-config")
+	}
+}
+
 func (ctrl *Controller) imageConfAdded(_ interface{}) {
 	ctrl.imgQueue.Add("openshift-config")
 }
@@ -276,7 +431,7 @@ func (ctrl *Controller) imageConfUpdated(_, _ interface{}) {
 }
 
 func (ctrl *Controller) imageConfDeleted(_ interface{}) {
-	ctrl.imgQueue.Add("openshift-config")
+	ctrl.imgQueue.Add(imageConfigDeletedKey)
 }
 
 func (ctrl *Controller) icspConfAdded(_ interface{}) {
@@ -315,6 +470,26 @@ func (ctrl *Controller) itmsConfDeleted(_ interface{}) {
 	ctrl.imgQueue.Add("openshift-config")
 }
 
+// The architecture of the nodes of each built-in pool selects the mirror sets configured for it, see
+// getBuiltInPoolArchitectures. The Image config sync is a no-op when the architectures did not change.
+func (ctrl *Controller) nodeAdded(_ interface{}) {
+	ctrl.imgQueue.Add("openshift-config")
+}
+
+func (ctrl *Controller) nodeUpdated(old, cur interface{}) {
+	oldNode := old.(*corev1.Node)
+	curNode := cur.(*corev1.Node)
+	// Skip the frequent status updates, only the labels and the architecture of a node can change its pool architecture
+	if oldNode.Status.NodeInfo.Architecture == curNode.Status.NodeInfo.Architecture && maps.Equal(oldNode.Labels, curNode.Labels) {
+		return
+	}
+	ctrl.imgQueue.Add("openshift-config")
+}
+
+func (ctrl *Controller) nodeDeleted(_ interface{}) {
+	ctrl.imgQueue.Add("openshift-config")
+}
+
 func (ctrl *Controller) addImagePolicyObservers() {
 	ctrl.configInformerFactory.Config().V1alpha1().ClusterImagePolicies().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc:    ctrl.clusterImagePolicyAdded,
@@ -373,6 +548,38 @@ func (ctrl *Controller) updateContainerRuntimeConfig(oldObj, newObj interface{})
 	if ctrConfigTriggerObjectChange(oldCtrCfg, newCtrCfg) {
 		klog.V(4).Infof("Update ContainerRuntimeConfig %s", oldCtrCfg.Name)
 		ctrl.enqueueContainerRuntimeConfig(newCtrCfg)
+		ctrl.enqueueOtherOverlaySizeConfigs(oldCtrCfg)
+		ctrl.enqueueOtherOverlaySizeConfigs(newCtrCfg)
+	}
+}
+
+// updateMachineConfigPool queues the ContainerRuntimeConfigs waiting for the pool to roll out their MachineConfigs
+// when the pool reports a new rendered config, and the Image config when the nodes of the pool may have changed, as
+// their architecture selects the mirror sets of its registries.conf
+func (ctrl *Controller) updateMachineConfigPool(oldObj, newObj interface{}) {
+	oldPool := oldObj.(*mcfgv1.MachineConfigPool)
+	newPool := newObj.(*mcfgv1.MachineConfigPool)
+	if oldPool.Status.MachineCount != newPool.Status.MachineCount || !reflect.DeepEqual(oldPool.Spec.NodeSelector, newPool.Spec.NodeSelector) {
+		klog.V(4).Infof("Nodes of MachineConfigPool %s changed, syncing the Image config", newPool.Name)
+		ctrl.imgQueue.Add("openshift-config")
+	}
+	if oldPool.Status.Configuration.Name == newPool.Status.Configuration.Name {
+		return
+	}
+	ctrcfgs, err := ctrl.mccrLister.List(labels.Everything())
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("couldn't list ContainerRuntimeConfigs: %w", err))
+		return
+	}
+	for _, cfg := range ctrcfgs {
+		if !isRolloutPending(cfg) {
+			continue
+		}
+		if selected, err := selectsPool(cfg, newPool); err != nil || !selected {
+			continue
+		}
+		klog.V(4).Infof("MachineConfigPool %s updated, checking the rollout of ContainerRuntimeConfig %s", newPool.Name, cfg.Name)
+		ctrl.enqueueContainerRuntimeConfig(cfg)
 	}
 }
 
@@ -380,6 +587,25 @@ func (ctrl *Controller) addContainerRuntimeConfig(obj interface{}) {
 	cfg := obj.(*mcfgv1.ContainerRuntimeConfig)
 	klog.V(4).Infof("Adding ContainerRuntimeConfig %s", cfg.Name)
 	ctrl.enqueueContainerRuntimeConfig(cfg)
+	ctrl.enqueueOtherOverlaySizeConfigs(cfg)
+}
+
+// enqueueOtherOverlaySizeConfigs queues the other ContainerRuntimeConfigs that set an overlaySize, as a change to cfg
+// can change which overlaySize applies to the pools they share
+func (ctrl *Controller) enqueueOtherOverlaySizeConfigs(cfg *mcfgv1.ContainerRuntimeConfig) {
+	if !hasOverlaySize(cfg) {
+		return
+	}
+	ctrcfgs, err := ctrl.mccrLister.List(labels.Everything())
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("couldn't list ContainerRuntimeConfigs: %w", err))
+		return
+	}
+	for _, other := range ctrcfgs {
+		if other.Name != cfg.Name && hasOverlaySize(other) {
+			ctrl.enqueue(other)
+		}
+	}
 }
 
 func (ctrl *Controller) deleteContainerRuntimeConfig(obj interface{}) {
@@ -396,6 +622,8 @@ func (ctrl *Controller) deleteContainerRuntimeConfig(obj interface{}) {
 			return
 		}
 	}
+	ctrl.clearUnreconciledMetric(cfg.Name)
+	ctrl.enqueueOtherOverlaySizeConfigs(cfg)
 	if err := ctrl.cascadeDelete(cfg); err != nil {
 		utilruntime.HandleError(fmt.Errorf("couldn't delete object %#v: %w", cfg, err))
 	} else {
@@ -403,16 +631,30 @@ func (ctrl *Controller) deleteContainerRuntimeConfig(obj interface{}) {
 	}
 }
 
+// cascadeDelete deletes the MachineConfigs generated by the ContainerRuntimeConfig, one per pool it applied to, and
+// drops their finalizers. A MachineConfig that is already gone, e.g. deleted by hand, still has its finalizer removed
+// so that the ContainerRuntimeConfig does not stay stuck terminating.
 func (ctrl *Controller) cascadeDelete(cfg *mcfgv1.ContainerRuntimeConfig) error {
-	if len(cfg.GetFinalizers()) == 0 {
+	var mcNames []string
+	for _, finalizer := range cfg.GetFinalizers() {
+		if isManagedMCFinalizer(finalizer) {
+			mcNames = append(mcNames, finalizer)
+		}
+	}
+	if len(mcNames) == 0 {
 		return nil
 	}
-	mcName := cfg.GetFinalizers()[0]
-	err := ctrl.client.MachineconfigurationV1().MachineConfigs().Delete(context.TODO(), mcName, metav1.DeleteOptions{})
-	if err != nil && !errors.IsNotFound(err) {
-		return err
+	for _, mcName := range mcNames {
+		err := ctrl.client.MachineconfigurationV1().MachineConfigs().Delete(context.TODO(), mcName, metav1.DeleteOptions{})
+		if errors.IsNotFound(err) {
+			klog.V(4).Infof("MachineConfig %s of ContainerRuntimeConfig %s is already deleted", mcName, cfg.Name)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("could not delete MachineConfig %s: %w", mcName, err)
+		}
 	}
-	return ctrl.popFinalizerFromContainerRuntimeConfig(cfg)
+	return ctrl.removeFinalizersFromContainerRuntimeConfig(cfg, mcNames)
 }
 
 func (ctrl *Controller) enqueue(cfg *mcfgv1.ContainerRuntimeConfig) {
@@ -477,7 +719,13 @@ func (ctrl *Controller) handleErr(err error, key string) {
 		return
 	}
 
-	if ctrl.queue.NumRequeues(key) < maxRetries {
+	if delay, ok := getThrottledRequeueDelay(err); ok {
+		klog.V(2).Infof("Throttled by the API server while syncing containerruntimeconfig %v, retrying in %v: %v", key, delay, err)
+		ctrl.queue.AddAfter(key, delay)
+		return
+	}
+
+	if ctrl.queue.NumRequeues(key) < ctrl.maxRetries {
 		klog.V(2).Infof("Error syncing containerruntimeconfig %v: %v", key, err)
 		ctrl.queue.AddRateLimited(key)
 		return
@@ -485,6 +733,7 @@ func (ctrl *Controller) handleErr(err error, key string) {
 
 	utilruntime.HandleError(err)
 	klog.V(2).Infof("Dropping containerruntimeconfig %q out of the queue: %v", key, err)
+	ctrlcommon.MCCContainerRuntimeConfigSyncFailures.WithLabelValues(key).Inc()
 	ctrl.queue.Forget(key)
 	ctrl.queue.AddAfter(key, 1*time.Minute)
 }
@@ -495,7 +744,13 @@ func (ctrl *Controller) handleImgErr(err error, key string) {
 		return
 	}
 
-	if ctrl.imgQueue.NumRequeues(key) < maxRetries {
+	if delay, ok := getThrottledRequeueDelay(err); ok {
+		klog.V(2).Infof("Throttled by the API server while syncing image config %v, retrying in %v: %v", key, delay, err)
+		ctrl.imgQueue.AddAfter(key, delay)
+		return
+	}
+
+	if ctrl.imgQueue.NumRequeues(key) < ctrl.maxRetries {
 		klog.V(2).Infof("Error syncing image config %v: %v", key, err)
 		ctrl.imgQueue.AddRateLimited(key)
 		return
@@ -507,16 +762,63 @@ func (ctrl *Controller) handleImgErr(err error, key string) {
 	ctrl.imgQueue.AddAfter(key, 1*time.Minute)
 }
 
-// generateOriginalContainerRuntimeConfigs returns rendered default storage, registries and policy config files
-func generateOriginalContainerRuntimeConfigs(templateDir string, cc *mcfgv1.ControllerConfig, role string) (*ign3types.File, *ign3types.File, *ign3types.File, error) {
-	// Render the default templates
+// getThrottledRequeueDelay returns how long to wait before retrying a sync that failed with err, and false if err
+// is not a throttling (429) error of the API server. Throttled syncs are not counted against maxRetries: the
+// config is not at fault, and retrying it a few milliseconds later would only add to the load of the API server.
+func getThrottledRequeueDelay(err error) (time.Duration, bool) {
+	if !errors.IsTooManyRequests(err) {
+		return 0, false
+	}
+	delay := throttledRequeueDelay
+	if seconds, ok := errors.SuggestsClientDelay(err); ok && time.Duration(seconds)*time.Second > delay {
+		delay = time.Duration(seconds) * time.Second
+	}
+	return delay, true
+}
+
+// renderTemplatesForRole renders the default MachineConfigs of the role from the templates
+func renderTemplatesForRole(templateDir string, cc *mcfgv1.ControllerConfig, role string) ([]*mcfgv1.MachineConfig, error) {
 	rc := &mtmpl.RenderConfig{
 		ControllerConfigSpec: &cc.Spec,
 	}
 	generatedConfigs, err := mtmpl.GenerateMachineConfigsForRole(rc, role, templateDir)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("generateMachineConfigsforRole failed with error %w", err)
+		return nil, fmt.Errorf("generateMachineConfigsforRole failed with error %w", err)
+	}
+	return generatedConfigs, nil
+}
+
+// generateOriginalContainerRuntimeConfigs returns rendered default storage, registries and policy config files
+func generateOriginalContainerRuntimeConfigs(templateDir string, cc *mcfgv1.ControllerConfig, role string) (*ign3types.File, *ign3types.File, *ign3types.File, error) {
+	// Render the default templates
+	generatedConfigs, err := renderTemplatesForRole(templateDir, cc, role)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return findOriginalContainerRuntimeConfigs(generatedConfigs)
+}
+
+// generateOriginalStorageAndCRIOConfigs renders the default templates of the role once, and returns the default
+// storage config file and CRI-O config the ContainerRuntimeConfig changes are merged in
+func generateOriginalStorageAndCRIOConfigs(templateDir string, cc *mcfgv1.ControllerConfig, role string) (*ign3types.File, *templateCRIOConfig, error) {
+	generatedConfigs, err := renderTemplatesForRole(templateDir, cc, role)
+	if err != nil {
+		return nil, nil, err
+	}
+	storageConfig, _, _, err := findOriginalContainerRuntimeConfigs(generatedConfigs)
+	if err != nil {
+		return nil, nil, err
+	}
+	crioConfig, err := findTemplateCRIOConfig(generatedConfigs, role)
+	if err != nil {
+		return nil, nil, err
 	}
+	return storageConfig, crioConfig, nil
+}
+
+// findOriginalContainerRuntimeConfigs returns the default storage, registries and policy config files of the rendered
+// default MachineConfigs
+func findOriginalContainerRuntimeConfigs(generatedConfigs []*mcfgv1.MachineConfig) (*ign3types.File, *ign3types.File, *ign3types.File, error) {
 	// Find generated storage.conf, registries.conf, and policy.json
 	var (
 		config, gmcStorageConfig, gmcRegistriesConfig, gmcPolicyJSON *ign3types.File
@@ -554,47 +856,170 @@ func generateOriginalContainerRuntimeConfigs(templateDir string, cc *mcfgv1.Cont
 	return gmcStorageConfig, gmcRegistriesConfig, gmcPolicyJSON, nil
 }
 
+// templateCRIOConfig is the default CRI-O config rendered from the templates for a role
+type templateCRIOConfig struct {
+	// runtimes are the sorted names of the OCI runtimes it defines
+	runtimes []string
+}
+
+// findTemplateCRIOConfig finds and decodes the default CRI-O config of the rendered default MachineConfigs of the role
+func findTemplateCRIOConfig(generatedConfigs []*mcfgv1.MachineConfig, role string) (*templateCRIOConfig, error) {
+	var (
+		crioConfig *ign3types.File
+		err        error
+	)
+	for _, gmc := range generatedConfigs {
+		if crioConfig, err = findCRIODefaultConfig(gmc); err == nil {
+			break
+		}
+	}
+	if crioConfig == nil || crioConfig.Contents.Source == nil {
+		return nil, fmt.Errorf("could not find the default CRI-O config for role %s", role)
+	}
+	contents, err := ctrlcommon.DecodeIgnitionFileContents(crioConfig.Contents.Source, crioConfig.Contents.Compression)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode the default CRI-O config: %w", err)
+	}
+	tomlConf := tomlConfigCRIOTemplate{}
+	if _, err := toml.Decode(string(contents), &tomlConf); err != nil {
+		return nil, fmt.Errorf("error unmarshalling the default CRI-O config: %w", err)
+	}
+	runtimes := make([]string, 0, len(tomlConf.Crio.Runtime.Runtimes))
+	for name := range tomlConf.Crio.Runtime.Runtimes {
+		runtimes = append(runtimes, name)
+	}
+	sort.Strings(runtimes)
+	return &templateCRIOConfig{
+		runtimes: runtimes,
+	}, nil
+}
+
+// validateDefaultRuntimeForRole checks that the DefaultRuntime of the ContainerRuntimeConfig is one of the runtimes
+// defined in the CRI-O config of the role, as CRI-O fails to start when its default_runtime has no runtime table
+func validateDefaultRuntimeForRole(tmpl *templateCRIOConfig, role string, cfg *mcfgv1.ContainerRuntimeConfig) error {
+	defaultRuntime := string(cfg.Spec.ContainerRuntimeConfig.DefaultRuntime)
+	if defaultRuntime == "" {
+		return nil
+	}
+	if slices.Contains(tmpl.runtimes, defaultRuntime) {
+		return nil
+	}
+	return newFieldValidationError("DefaultRuntime", fmt.Errorf("invalid DefaultRuntime %q, the CRI-O config of pool %s only defines the runtimes %s", defaultRuntime, role, strings.Join(tmpl.runtimes, ", ")))
+}
+
+// syncStatusOnly records the result of a sync in the status of the ContainerRuntimeConfig. The result is recorded
+// against the generation of cfg, and discarded if the ContainerRuntimeConfig has moved on to another generation in
+// the meantime: that generation is queued again and will record its own result.
 func (ctrl *Controller) syncStatusOnly(cfg *mcfgv1.ContainerRuntimeConfig, err error, args ...interface{}) error {
-	statusUpdateErr := retry.RetryOnConflict(updateBackoff, func() error {
-		newcfg, getErr := ctrl.mccrLister.Get(cfg.Name)
-		if getErr != nil {
-			return getErr
+	return ctrl.syncStatusCondition(cfg, err, wrapErrorWithCondition(err, args...))
+}
+
+// syncStatusCondition is syncStatusOnly, recording newStatusCondition instead of the condition derived from err
+func (ctrl *Controller) syncStatusCondition(cfg *mcfgv1.ContainerRuntimeConfig, err error, newStatusCondition mcfgv1.ContainerRuntimeConfigCondition) error {
+	newGeneration := false
+	stale := false
+	statusUpdateErr := ctrl.updateStatus(cfg.Name, func(newcfg *mcfgv1.ContainerRuntimeConfig) bool {
+		if isStaleGeneration(cfg, newcfg) {
+			stale = true
+			return false
 		}
+		stale = false
 		// Update the observedGeneration
 		if newcfg.GetGeneration() != newcfg.Status.ObservedGeneration {
+			newGeneration = true
 			newcfg.Status.ObservedGeneration = newcfg.GetGeneration()
 		}
 		// To avoid a long list of same statuses, only append a status if it is the first status
 		// or if the status message is different from the message of the last status recorded
 		// If the last status message is the same as the new one, then update the last status to
 		// reflect the latest time stamp from the new status message.
-		newStatusCondition := wrapErrorWithCondition(err, args...)
 		if len(newcfg.Status.Conditions) == 0 || newStatusCondition.Message != newcfg.Status.Conditions[len(newcfg.Status.Conditions)-1].Message {
 			newcfg.Status.Conditions = append(newcfg.Status.Conditions, newStatusCondition)
 		} else if newcfg.Status.Conditions[len(newcfg.Status.Conditions)-1].Message == newStatusCondition.Message {
 			newcfg.Status.Conditions[len(newcfg.Status.Conditions)-1] = newStatusCondition
 		}
-		_, updateErr := ctrl.client.MachineconfigurationV1().ContainerRuntimeConfigs().UpdateStatus(context.TODO(), newcfg, metav1.UpdateOptions{})
-		return updateErr
+		return true
 	})
 	// If an error occurred in updating the status just log it
 	if statusUpdateErr != nil {
 		klog.Warningf("error updating container runtime config status: %v", statusUpdateErr)
 	}
+	if stale {
+		klog.V(4).Infof("Discarding status of ContainerRuntimeConfig %s computed for stale generation %d", cfg.Name, cfg.GetGeneration())
+	}
+	ctrl.updateUnreconciledMetric(cfg.Name, newGeneration, err == nil && statusUpdateErr == nil && !stale)
+	if err != nil {
+		ctrlcommon.MCCContainerRuntimeConfigFailing.WithLabelValues(cfg.Name).Set(1)
+	} else if !stale {
+		ctrlcommon.MCCContainerRuntimeConfigFailing.WithLabelValues(cfg.Name).Set(0)
+	}
 	// Want to return the actual error received from the sync function
 	return err
 }
 
+// isStaleGeneration returns true if the result of a sync of cfg should not be recorded on latest, because
+// latest is at another generation than the one the sync was computed for or has already observed a newer one
+func isStaleGeneration(cfg, latest *mcfgv1.ContainerRuntimeConfig) bool {
+	return latest.GetGeneration() != cfg.GetGeneration() || latest.Status.ObservedGeneration > cfg.GetGeneration()
+}
+
+// updateStatus applies update to the latest version of the ContainerRuntimeConfig and writes back its status,
+// retrying on conflicts. Nothing is written if update returns false.
+func (ctrl *Controller) updateStatus(name string, update func(newcfg *mcfgv1.ContainerRuntimeConfig) bool) error {
+	return retry.RetryOnConflict(ctrl.updateBackoff, func() error {
+		newcfg, getErr := ctrl.mccrLister.Get(name)
+		if getErr != nil {
+			return getErr
+		}
+		if !update(newcfg) {
+			return nil
+		}
+		_, updateErr := ctrl.client.MachineconfigurationV1().ContainerRuntimeConfigs().UpdateStatus(context.TODO(), newcfg, metav1.UpdateOptions{})
+		return updateErr
+	})
+}
+
+// updateUnreconciledMetric records since when the latest generation of the ContainerRuntimeConfig has been waiting
+// to be reconciled. The clock (re)starts whenever a generation that differs from the observedGeneration is seen, and
+// is reset once the generation has been reconciled successfully.
+func (ctrl *Controller) updateUnreconciledMetric(name string, newGeneration, reconciled bool) {
+	ctrl.unreconciledSinceLock.Lock()
+	defer ctrl.unreconciledSinceLock.Unlock()
+
+	if reconciled {
+		delete(ctrl.unreconciledSince, name)
+		ctrlcommon.MCCContainerRuntimeConfigUnreconciled.WithLabelValues(name).Set(0)
+		return
+	}
+	since, ok := ctrl.unreconciledSince[name]
+	if !ok || newGeneration {
+		since = time.Now()
+		ctrl.unreconciledSince[name] = since
+	}
+	ctrlcommon.MCCContainerRuntimeConfigUnreconciled.WithLabelValues(name).Set(float64(since.Unix()))
+}
+
+// clearUnreconciledMetric stops tracking a ContainerRuntimeConfig that has been deleted and drops its metrics
+func (ctrl *Controller) clearUnreconciledMetric(name string) {
+	ctrl.unreconciledSinceLock.Lock()
+	defer ctrl.unreconciledSinceLock.Unlock()
+
+	delete(ctrl.unreconciledSince, name)
+	ctrlcommon.MCCContainerRuntimeConfigUnreconciled.DeleteLabelValues(name)
+	ctrlcommon.MCCContainerRuntimeConfigSyncFailures.DeleteLabelValues(name)
+	ctrlcommon.MCCContainerRuntimeConfigFailing.DeleteLabelValues(name)
+	ctrlcommon.MCCContainerRuntimeConfigSyncs.DeletePartialMatch(prometheus.Labels{"ctrcfg": name})
+}
+
 // addAnnotation adds the annotions for a ctrcfg object with the given annotationKey and annotationVal
 func (ctrl *Controller) addAnnotation(cfg *mcfgv1.ContainerRuntimeConfig, annotationKey, annotationVal string) error {
-	annotationUpdateErr := retry.RetryOnConflict(updateBackoff, func() error {
+	annotationUpdateErr := retry.RetryOnConflict(ctrl.updateBackoff, func() error {
 		newcfg, getErr := ctrl.mccrLister.Get(cfg.Name)
 		if getErr != nil {
 			return getErr
 		}
-		newcfg.SetAnnotations(map[string]string{
-			annotationKey: annotationVal,
-		})
+		// Keep the annotations set by the user, e.g. the acknowledgement of changes to the master pool
+		metav1.SetMetaDataAnnotation(&newcfg.ObjectMeta, annotationKey, annotationVal)
 		_, updateErr := ctrl.client.MachineconfigurationV1().ContainerRuntimeConfigs().Update(context.TODO(), newcfg, metav1.UpdateOptions{})
 		return updateErr
 	})
@@ -645,6 +1070,11 @@ func (ctrl *Controller) syncContainerRuntimeConfig(key string) error {
 		return ctrl.syncStatusOnly(cfg, err)
 	}
 
+	for _, warning := range getContainerRuntimeConfigWarnings(cfg) {
+		klog.Warningf("ContainerRuntimeConfig %s: %s", cfg.Name, warning)
+		ctrl.eventRecorder.Event(cfg, corev1.EventTypeWarning, "ContainerRuntimeConfigWarning", warning)
+	}
+
 	// Get ControllerConfig
 	controllerConfig, err := ctrl.ccLister.Get(ctrlcommon.ControllerConfigName)
 	if err != nil {
@@ -663,6 +1093,26 @@ func (ctrl *Controller) syncContainerRuntimeConfig(key string) error {
 		return ctrl.syncStatusOnly(cfg, err)
 	}
 
+	dryRun := isDryRun(cfg)
+	// Nothing is applied in dry-run mode, so previewing changes to the master pool does not need to be acknowledged
+	if !dryRun && cfg.GetAnnotations()[ctrlcommon.ContainerRuntimeConfigAllowMasterChangesAnnotationKey] != "true" {
+		// ContainerRuntimeConfigs already applied to the master pool, e.g. before an upgrade to a version requiring the
+		// acknowledgement, keep being applied until they are changed
+		applied, err := ctrl.isAppliedToMasterPool(cfg, mcpPools)
+		if err != nil {
+			return ctrl.syncStatusOnly(cfg, err, "could not check the MachineConfig of the master pool: %v", err)
+		}
+		if !applied {
+			if err := validateMasterPoolAcknowledged(cfg, mcpPools); err != nil {
+				return ctrl.syncStatusOnly(cfg, err)
+			}
+		}
+	}
+	// warnings are added to the message of the recorded condition
+	var warnings []string
+	// changedPools is the number of pools whose MachineConfig was created or updated by this sync
+	changedPools := 0
+	managedKeys := make([]string, 0, len(mcpPools))
 	for _, pool := range mcpPools {
 		role := pool.Name
 		// Get MachineConfig
@@ -670,42 +1120,94 @@ func (ctrl *Controller) syncContainerRuntimeConfig(key string) error {
 		if err != nil {
 			return ctrl.syncStatusOnly(cfg, err, "could not get ctrcfg key: %v", err)
 		}
+		managedKeys = append(managedKeys, managedKey)
 		mc, err := ctrl.client.MachineconfigurationV1().MachineConfigs().Get(context.TODO(), managedKey, metav1.GetOptions{})
 		isNotFound := errors.IsNotFound(err)
 		if err != nil && !isNotFound {
 			return ctrl.syncStatusOnly(cfg, err, "could not find MachineConfig: %v", managedKey)
 		}
-		// If we have seen this generation and the sync didn't fail, then skip
-		if !isNotFound && cfg.Status.ObservedGeneration >= cfg.Generation && cfg.Status.Conditions[len(cfg.Status.Conditions)-1].Type == mcfgv1.ContainerRuntimeConfigSuccess {
-			// But we still need to compare the generated controller version because during an upgrade we need a new one
-			mcCtrlVersion := mc.Annotations[ctrlcommon.GeneratedByControllerVersionAnnotationKey]
-			if mcCtrlVersion == version.Hash {
-				return nil
-			}
+		// Find which ContainerRuntimeConfig decides the overlaySize of the pool
+		overlaySizeCfg, _, err := ctrl.getOverlaySizeConfigForPool(pool)
+		if err != nil {
+			return ctrl.syncStatusOnly(cfg, err, "could not get the overlaySize for MachineConfigPool %v: %v", pool.Name, err)
+		}
+		// The warnings are checked on every sync, so that the recorded condition lists the current ones. Events are
+		// only emitted when the MachineConfig is written.
+		conflicts, err := ctrl.getConflictingConfigWarningsForPool(cfg, pool)
+		if err != nil {
+			return ctrl.syncStatusOnly(cfg, err, "could not check the other ContainerRuntimeConfigs of MachineConfigPool %v: %v", pool.Name, err)
+		}
+		warnings = append(warnings, conflicts...)
+		logRotationWarning := getExternalLogRotationWarning(cfg, pool)
+		if logRotationWarning != "" {
+			warnings = append(warnings, logRotationWarning)
 		}
-		// Generate the original ContainerRuntimeConfig
-		originalStorageIgn, _, _, err := generateOriginalContainerRuntimeConfigs(ctrl.templatesDir, controllerConfig, role)
+		mcAnnotations := map[string]string{
+			ctrlcommon.GeneratedByControllerVersionAnnotationKey: version.Hash,
+		}
+		if !isNotFound {
+			mcAnnotations[ctrlcommon.ContainerRuntimeConfigInputsAnnotationKey] = getContainerRuntimeConfigInputsHash(controllerConfig, overlaySizeCfg, mc.Spec.Config.Raw)
+		}
+		if forceRegenerate, ok := cfg.GetAnnotations()[ctrlcommon.ContainerRuntimeConfigForceRegenerateAnnotationKey]; ok {
+			mcAnnotations[ctrlcommon.ContainerRuntimeConfigForceRegenerateAnnotationKey] = forceRegenerate
+		}
+		if wantsSingleCRIODropin(cfg) {
+			mcAnnotations[ctrlcommon.ContainerRuntimeConfigSingleCRIODropinAnnotationKey] = "true"
+		}
+		// The MachineConfig of the master pool records the generation it is rendered from, see isAppliedToMasterPool
+		if role == ctrlcommon.MachineConfigPoolMaster {
+			mcAnnotations[ctrlcommon.ContainerRuntimeConfigGenerationAnnotationKey] = strconv.FormatInt(cfg.Generation, 10)
+		}
+		oref := metav1.NewControllerRef(cfg, controllerKind)
+		// If we have seen this generation and the sync didn't fail, then skip rendering the MachineConfig again. The
+		// inputs the generation does not track are echoed in the annotations of the MachineConfig: the controller
+		// version during an upgrade, the ControllerConfig and release image the default configs are rendered from, the
+		// ContainerRuntimeConfig deciding the overlaySize of the pool and the annotations of the
+		// ContainerRuntimeConfig. Pools starting to match the selector have no MachineConfig yet, and the
+		// MachineConfigs of the pools no longer matched are removed below.
+		if !isNotFound && !dryRun && cfg.Status.ObservedGeneration >= cfg.Generation &&
+			len(cfg.Status.Conditions) > 0 && cfg.Status.Conditions[len(cfg.Status.Conditions)-1].Type == mcfgv1.ContainerRuntimeConfigSuccess &&
+			maps.Equal(mc.GetAnnotations(), mcAnnotations) && isOwnedBy(mc, *oref) {
+			klog.V(4).Infof("ContainerRuntimeConfig %v and the inputs of MachineConfig %v are unchanged, skipping", key, managedKey)
+			continue
+		}
+		// The default configs of the role are rendered once, the CRI-O config is used by the validations and the
+		// generated drop-ins
+		originalStorageIgn, templateCRIOConfig, err := generateOriginalStorageAndCRIOConfigs(ctrl.templatesDir, controllerConfig, role)
 		if err != nil {
 			return ctrl.syncStatusOnly(cfg, err, "could not generate origin ContainerRuntime Configs: %v", err)
 		}
+		if err := validateDefaultRuntimeForRole(templateCRIOConfig, role, cfg); err != nil {
+			return ctrl.syncStatusOnly(cfg, err)
+		}
 
-		var configFileList []generatedConfigFile
-		ctrcfg := cfg.Spec.ContainerRuntimeConfig
-		if ctrcfg.OverlaySize != nil && !ctrcfg.OverlaySize.IsZero() {
-			storageTOML, err := mergeConfigChanges(originalStorageIgn, cfg, updateStorageConfig)
-			if err != nil {
-				klog.V(2).Infoln(cfg, err, "error merging user changes to storage.conf: %v", err)
-				ctrl.syncStatusOnly(cfg, err)
-			} else {
-				configFileList = append(configFileList, generatedConfigFile{filePath: storageConfigPath, data: storageTOML})
-				ctrl.syncStatusOnly(cfg, nil)
-			}
+		if hasOverlaySize(cfg) && overlaySizeCfg != nil && overlaySizeCfg.Name != cfg.Name {
+			klog.V(2).Infof("overlaySize of ContainerRuntimeConfig %v on MachineConfigPool %v is overridden by the more specific ContainerRuntimeConfig %v", cfg.Name, pool.Name, overlaySizeCfg.Name)
+		}
+		configFileList, err := generateContainerRuntimeConfigFiles(originalStorageIgn, cfg, overlaySizeCfg)
+		if err != nil {
+			return ctrl.syncStatusOnly(cfg, err, "could not generate ContainerRuntime config files: %v", err)
 		}
 
-		// Create the cri-o drop-in files
-		if ctrcfg.LogLevel != "" || ctrcfg.PidsLimit != nil || (ctrcfg.LogSizeMax != nil && !ctrcfg.LogSizeMax.IsZero()) || ctrcfg.DefaultRuntime != mcfgv1.ContainerRuntimeDefaultRuntimeEmpty {
-			crioFileConfigs := createCRIODropinFiles(cfg)
-			configFileList = append(configFileList, crioFileConfigs...)
+		// In dry-run mode the rendered config is only summarized in an event, no MachineConfig or finalizer is added.
+		// The event lists the files and the hash of the Ignition config rather than the config itself, which can be
+		// larger than an event message.
+		if dryRun {
+			previewIgn, err := createNewIgnition(configFileList)
+			if err != nil {
+				return ctrl.syncStatusOnly(cfg, err, "could not create container runtime Ignition config: %v", err)
+			}
+			rawPreviewIgn, err := json.Marshal(previewIgn)
+			if err != nil {
+				return ctrl.syncStatusOnly(cfg, err, "error marshalling container runtime config Ignition: %v", err)
+			}
+			paths := make([]string, 0, len(configFileList))
+			for _, file := range configFileList {
+				paths = append(paths, file.filePath)
+			}
+			ctrl.eventRecorder.Eventf(cfg, corev1.EventTypeNormal, "ContainerRuntimeConfigDryRunPreview", "MachineConfig %s of MachineConfigPool %s would be rendered with files %s and Ignition config sha256 %s",
+				managedKey, pool.Name, strings.Join(paths, ", "), getIgnitionConfigHash(rawPreviewIgn))
+			continue
 		}
 
 		if isNotFound {
@@ -735,21 +1237,39 @@ func (ctrl *Controller) syncContainerRuntimeConfig(key string) error {
 			}
 		}
 
-		ctrRuntimeConfigIgn := createNewIgnition(configFileList)
-		rawCtrRuntimeConfigIgn, err := json.Marshal(ctrRuntimeConfigIgn)
+		ctrRuntimeConfigIgn, err := createNewIgnition(configFileList)
+		if err != nil {
+			return ctrl.syncStatusOnly(cfg, err, "could not create container runtime Ignition config: %v", err)
+		}
+		rawCtrRuntimeConfigIgn, err := ctrl.marshalIgnition(ctrRuntimeConfigIgn)
 		if err != nil {
 			return ctrl.syncStatusOnly(cfg, err, "error marshalling container runtime config Ignition: %v", err)
 		}
+		// Never write a MachineConfig the nodes could not parse
+		if err := validateGeneratedIgnition(rawCtrRuntimeConfigIgn); err != nil {
+			return ctrl.syncStatusOnly(cfg, err, "invalid container runtime config Ignition for MachineConfig %v: %v", managedKey, err)
+		}
+		mcAnnotations[ctrlcommon.ContainerRuntimeConfigInputsAnnotationKey] = getContainerRuntimeConfigInputsHash(controllerConfig, overlaySizeCfg, rawCtrRuntimeConfigIgn)
+		// The MachineConfig is only written when its contents change, e.g. because of the generated controller
+		// version during an upgrade, so that resyncs of an already applied ContainerRuntimeConfig are no-ops
+		if !isNotFound && bytes.Equal(mc.Spec.Config.Raw, rawCtrRuntimeConfigIgn) && maps.Equal(mc.GetAnnotations(), mcAnnotations) && isOwnedBy(mc, *oref) {
+			continue
+		}
+		changedPools++
+		for _, conflict := range conflicts {
+			klog.Warningf("ContainerRuntimeConfig %s: %s", cfg.Name, conflict)
+			ctrl.eventRecorder.Event(cfg, corev1.EventTypeWarning, "ConflictingContainerRuntimeConfig", conflict)
+		}
+		if logRotationWarning != "" {
+			klog.Warningf("ContainerRuntimeConfig %s: %s", cfg.Name, logRotationWarning)
+			ctrl.eventRecorder.Event(cfg, corev1.EventTypeWarning, "ExternalLogRotation", logRotationWarning)
+		}
 		mc.Spec.Config.Raw = rawCtrRuntimeConfigIgn
-
-		mc.SetAnnotations(map[string]string{
-			ctrlcommon.GeneratedByControllerVersionAnnotationKey: version.Hash,
-		})
-		oref := metav1.NewControllerRef(cfg, controllerKind)
+		mc.SetAnnotations(mcAnnotations)
 		mc.SetOwnerReferences([]metav1.OwnerReference{*oref})
 
 		// Create or Update, on conflict retry
-		if err := retry.RetryOnConflict(updateBackoff, func() error {
+		if err := retry.RetryOnConflict(ctrl.updateBackoff, func() error {
 			var err error
 			if isNotFound {
 				_, err = ctrl.client.MachineconfigurationV1().MachineConfigs().Create(context.TODO(), mc, metav1.CreateOptions{})
@@ -767,35 +1287,133 @@ func (ctrl *Controller) syncContainerRuntimeConfig(key string) error {
 		klog.Infof("Applied ContainerRuntimeConfig %v on MachineConfigPool %v", key, pool.Name)
 		ctrlcommon.UpdateStateMetric(ctrlcommon.MCCSubControllerState, "machine-config-controller-container-runtime-config", "Sync Container Runtime Config", pool.Name)
 	}
-	if err := ctrl.cleanUpDuplicatedMC(); err != nil {
-		return err
+	if dryRun {
+		msg := withWarnings(fmt.Sprintf("Dry run: MachineConfigs %v were rendered but not applied", managedKeys), warnings)
+		klog.Infof("ContainerRuntimeConfig %v: %s", key, msg)
+		ctrl.eventRecorder.Event(cfg, corev1.EventTypeNormal, "ContainerRuntimeConfigDryRun", msg)
+		return ctrl.syncStatusOnly(cfg, nil, "%s", msg)
+	}
+	if changedPools == 0 {
+		ctrlcommon.MCCContainerRuntimeConfigSyncs.WithLabelValues(cfg.Name, syncResultNoop).Inc()
+	} else {
+		ctrlcommon.MCCContainerRuntimeConfigSyncs.WithLabelValues(cfg.Name, syncResultApplied).Inc()
+	}
+	if err := ctrl.removeStaleManagedMCs(cfg, managedKeys); err != nil {
+		return ctrl.syncStatusOnly(cfg, err, "could not remove MachineConfigs for pools no longer matched: %v", err)
+	}
+	kept, err := ctrl.cleanUpDuplicatedMC()
+	if err != nil {
+		// Recorded in the status so that the sync is not skipped and the clean up is retried
+		return ctrl.syncStatusOnly(cfg, err)
+	}
+	if len(kept) > 0 {
+		// Not a failure of the ContainerRuntimeConfig, the clean up is retried once the pools may have moved on
+		klog.Infof("Keeping containerruntime machine configs %v generated by an older controller while MachineConfigPools reference them, retrying in %v", kept, duplicatedMCRequeueDelay)
+		ctrl.queue.AddAfter(key, duplicatedMCRequeueDelay)
+	}
+	// The MachineConfigs are only in effect once the pools have rolled them out, the pools are watched to clear this
+	if pending := getPoolsNotRolledOut(mcpPools, managedKeys); len(pending) > 0 {
+		msg := withWarnings(fmt.Sprintf("Waiting for MachineConfigPools %v to roll out MachineConfigs %v", pending, managedKeys), warnings)
+		klog.V(2).Infof("ContainerRuntimeConfig %v: %s", key, msg)
+		return ctrl.syncStatusCondition(cfg, nil, *apihelpers.NewContainerRuntimeConfigCondition(ContainerRuntimeConfigPending, corev1.ConditionTrue, msg))
+	}
+	msg := withWarnings("Success", warnings)
+	// Nothing to record if this generation has already been applied with the same result
+	if changedPools == 0 && cfg.Status.ObservedGeneration >= cfg.Generation && len(cfg.Status.Conditions) > 0 {
+		lastCondition := cfg.Status.Conditions[len(cfg.Status.Conditions)-1]
+		if lastCondition.Type == mcfgv1.ContainerRuntimeConfigSuccess && lastCondition.Message == msg {
+			return nil
+		}
+	}
+	return ctrl.syncStatusOnly(cfg, nil, "%s", msg)
+}
+
+// getConflictingConfigWarningsForPool returns the settings of the ContainerRuntimeConfig that conflict with the ones
+// of the other ContainerRuntimeConfigs applying to the pool
+func (ctrl *Controller) getConflictingConfigWarningsForPool(cfg *mcfgv1.ContainerRuntimeConfig, pool *mcfgv1.MachineConfigPool) ([]string, error) {
+	ctrcfgs, err := ctrl.mccrLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	return getConflictingConfigWarnings(cfg, pool, ctrcfgs)
+}
+
+// removeStaleManagedMCs deletes the MachineConfigs the ContainerRuntimeConfig generated for pools it no longer matches,
+// e.g. when a pool was deleted and recreated with a different name but the same labels, and drops their finalizers.
+// managedKeys are the names of the MachineConfigs generated for the pools that are currently matched.
+func (ctrl *Controller) removeStaleManagedMCs(cfg *mcfgv1.ContainerRuntimeConfig, managedKeys []string) error {
+	var stale []string
+	for _, finalizer := range cfg.GetFinalizers() {
+		if isManagedMCFinalizer(finalizer) && !ctrlcommon.InSlice(finalizer, managedKeys) {
+			stale = append(stale, finalizer)
+		}
+	}
+	if len(stale) == 0 {
+		return nil
 	}
-	return ctrl.syncStatusOnly(cfg, nil)
+	for _, mcName := range stale {
+		err := ctrl.client.MachineconfigurationV1().MachineConfigs().Delete(context.TODO(), mcName, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("could not delete MachineConfig %s: %w", mcName, err)
+		}
+		klog.Infof("Removed MachineConfig %s generated by ContainerRuntimeConfig %s for a pool it no longer matches", mcName, cfg.Name)
+	}
+	return ctrl.removeFinalizersFromContainerRuntimeConfig(cfg, stale)
 }
 
 // cleanUpDuplicatedMC removes the MC of non-updated GeneratedByControllerVersionKey if its name contains 'generated-containerruntimeconfig'.
 // BZ 1955517: upgrade when there are more than one configs, the duplicated and upgraded MC will be generated (func getManagedKubeletConfigKey())
 // MC with old GeneratedByControllerVersionKey fails the upgrade.
-func (ctrl *Controller) cleanUpDuplicatedMC() error {
+// MCs that are referenced by the spec or status configuration of a pool may still be in use by its nodes, they are kept
+// and their names returned so that the clean up is retried later.
+func (ctrl *Controller) cleanUpDuplicatedMC() ([]string, error) {
 	generatedCtrCfg := "generated-containerruntime"
 	// Get all machine configs
 	mcList, err := ctrl.client.MachineconfigurationV1().MachineConfigs().List(context.TODO(), metav1.ListOptions{})
 	if err != nil {
-		return fmt.Errorf("error listing containerruntime machine configs: %w", err)
+		return nil, fmt.Errorf("error listing containerruntime machine configs: %w", err)
+	}
+	inUse, err := ctrl.getMCsReferencedByPools()
+	if err != nil {
+		return nil, err
 	}
+	var kept []string
 	for _, mc := range mcList.Items {
 		if !strings.Contains(mc.Name, generatedCtrCfg) {
 			continue
 		}
 		// delete the containerruntime mc if its degraded
 		if mc.Annotations[ctrlcommon.GeneratedByControllerVersionAnnotationKey] != version.Hash {
+			if pool, ok := inUse[mc.Name]; ok {
+				klog.Infof("Keeping degraded containerruntime machine config %s while MachineConfigPool %s references it", mc.Name, pool)
+				kept = append(kept, mc.Name)
+				continue
+			}
 			if err := ctrl.client.MachineconfigurationV1().MachineConfigs().Delete(context.TODO(), mc.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
-				return fmt.Errorf("error deleting degraded containerruntime machine config %s: %w", mc.Name, err)
+				return nil, fmt.Errorf("error deleting degraded containerruntime machine config %s: %w", mc.Name, err)
 			}
 
 		}
 	}
-	return nil
+	return kept, nil
+}
+
+// getMCsReferencedByPools returns the names of the MachineConfigs referenced by the spec or status configuration of
+// the MachineConfigPools, mapped to the name of the pool.
+func (ctrl *Controller) getMCsReferencedByPools() (map[string]string, error) {
+	pools, err := ctrl.mcpLister.List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("error listing MachineConfigPools: %w", err)
+	}
+	inUse := make(map[string]string)
+	for _, pool := range pools {
+		for _, configuration := range []mcfgv1.MachineConfigPoolStatusConfiguration{pool.Spec.Configuration, pool.Status.Configuration} {
+			for _, source := range configuration.Source {
+				inUse[source.Name] = pool.Name
+			}
+		}
+	}
+	return inUse, nil
 }
 
 // mergeConfigChanges retrieves the original/default config data from the templates, decodes it and merges in the changes given by the Custom Resource.
@@ -815,6 +1433,29 @@ func mergeConfigChanges(origFile *ign3types.File, cfg *mcfgv1.ContainerRuntimeCo
 	return cfgTOML, nil
 }
 
+// generateContainerRuntimeConfigFiles returns every file the ContainerRuntimeConfig renders into the MachineConfig of a pool:
+// storage.conf when the overlaySize is set, followed by the cri-o drop-in files. overlaySizeCfg is the ContainerRuntimeConfig
+// that decides the overlaySize of the pool; when nil, the overlaySize of cfg is used.
+func generateContainerRuntimeConfigFiles(originalStorageIgn *ign3types.File, cfg, overlaySizeCfg *mcfgv1.ContainerRuntimeConfig) ([]generatedConfigFile, error) {
+	var configFileList []generatedConfigFile
+	if hasOverlaySize(cfg) {
+		if overlaySizeCfg == nil {
+			overlaySizeCfg = cfg
+		}
+		storageTOML, err := mergeConfigChanges(originalStorageIgn, overlaySizeCfg, updateStorageConfig)
+		if err != nil {
+			return nil, fmt.Errorf("error merging user changes to storage.conf: %w", err)
+		}
+		configFileList = append(configFileList, generatedConfigFile{filePath: storageConfigPath, data: storageTOML})
+	}
+	// createCRIODropinFiles only renders the fields that are set
+	configFileList = append(configFileList, createCRIODropinFiles(cfg)...)
+	if wantsSingleCRIODropin(cfg) {
+		return mergeCRIODropinFiles(configFileList)
+	}
+	return configFileList, nil
+}
+
 // nolint: gocyclo
 func (ctrl *Controller) syncImageConfig(key string) error {
 	startTime := time.Now()
@@ -827,6 +1468,9 @@ func (ctrl *Controller) syncImageConfig(key string) error {
 	imgcfg, err := ctrl.imgLister.Get("cluster")
 	if errors.IsNotFound(err) {
 		klog.V(2).Infof("ImageConfig 'cluster' does not exist or has been deleted")
+		if key == imageConfigDeletedKey {
+			return ctrl.removeImageConfigMCs()
+		}
 		return nil
 	}
 	if err != nil {
@@ -846,6 +1490,28 @@ func (ctrl *Controller) syncImageConfig(key string) error {
 		return err
 	}
 
+	// Nothing is rendered again when neither the Image config nor any of the other inputs changed since the last
+	// successful sync, as long as the registries MachineConfigs are still those generated by this controller version
+	poolArchs, err := ctrl.getBuiltInPoolArchitectures()
+	if err != nil {
+		return err
+	}
+	inputs, err := ctrl.getImageConfigSyncInputs(imgcfg, clusterVersionCfg, poolArchs)
+	if err != nil {
+		return err
+	}
+	if lastSync := ctrl.getLastObservedImageSync(); imgcfg.Generation == lastSync.generation && inputs == lastSync.inputs {
+		upToDate, err := ctrl.registriesMCsUpToDate(lastSync.mcHashes)
+		if err != nil {
+			return err
+		}
+		if upToDate {
+			klog.V(4).Infof("ImageConfig %q and the objects the registries are rendered from are unchanged, skipping", key)
+			ctrlcommon.MCCImageConfigSyncs.WithLabelValues(syncResultNoop).Inc()
+			return nil
+		}
+	}
+
 	// Find all ImageContentSourcePolicy objects
 	icspRules, err := ctrl.icspLister.List(labels.Everything())
 	if err != nil && errors.IsNotFound(err) {
@@ -869,6 +1535,33 @@ func (ctrl *Controller) syncImageConfig(key string) error {
 		return err
 	}
 
+	// ImageDigestMirrorSet rules take precedence over ImageContentSourcePolicy rules for the same source
+	icspRules, conflicts := preferIDMSOverICSP(icspRules, idmsRules)
+	if len(conflicts) > 0 {
+		klog.Warningf("imagecontentsourcepolicy mirrors for %v are ignored, these sources are also configured by imagedigestmirrorsets", conflicts)
+	}
+	// ImageContentSourcePolicy mirrors are digest-only, which takes precedence over ImageTagMirrorSet rules for the same mirrors
+	itmsRules, conflicts = preferDigestOnlyOverTagMirrors(icspRules, itmsRules)
+	if len(conflicts) > 0 {
+		msg := fmt.Sprintf("imagetagmirrorset mirrors for %v are ignored, these mirrors are digest-only as they are also configured by imagecontentsourcepolicies", conflicts)
+		klog.Warning(msg)
+		ctrl.eventRecorder.Event(imgcfg, corev1.EventTypeWarning, "DigestOnlyMirrorConflict", msg)
+	}
+
+	// Warn the admin when the number of mirror rules is getting close to the practical limits
+	if numRules := countMirrorRules(icspRules, idmsRules, itmsRules); numRules > mirrorRulesWarningThreshold {
+		msg := fmt.Sprintf("%d mirror rules are configured across imagecontentsourcepolicies, imagedigestmirrorsets and imagetagmirrorsets, which exceeds the recommended limit of %d and may slow down registries config syncs", numRules, mirrorRulesWarningThreshold)
+		klog.Warning(msg)
+		ctrl.eventRecorder.Event(imgcfg, corev1.EventTypeWarning, "MirrorRulesLimitExceeded", msg)
+	}
+	// Warn the admin when registries are configured as insecure but excluded from the allowed registries, pulls from
+	// them are rejected by policy.json anyway
+	if notAllowed := getInsecureRegistriesNotAllowed(imgcfg.Spec.RegistrySources.InsecureRegistries, imgcfg.Spec.RegistrySources.AllowedRegistries); len(notAllowed) > 0 {
+		msg := fmt.Sprintf("insecureRegistries %v are not in allowedRegistries, images from them will be rejected", notAllowed)
+		klog.Warning(msg)
+		ctrl.eventRecorder.Event(imgcfg, corev1.EventTypeWarning, "InsecureRegistryNotAllowed", msg)
+	}
+
 	var (
 		registriesBlocked, policyBlocked, allowedRegs []string
 		releaseImage                                  string
@@ -895,30 +1588,44 @@ func (ctrl *Controller) syncImageConfig(key string) error {
 		}
 	}
 
+	// Get ControllerConfig
+	controllerConfig, err := ctrl.ccLister.Get(ctrlcommon.ControllerConfigName)
+	if err != nil {
+		return fmt.Errorf("could not get ControllerConfig %w", err)
+	}
+
+	if err := validateBlockedAndAllowedRegistries(&imgcfg.Spec.RegistrySources); err != nil {
+		return err
+	}
+
 	if clusterVersionCfg != nil {
-		// The possibility of releaseImage being "" is very unlikely, will only happen if clusterVersionCfg is nil. If this happens
-		// then there is something very wrong with the cluster and in that situation it would be best to fail here till clusterVersionCfg
-		// has been recovered
+		// The desired release image is not set yet during very early bootstrap. Everything is rendered without
+		// excluding the payload registry from the blocked registries, and the sync is retried until the image is set
+		// to exclude it. There is no ClusterVersion event handler, so requeue explicitly.
+		if clusterVersionCfg.Status.Desired.Image == "" {
+			klog.Infof("ClusterVersion 'version' has no desired release image yet, retrying ImageConfig sync in %v", releaseImagePendingRequeueDelay)
+			ctrl.imgQueue.AddAfter(key, releaseImagePendingRequeueDelay)
+		}
 		releaseImage = clusterVersionCfg.Status.Desired.Image
 		// Go through the registries in the image spec to get and validate the blocked registries
-		registriesBlocked, policyBlocked, allowedRegs, err = getValidBlockedAndAllowedRegistries(releaseImage, &imgcfg.Spec, icspRules, idmsRules)
+		registriesBlocked, policyBlocked, allowedRegs, err = getValidBlockedAndAllowedRegistries(releaseImage, getPauseImage(controllerConfig), &imgcfg.Spec, icspRules, idmsRules)
 		if err != nil && err != errParsingReference {
 			klog.V(2).Infof("%v, skipping....", err)
 		} else if err == errParsingReference {
 			return err
 		}
+		// Warn the admin when the payload registry is needlessly listed in the search registries
+		if payloadRegistry, found, err := payloadRegistryInSearchRegistries(releaseImage, imgcfg.Spec.RegistrySources.ContainerRuntimeSearchRegistries); err == nil && found {
+			msg := fmt.Sprintf("the payload registry %q is listed in containerRuntimeSearchRegistries, which is not needed to pull the release payload", payloadRegistry)
+			klog.Warning(msg)
+			ctrl.eventRecorder.Event(imgcfg, corev1.EventTypeWarning, "PayloadRegistryInSearchRegistries", msg)
+		}
 	}
 
 	if clusterScopePolicies, scopeNamespacePolicies, err = getValidScopePolicies(clusterImagePolicies, imagePolicies, ctrl); err != nil {
 		return err
 	}
 
-	// Get ControllerConfig
-	controllerConfig, err := ctrl.ccLister.Get(ctrlcommon.ControllerConfigName)
-	if err != nil {
-		return fmt.Errorf("could not get ControllerConfig %w", err)
-	}
-
 	sel, err := metav1.LabelSelectorAsSelector(metav1.AddLabelToSelector(&metav1.LabelSelector{}, builtInLabelKey, ""))
 	if err != nil {
 		return err
@@ -928,6 +1635,10 @@ func (ctrl *Controller) syncImageConfig(key string) error {
 	if err != nil {
 		return err
 	}
+	// The registries MachineConfig of each pool and the hash of the registries.conf it renders, recorded for debugging
+	registriesMCs := make(map[string]string, 2*len(mcpPools))
+	mcHashes := make(map[string]string, len(mcpPools))
+	syncResult := syncResultNoop
 	for _, pool := range mcpPools {
 		// To keep track of whether we "actually" got an updated image config
 		applied := true
@@ -937,15 +1648,21 @@ func (ctrl *Controller) syncImageConfig(key string) error {
 		if err != nil {
 			return err
 		}
-		if err := retry.RetryOnConflict(updateBackoff, func() error {
-			registriesIgn, err := registriesConfigIgnition(ctrl.templatesDir, controllerConfig, role, releaseImage,
+		var (
+			registriesIgn *ign3types.Config
+			rawIgn        []byte
+			tookOver      bool
+		)
+		if err := retry.RetryOnConflict(ctrl.updateBackoff, func() error {
+			var err error
+			registriesIgn, err = registriesConfigIgnition(ctrl.templatesDir, controllerConfig, role, poolArchs[pool.Name], releaseImage,
 				imgcfg.Spec.RegistrySources.InsecureRegistries, registriesBlocked, policyBlocked, allowedRegs,
 				imgcfg.Spec.RegistrySources.ContainerRuntimeSearchRegistries, icspRules, idmsRules, itmsRules, clusterScopePolicies, scopeNamespacePolicies)
 			if err != nil {
 				return err
 			}
 
-			applied, err = ctrl.syncIgnitionConfig(managedKey, registriesIgn, pool, ownerReferenceImageConfig(imgcfg))
+			rawIgn, applied, tookOver, err = ctrl.syncIgnitionConfig(managedKey, registriesIgn, pool, ownerReferenceImageConfig(imgcfg))
 			if err != nil {
 				return fmt.Errorf("could not sync registries Ignition config: %w", err)
 			}
@@ -953,39 +1670,422 @@ func (ctrl *Controller) syncImageConfig(key string) error {
 		}); err != nil {
 			return fmt.Errorf("could not Create/Update MachineConfig: %w", err)
 		}
+		hash, err := getRenderedRegistriesConfigHash(registriesIgn)
+		if err != nil {
+			return err
+		}
+		registriesMCs[pool.Name+registriesMachineConfigKeySuffix] = managedKey
+		registriesMCs[pool.Name+registriesConfigHashKeySuffix] = hash
+		mcHashes[managedKey] = getIgnitionConfigHash(rawIgn)
+		// A registries MC owned by something else than the Image config is an upgrade artifact, which may have left a duplicate behind
+		if tookOver {
+			if err := ctrl.removeDuplicateRegistriesMC(pool, managedKey); err != nil {
+				return err
+			}
+		}
 		if applied {
 			klog.Infof("Applied ImageConfig cluster on MachineConfigPool %v", pool.Name)
 			ctrlcommon.UpdateStateMetric(ctrlcommon.MCCSubControllerState, "machine-config-controller-container-runtime-config", "Sync Image Config", pool.Name)
+			syncResult = syncResultApplied
 		}
 	}
+	// Failing to record the listing does not affect the rendered configs, so only log it
+	if err := ctrl.syncRegistriesMachineConfigsConfigMap(registriesMCs); err != nil {
+		klog.Warningf("error updating ConfigMap %s/%s: %v", ctrlcommon.MCONamespace, registriesMachineConfigsConfigMapName, err)
+	}
+	ctrl.setLastObservedImageSync(imageConfigSyncState{generation: imgcfg.Generation, inputs: inputs, mcHashes: mcHashes})
+	ctrlcommon.MCCImageConfigSyncs.WithLabelValues(syncResult).Inc()
 	return nil
 }
 
-func (ctrl *Controller) syncIgnitionConfig(managedKey string, ignFile *ign3types.Config, pool *mcfgv1.MachineConfigPool, ownerRef metav1.OwnerReference) (bool, error) {
-	rawIgn, err := json.Marshal(ignFile)
+// getImageConfigSyncInputs returns a summary of the objects, other than the Image config spec, the registries
+// MachineConfigs are rendered from: the resource versions of the mirror sets, the generations of the ControllerConfig
+// and of the image policies, the desired release image and the built-in pools, with the architecture of their nodes
+// in poolArchs. Status updates of these objects do not change it, so that they do not cause the registries to be
+// rendered again.
+func (ctrl *Controller) getImageConfigSyncInputs(imgcfg *apicfgv1.Image, clusterVersionCfg *apicfgv1.ClusterVersion, poolArchs map[string]string) (string, error) {
+	var inputs []string
+	add := func(kind string, obj metav1.Object, version string) {
+		inputs = append(inputs, fmt.Sprintf("%s/%s/%s/%s", kind, obj.GetName(), obj.GetUID(), version))
+	}
+	// The Image config is tracked by its generation, its UID tells a recreated one apart
+	add("Image", imgcfg, "")
+
+	controllerConfig, err := ctrl.ccLister.Get(ctrlcommon.ControllerConfigName)
+	if err != nil {
+		return "", fmt.Errorf("could not get ControllerConfig %w", err)
+	}
+	add("ControllerConfig", controllerConfig, strconv.FormatInt(controllerConfig.Generation, 10))
+	if clusterVersionCfg != nil {
+		inputs = append(inputs, "ReleaseImage/"+clusterVersionCfg.Status.Desired.Image)
+	}
+
+	icsps, err := ctrl.icspLister.List(labels.Everything())
+	if err != nil && !errors.IsNotFound(err) {
+		return "", err
+	}
+	for _, icsp := range icsps {
+		add("ImageContentSourcePolicy", icsp, icsp.ResourceVersion)
+	}
+	idmss, err := ctrl.idmsLister.List(labels.Everything())
+	if err != nil && !errors.IsNotFound(err) {
+		return "", err
+	}
+	for _, idms := range idmss {
+		add("ImageDigestMirrorSet", idms, idms.ResourceVersion)
+	}
+	itmss, err := ctrl.itmsLister.List(labels.Everything())
+	if err != nil && !errors.IsNotFound(err) {
+		return "", err
+	}
+	for _, itms := range itmss {
+		add("ImageTagMirrorSet", itms, itms.ResourceVersion)
+	}
+
+	if ctrl.sigstoreAPIEnabled() && ctrl.addedPolicyObservers {
+		clusterImagePolicies, err := ctrl.clusterImagePolicyLister.List(labels.Everything())
+		if err != nil && !errors.IsNotFound(err) {
+			return "", err
+		}
+		for _, policy := range clusterImagePolicies {
+			add("ClusterImagePolicy", policy, strconv.FormatInt(policy.Generation, 10))
+		}
+		imagePolicies, err := ctrl.imagePolicyLister.List(labels.Everything())
+		if err != nil && !errors.IsNotFound(err) {
+			return "", err
+		}
+		for _, policy := range imagePolicies {
+			add("ImagePolicy/"+policy.Namespace, policy, strconv.FormatInt(policy.Generation, 10))
+		}
+	}
+
+	sel, err := metav1.LabelSelectorAsSelector(metav1.AddLabelToSelector(&metav1.LabelSelector{}, builtInLabelKey, ""))
+	if err != nil {
+		return "", err
+	}
+	pools, err := ctrl.mcpLister.List(sel)
+	if err != nil {
+		return "", err
+	}
+	for _, pool := range pools {
+		// The architecture of the pool selects the mirror sets of its registries.conf
+		add("MachineConfigPool", pool, poolArchs[pool.Name])
+	}
+
+	sort.Strings(inputs)
+	return strings.Join(inputs, ","), nil
+}
+
+// getBuiltInPoolArchitectures returns the architecture of the nodes of each built-in pool, see getNodesArchitecture
+func (ctrl *Controller) getBuiltInPoolArchitectures() (map[string]string, error) {
+	sel, err := metav1.LabelSelectorAsSelector(metav1.AddLabelToSelector(&metav1.LabelSelector{}, builtInLabelKey, ""))
+	if err != nil {
+		return nil, err
+	}
+	pools, err := ctrl.mcpLister.List(sel)
+	if err != nil {
+		return nil, err
+	}
+	poolArchs := make(map[string]string, len(pools))
+	for _, pool := range pools {
+		// A pool without a node selector has no nodes
+		if pool.Spec.NodeSelector == nil {
+			continue
+		}
+		nodeSel, err := metav1.LabelSelectorAsSelector(pool.Spec.NodeSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid node selector of MachineConfigPool %s: %w", pool.Name, err)
+		}
+		nodes, err := ctrl.nodeLister.List(nodeSel)
+		if err != nil {
+			return nil, fmt.Errorf("could not list the nodes of MachineConfigPool %s: %w", pool.Name, err)
+		}
+		poolArchs[pool.Name] = getNodesArchitecture(nodes)
+	}
+	return poolArchs, nil
+}
+
+// getLastObservedImageSync returns the state recorded by the last successful Image config sync
+func (ctrl *Controller) getLastObservedImageSync() imageConfigSyncState {
+	ctrl.lastObservedImageSyncLock.Lock()
+	defer ctrl.lastObservedImageSyncLock.Unlock()
+	return ctrl.lastObservedImageSync
+}
+
+// setLastObservedImageSync records the state of a successful Image config sync, an empty state forgets it
+func (ctrl *Controller) setLastObservedImageSync(state imageConfigSyncState) {
+	ctrl.lastObservedImageSyncLock.Lock()
+	defer ctrl.lastObservedImageSyncLock.Unlock()
+	ctrl.lastObservedImageSync = state
+}
+
+// registriesMCsUpToDate returns true if the registries MachineConfig of every built-in pool exists under its current
+// name, is owned by the Image config, was generated by this version of the controller and still holds the Ignition
+// config whose hash is in mcHashes, i.e. it was not edited since it was written
+func (ctrl *Controller) registriesMCsUpToDate(mcHashes map[string]string) (bool, error) {
+	sel, err := metav1.LabelSelectorAsSelector(metav1.AddLabelToSelector(&metav1.LabelSelector{}, builtInLabelKey, ""))
+	if err != nil {
+		return false, err
+	}
+	pools, err := ctrl.mcpLister.List(sel)
+	if err != nil {
+		return false, err
+	}
+	for _, pool := range pools {
+		// A nil client returns the current name without migrating the deprecated one
+		managedKey, err := getManagedKeyReg(pool, nil)
+		if err != nil {
+			return false, err
+		}
+		mc, err := ctrl.client.MachineconfigurationV1().MachineConfigs().Get(context.TODO(), managedKey, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("could not get MachineConfig %q: %w", managedKey, err)
+		}
+		if !isOwnedByImageConfig(mc) || mc.Annotations[ctrlcommon.GeneratedByControllerVersionAnnotationKey] != version.Hash ||
+			getIgnitionConfigHash(mc.Spec.Config.Raw) != mcHashes[managedKey] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// syncRegistriesMachineConfigsConfigMap writes data to the ConfigMap listing the registries MachineConfigs generated
+// from the Image config, creating it if needed. Nothing is written if it already holds the same data.
+func (ctrl *Controller) syncRegistriesMachineConfigsConfigMap(data map[string]string) error {
+	cm, err := ctrl.kubeClient.CoreV1().ConfigMaps(ctrlcommon.MCONamespace).Get(context.TODO(), registriesMachineConfigsConfigMapName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: registriesMachineConfigsConfigMapName, Namespace: ctrlcommon.MCONamespace},
+			Data:       data,
+		}
+		_, err = ctrl.kubeClient.CoreV1().ConfigMaps(ctrlcommon.MCONamespace).Create(context.TODO(), cm, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if equality.Semantic.DeepEqual(cm.Data, data) {
+		return nil
+	}
+	cm = cm.DeepCopy()
+	cm.Data = data
+	_, err = ctrl.kubeClient.CoreV1().ConfigMaps(ctrlcommon.MCONamespace).Update(context.TODO(), cm, metav1.UpdateOptions{})
+	return err
+}
+
+// removeImageConfigMCs deletes the registries MachineConfigs generated for the built-in pools from the cluster Image config
+// once it has been deleted. MachineConfigs with the same name but not owned by an Image config are left alone.
+func (ctrl *Controller) removeImageConfigMCs() error {
+	ctrl.setLastObservedImageSync(imageConfigSyncState{})
+	sel, err := metav1.LabelSelectorAsSelector(metav1.AddLabelToSelector(&metav1.LabelSelector{}, builtInLabelKey, ""))
 	if err != nil {
-		return false, fmt.Errorf("could not encode Ignition config: %w", err)
+		return err
+	}
+	mcpPools, err := ctrl.mcpLister.List(sel)
+	if err != nil {
+		return err
+	}
+	for _, pool := range mcpPools {
+		// A nil client returns the current name without migrating the deprecated one
+		managedKey, err := getManagedKeyReg(pool, nil)
+		if err != nil {
+			return err
+		}
+		for _, key := range []string{managedKey, getManagedKeyRegDeprecated(pool)} {
+			mc, err := ctrl.client.MachineconfigurationV1().MachineConfigs().Get(context.TODO(), key, metav1.GetOptions{})
+			if errors.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("could not get MachineConfig %q: %w", key, err)
+			}
+			if !isOwnedByImageConfig(mc) {
+				klog.V(2).Infof("Not removing MachineConfig %v as it is not owned by the Image config", key)
+				continue
+			}
+			required, err := ctrl.isRegistriesMCRequired(pool, key)
+			if err != nil {
+				return err
+			}
+			if required {
+				klog.Infof("Not removing registries MachineConfig %v of MachineConfigPool %v as the Image config was recreated and still requires it", key, pool.Name)
+				continue
+			}
+			if err := ctrl.client.MachineconfigurationV1().MachineConfigs().Delete(context.TODO(), key, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+				return fmt.Errorf("could not delete registries MachineConfig %q: %w", key, err)
+			}
+			klog.Infof("Removed registries MachineConfig %v of MachineConfigPool %v as the Image config was deleted", key, pool.Name)
+		}
+	}
+	err = ctrl.kubeClient.CoreV1().ConfigMaps(ctrlcommon.MCONamespace).Delete(context.TODO(), registriesMachineConfigsConfigMapName, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("could not delete ConfigMap %s/%s: %w", ctrlcommon.MCONamespace, registriesMachineConfigsConfigMapName, err)
+	}
+	return nil
+}
+
+// isRegistriesMCRequired returns true if deleting the registries MachineConfig mcName would leave the pool without the
+// registries config the Image config still requires, i.e. the Image config exists and no other registries MachineConfig
+// of the pool is owned by it. The Image config is read from the API, the lister may not have seen it being recreated.
+func (ctrl *Controller) isRegistriesMCRequired(pool *mcfgv1.MachineConfigPool, mcName string) (bool, error) {
+	_, err := ctrl.configClient.ConfigV1().Images().Get(context.TODO(), "cluster", metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("could not get the Image config: %w", err)
+	}
+	// A nil client returns the current name without migrating the deprecated one
+	managedKey, err := getManagedKeyReg(pool, nil)
+	if err != nil {
+		return false, err
+	}
+	for _, key := range []string{managedKey, getManagedKeyRegDeprecated(pool)} {
+		if key == mcName {
+			continue
+		}
+		mc, err := ctrl.client.MachineconfigurationV1().MachineConfigs().Get(context.TODO(), key, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return false, fmt.Errorf("could not get MachineConfig %q: %w", key, err)
+		}
+		if isOwnedByImageConfig(mc) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// isOwnedByImageConfig returns true if the MachineConfig has an owner reference to an Image config
+func isOwnedByImageConfig(mc *mcfgv1.MachineConfig) bool {
+	for _, oref := range mc.OwnerReferences {
+		gv, err := schema.ParseGroupVersion(oref.APIVersion)
+		if err == nil && gv.Group == apicfgv1.GroupName && oref.Kind == "Image" {
+			return true
+		}
+	}
+	return false
+}
+
+// removeDuplicateRegistriesMC deletes the registries MachineConfig of the pool that was left behind under the deprecated
+// name by an upgrade, e.g. one still owned by a ContainerRuntimeConfig. Both would render registries.conf and
+// policy.json for the pool, so only managedKey, owned by the Image config, is kept.
+func (ctrl *Controller) removeDuplicateRegistriesMC(pool *mcfgv1.MachineConfigPool, managedKey string) error {
+	deprecatedKey := getManagedKeyRegDeprecated(pool)
+	if deprecatedKey == managedKey {
+		return nil
+	}
+	mc, err := ctrl.client.MachineconfigurationV1().MachineConfigs().Get(context.TODO(), deprecatedKey, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not get MachineConfig %q: %w", deprecatedKey, err)
+	}
+	required, err := ctrl.isRegistriesMCRequired(pool, deprecatedKey)
+	if err != nil {
+		return err
+	}
+	if required {
+		return fmt.Errorf("not removing registries MachineConfig %q, MachineConfig %q does not configure the registries of MachineConfigPool %v yet", deprecatedKey, managedKey, pool.Name)
+	}
+	owners := make([]string, 0, len(mc.OwnerReferences))
+	for _, oref := range mc.OwnerReferences {
+		owners = append(owners, fmt.Sprintf("%s/%s", oref.Kind, oref.Name))
+	}
+	if err := ctrl.client.MachineconfigurationV1().MachineConfigs().Delete(context.TODO(), deprecatedKey, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("could not delete duplicate registries MachineConfig %q: %w", deprecatedKey, err)
+	}
+	klog.Infof("Removed registries MachineConfig %v owned by %v, MachineConfig %v now configures the registries of MachineConfigPool %v", deprecatedKey, owners, managedKey, pool.Name)
+	return nil
+}
+
+// isAppliedToMasterPool returns true if the current generation of the ContainerRuntimeConfig is already applied to the
+// master pool. The MachineConfig of the master pool records the generation it was rendered from, the ones rendered
+// before it was recorded, e.g. by the version before an upgrade, are trusted if the last sync of the current generation
+// succeeded. A failed sync also updates the observed generation, so it alone does not tell the generation was applied.
+func (ctrl *Controller) isAppliedToMasterPool(cfg *mcfgv1.ContainerRuntimeConfig, pools []*mcfgv1.MachineConfigPool) (bool, error) {
+	if cfg.Generation == 0 {
+		return false, nil
+	}
+	for _, pool := range pools {
+		if pool.Name != ctrlcommon.MachineConfigPoolMaster {
+			continue
+		}
+		managedKey, err := getManagedKeyCtrCfg(pool, ctrl.client, cfg)
+		if err != nil {
+			return false, err
+		}
+		mc, err := ctrl.client.MachineconfigurationV1().MachineConfigs().Get(context.TODO(), managedKey, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if !isOwnedBy(mc, *metav1.NewControllerRef(cfg, controllerKind)) {
+			return false, nil
+		}
+		if generation, ok := mc.Annotations[ctrlcommon.ContainerRuntimeConfigGenerationAnnotationKey]; ok {
+			return generation == strconv.FormatInt(cfg.Generation, 10), nil
+		}
+		return cfg.Status.ObservedGeneration >= cfg.Generation && len(cfg.Status.Conditions) > 0 &&
+			cfg.Status.Conditions[len(cfg.Status.Conditions)-1].Type == mcfgv1.ContainerRuntimeConfigSuccess, nil
+	}
+	return false, nil
+}
+
+// isOwnedBy returns true if ownerRef is the only owner of the MachineConfig
+func isOwnedBy(mc *mcfgv1.MachineConfig, ownerRef metav1.OwnerReference) bool {
+	return len(mc.OwnerReferences) == 1 && mc.OwnerReferences[0].Kind == ownerRef.Kind && mc.OwnerReferences[0].UID == ownerRef.UID
+}
+
+// hasOwnerOfOtherKind returns true if the MachineConfig is owned by an object of another kind than ownerRef
+func hasOwnerOfOtherKind(mc *mcfgv1.MachineConfig, ownerRef metav1.OwnerReference) bool {
+	for _, oref := range mc.OwnerReferences {
+		if oref.Kind != ownerRef.Kind {
+			return true
+		}
+	}
+	return false
+}
+
+func (ctrl *Controller) syncIgnitionConfig(managedKey string, ignFile *ign3types.Config, pool *mcfgv1.MachineConfigPool, ownerRef metav1.OwnerReference) (rawIgn []byte, applied, tookOver bool, err error) {
+	rawIgn, err = ctrl.marshalIgnition(ignFile)
+	if err != nil {
+		return nil, false, false, fmt.Errorf("could not encode Ignition config: %w", err)
+	}
+	// Never write a MachineConfig the nodes could not parse
+	if err := validateGeneratedIgnition(rawIgn); err != nil {
+		return nil, false, false, fmt.Errorf("invalid Ignition config for MachineConfig %v: %w", managedKey, err)
 	}
 	mc, err := ctrl.client.MachineconfigurationV1().MachineConfigs().Get(context.TODO(), managedKey, metav1.GetOptions{})
 	if err != nil && !errors.IsNotFound(err) {
-		return false, fmt.Errorf("could not find MachineConfig: %w", err)
+		return nil, false, false, fmt.Errorf("could not find MachineConfig: %w", err)
 	}
 	isNotFound := errors.IsNotFound(err)
-	if !isNotFound && equality.Semantic.DeepEqual(rawIgn, mc.Spec.Config.Raw) {
+	if !isNotFound && equality.Semantic.DeepEqual(rawIgn, mc.Spec.Config.Raw) && isOwnedBy(mc, ownerRef) {
 		// if the configuration for the registries is equal, we still need to compare
-		// the generated controller version because during an upgrade we need a new one
+		// the generated controller version because during an upgrade we need a new one.
+		// A MachineConfig left owned by something else, e.g. a ContainerRuntimeConfig, is always taken over.
 		mcCtrlVersion := mc.Annotations[ctrlcommon.GeneratedByControllerVersionAnnotationKey]
 		if mcCtrlVersion == version.Hash {
-			return false, nil
+			return rawIgn, false, false, nil
 		}
 	}
 	if isNotFound {
 		tempIgnCfg := ctrlcommon.NewIgnConfig()
 		mc, err = ctrlcommon.MachineConfigFromIgnConfig(pool.Name, managedKey, tempIgnCfg)
 		if err != nil {
-			return false, fmt.Errorf("could not create MachineConfig from new Ignition config: %w", err)
+			return nil, false, false, fmt.Errorf("could not create MachineConfig from new Ignition config: %w", err)
 		}
 	}
+	tookOver = !isNotFound && hasOwnerOfOtherKind(mc, ownerRef)
 	mc.Spec.Config.Raw = rawIgn
 	mc.ObjectMeta.Annotations = map[string]string{
 		ctrlcommon.GeneratedByControllerVersionAnnotationKey: version.Hash,
@@ -998,10 +2098,12 @@ func (ctrl *Controller) syncIgnitionConfig(managedKey string, ignFile *ign3types
 		_, err = ctrl.client.MachineconfigurationV1().MachineConfigs().Update(context.TODO(), mc, metav1.UpdateOptions{})
 	}
 
-	return true, err
+	return rawIgn, true, tookOver, err
 }
 
-func registriesConfigIgnition(templateDir string, controllerConfig *mcfgv1.ControllerConfig, role, releaseImage string,
+// registriesConfigIgnition renders the registries.conf, policy.json and sigstore configs of the pool role, whose nodes run on
+// arch. Only the mirror sets without an architecture and the ones for arch are configured in registries.conf.
+func registriesConfigIgnition(templateDir string, controllerConfig *mcfgv1.ControllerConfig, role, arch, releaseImage string,
 	insecureRegs, registriesBlocked, policyBlocked, allowedRegs, searchRegs []string,
 	icspRules []*apioperatorsv1alpha1.ImageContentSourcePolicy, idmsRules []*apicfgv1.ImageDigestMirrorSet, itmsRules []*apicfgv1.ImageTagMirrorSet,
 	clusterScopePolicies map[string]signature.PolicyRequirements, scopeNamespacePolicies map[string]map[string]signature.PolicyRequirements) (*ign3types.Config, error) {
@@ -1014,13 +2116,23 @@ func registriesConfigIgnition(templateDir string, controllerConfig *mcfgv1.Contr
 		err                          error
 	)
 
+	// The listers return the objects in no particular order, normalize the lists so that the same configuration
+	// always renders the same files and does not cause needless MachineConfig updates. The order of the search
+	// registries is the order they are tried in, so only their duplicates are dropped.
+	insecureRegs = sortedUniqueScopes(insecureRegs)
+	registriesBlocked = sortedUniqueScopes(registriesBlocked)
+	policyBlocked = sortedUniqueScopes(policyBlocked)
+	allowedRegs = sortedUniqueScopes(allowedRegs)
+	searchRegs = uniqueScopes(searchRegs)
+
 	// Generate the original registries config
 	_, originalRegistriesIgn, originalPolicyIgn, err := generateOriginalContainerRuntimeConfigs(templateDir, controllerConfig, role)
 	if err != nil {
 		return nil, fmt.Errorf("could not generate original ContainerRuntime Configs: %w", err)
 	}
 
-	if insecureRegs != nil || registriesBlocked != nil || len(icspRules) != 0 || len(idmsRules) != 0 || len(itmsRules) != 0 {
+	mirrorRules := registryMirrorRules{icsp: icspRules, idms: idmsRules, itms: itmsRules}.forArchitecture(arch)
+	if insecureRegs != nil || registriesBlocked != nil || !mirrorRules.isEmpty() {
 		if originalRegistriesIgn.Contents.Source == nil {
 			return nil, fmt.Errorf("original registries config is empty")
 		}
@@ -1028,7 +2140,7 @@ func registriesConfigIgnition(templateDir string, controllerConfig *mcfgv1.Contr
 		if err != nil {
 			return nil, fmt.Errorf("could not decode original registries config: %w", err)
 		}
-		registriesTOML, err = updateRegistriesConfig(contents, insecureRegs, registriesBlocked, icspRules, idmsRules, itmsRules)
+		registriesTOML, err = updateRegistriesConfig(contents, insecureRegs, registriesBlocked, mirrorRules)
 		if err != nil {
 			return nil, fmt.Errorf("could not update registries config with new changes: %w", err)
 		}
@@ -1055,6 +2167,15 @@ func registriesConfigIgnition(templateDir string, controllerConfig *mcfgv1.Contr
 		if err != nil {
 			return nil, err
 		}
+		// A policy.json identical to the one rendered by default is already on the nodes, writing it again only
+		// churns the MachineConfig, so it is left out. The namespaced policies above still inherit from it.
+		unchanged, err := isSamePolicyJSON(contents, policyJSON)
+		if err != nil {
+			return nil, err
+		}
+		if unchanged {
+			policyJSON = nil
+		}
 	}
 
 	generatedConfigFileList := []generatedConfigFile{
@@ -1068,7 +2189,10 @@ func registriesConfigIgnition(templateDir string, controllerConfig *mcfgv1.Contr
 		generatedConfigFileList = append(generatedConfigFileList, updateSearchRegistriesConfig(searchRegs)...)
 	}
 
-	registriesIgn := createNewIgnition(generatedConfigFileList)
+	registriesIgn, err := createNewIgnition(generatedConfigFileList)
+	if err != nil {
+		return nil, err
+	}
 	return &registriesIgn, nil
 }
 
@@ -1125,7 +2249,7 @@ func getValidScopePolicies(clusterImagePolicies []*apicfgv1alpha1.ClusterImagePo
 }
 
 func (ctrl *Controller) syncImagePolicyStatusOnly(namespace, imagepolicy, conditionType, reason, msg string, status metav1.ConditionStatus) {
-	statusUpdateErr := retry.RetryOnConflict(updateBackoff, func() error {
+	statusUpdateErr := retry.RetryOnConflict(ctrl.updateBackoff, func() error {
 		newImagePolicy, err := ctrl.configClient.ConfigV1alpha1().ImagePolicies(namespace).Get(context.TODO(), imagepolicy, metav1.GetOptions{})
 		if err != nil {
 			return err
@@ -1145,8 +2269,9 @@ func (ctrl *Controller) syncImagePolicyStatusOnly(namespace, imagepolicy, condit
 }
 
 // RunImageBootstrap generates MachineConfig objects for mcpPools that would have been generated by syncImageConfig,
-// except that mcfgv1.Image is not available.
-func RunImageBootstrap(templateDir string, controllerConfig *mcfgv1.ControllerConfig, mcpPools []*mcfgv1.MachineConfigPool, icspRules []*apioperatorsv1alpha1.ImageContentSourcePolicy,
+// except that mcfgv1.Image is not available. The nodes do not exist yet, poolArchs holds the architecture of the nodes
+// of each pool, e.g. from GetInstallConfigPoolArchitectures.
+func RunImageBootstrap(templateDir string, controllerConfig *mcfgv1.ControllerConfig, mcpPools []*mcfgv1.MachineConfigPool, poolArchs map[string]string, icspRules []*apioperatorsv1alpha1.ImageContentSourcePolicy,
 	idmsRules []*apicfgv1.ImageDigestMirrorSet, itmsRules []*apicfgv1.ImageTagMirrorSet, imgCfg *apicfgv1.Image, clusterImagePolicies []*apicfgv1alpha1.ClusterImagePolicy, imagePolicies []*apicfgv1alpha1.ImagePolicy,
 	featureGateAccess featuregates.FeatureGateAccess) ([]*mcfgv1.MachineConfig, error) {
 
@@ -1168,11 +2293,20 @@ func RunImageBootstrap(templateDir string, controllerConfig *mcfgv1.ControllerCo
 		}
 	}
 
+	icspRules, conflicts := preferIDMSOverICSP(icspRules, idmsRules)
+	if len(conflicts) > 0 {
+		klog.Warningf("imagecontentsourcepolicy mirrors for %v are ignored, these sources are also configured by imagedigestmirrorsets", conflicts)
+	}
+	itmsRules, conflicts = preferDigestOnlyOverTagMirrors(icspRules, itmsRules)
+	if len(conflicts) > 0 {
+		klog.Warningf("imagetagmirrorset mirrors for %v are ignored, these mirrors are digest-only as they are also configured by imagecontentsourcepolicies", conflicts)
+	}
+
 	// Read the search, insecure, blocked, and allowed registries from the cluster-wide Image CR if it is not nil
 	if imgCfg != nil {
 		insecureRegs = imgCfg.Spec.RegistrySources.InsecureRegistries
 		searchRegs = imgCfg.Spec.RegistrySources.ContainerRuntimeSearchRegistries
-		registriesBlocked, policyBlocked, allowedRegs, err = getValidBlockedAndAllowedRegistries(controllerConfig.Spec.ReleaseImage, &imgCfg.Spec, icspRules, idmsRules)
+		registriesBlocked, policyBlocked, allowedRegs, err = getValidBlockedAndAllowedRegistries(controllerConfig.Spec.ReleaseImage, getPauseImage(controllerConfig), &imgCfg.Spec, icspRules, idmsRules)
 		if err != nil && err != errParsingReference {
 			klog.V(2).Infof("%v, skipping....", err)
 		} else if err == errParsingReference {
@@ -1188,7 +2322,9 @@ func RunImageBootstrap(templateDir string, controllerConfig *mcfgv1.ControllerCo
 		if err != nil {
 			return nil, err
 		}
-		registriesIgn, err := registriesConfigIgnition(templateDir, controllerConfig, role, controllerConfig.Spec.ReleaseImage,
+		// Only the mirror sets for every architecture are configured for the pools whose architecture is not known,
+		// the others are added by the first sync of the Image config in the cluster
+		registriesIgn, err := registriesConfigIgnition(templateDir, controllerConfig, role, poolArchs[role], controllerConfig.Spec.ReleaseImage,
 			insecureRegs, registriesBlocked, policyBlocked, allowedRegs, searchRegs, icspRules, idmsRules, itmsRules, clusterScopePolicies, scopeNamespacePolicies)
 		if err != nil {
 			return nil, err
@@ -1211,9 +2347,17 @@ func RunImageBootstrap(templateDir string, controllerConfig *mcfgv1.ControllerCo
 	return res, nil
 }
 
-func (ctrl *Controller) popFinalizerFromContainerRuntimeConfig(ctrCfg *mcfgv1.ContainerRuntimeConfig) error {
-	return retry.RetryOnConflict(updateBackoff, func() error {
-		newcfg, err := ctrl.mccrLister.Get(ctrCfg.Name)
+func (ctrl *Controller) patchContainerRuntimeConfigs(name string, patch []byte) error {
+	_, err := ctrl.client.MachineconfigurationV1().ContainerRuntimeConfigs().Patch(context.TODO(), name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// removeFinalizersFromContainerRuntimeConfig removes the given finalizers from the ContainerRuntimeConfig
+func (ctrl *Controller) removeFinalizersFromContainerRuntimeConfig(ctrCfg *mcfgv1.ContainerRuntimeConfig, finalizers []string) error {
+	return retry.RetryOnConflict(ctrl.updateBackoff, func() error {
+		// Read from the API rather than the lister, the finalizers of the MachineConfigs applied in this
+		// sync have just been added and the cache may not have caught up yet
+		newcfg, err := ctrl.client.MachineconfigurationV1().ContainerRuntimeConfigs().Get(context.TODO(), ctrCfg.Name, metav1.GetOptions{})
 		if errors.IsNotFound(err) {
 			return nil
 		}
@@ -1227,7 +2371,12 @@ func (ctrl *Controller) popFinalizerFromContainerRuntimeConfig(ctrCfg *mcfgv1.Co
 		}
 
 		ctrCfgTmp := newcfg.DeepCopy()
-		ctrCfgTmp.Finalizers = append([]string{}, ctrCfg.Finalizers[1:]...)
+		ctrCfgTmp.Finalizers = []string{}
+		for _, finalizer := range newcfg.Finalizers {
+			if !ctrlcommon.InSlice(finalizer, finalizers) {
+				ctrCfgTmp.Finalizers = append(ctrCfgTmp.Finalizers, finalizer)
+			}
+		}
 
 		modJSON, err := json.Marshal(ctrCfgTmp)
 		if err != nil {
@@ -1242,13 +2391,8 @@ func (ctrl *Controller) popFinalizerFromContainerRuntimeConfig(ctrCfg *mcfgv1.Co
 	})
 }
 
-func (ctrl *Controller) patchContainerRuntimeConfigs(name string, patch []byte) error {
-	_, err := ctrl.client.MachineconfigurationV1().ContainerRuntimeConfigs().Patch(context.TODO(), name, types.MergePatchType, patch, metav1.PatchOptions{})
-	return err
-}
-
 func (ctrl *Controller) addFinalizerToContainerRuntimeConfig(ctrCfg *mcfgv1.ContainerRuntimeConfig, mc *mcfgv1.MachineConfig) error {
-	return retry.RetryOnConflict(updateBackoff, func() error {
+	return retry.RetryOnConflict(ctrl.updateBackoff, func() error {
 		newcfg, err := ctrl.mccrLister.Get(ctrCfg.Name)
 		if errors.IsNotFound(err) {
 			return nil
@@ -1284,24 +2428,35 @@ func (ctrl *Controller) addFinalizerToContainerRuntimeConfig(ctrCfg *mcfgv1.Cont
 	})
 }
 
-func (ctrl *Controller) getPoolsForContainerRuntimeConfig(config *mcfgv1.ContainerRuntimeConfig) ([]*mcfgv1.MachineConfigPool, error) {
-	pList, err := ctrl.mcpLister.List(labels.Everything())
+// getOverlaySizeConfigForPool returns the ContainerRuntimeConfig whose overlaySize applies to the pool and the number
+// of ContainerRuntimeConfigs selecting the pool that set one
+func (ctrl *Controller) getOverlaySizeConfigForPool(pool *mcfgv1.MachineConfigPool) (*mcfgv1.ContainerRuntimeConfig, int, error) {
+	pools, err := ctrl.mcpLister.List(labels.Everything())
 	if err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+	ctrcfgs, err := ctrl.mccrLister.List(labels.Everything())
+	if err != nil {
+		return nil, 0, err
 	}
+	return selectOverlaySizeConfig(pool, pools, ctrcfgs)
+}
 
-	selector, err := metav1.LabelSelectorAsSelector(config.Spec.MachineConfigPoolSelector)
+func (ctrl *Controller) getPoolsForContainerRuntimeConfig(config *mcfgv1.ContainerRuntimeConfig) ([]*mcfgv1.MachineConfigPool, error) {
+	pList, err := ctrl.mcpLister.List(labels.Everything())
 	if err != nil {
-		return nil, fmt.Errorf("invalid label selector: %w", err)
+		return nil, err
 	}
 
 	var pools []*mcfgv1.MachineConfigPool
 	for _, p := range pList {
-		// If a pool with a nil or empty selector creeps in, it should match nothing, not everything.
-		if selector.Empty() || !selector.Matches(labels.Set(p.Labels)) {
-			continue
+		selected, err := selectsPool(config, p)
+		if err != nil {
+			return nil, err
+		}
+		if selected {
+			pools = append(pools, p)
 		}
-		pools = append(pools, p)
 	}
 
 	if len(pools) == 0 {
@@ -2,31 +2,41 @@ package containerruntimeconfig
 
 import (
 	"context"
+	goerrs "errors"
 	"fmt"
 	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	"k8s.io/apimachinery/pkg/api/resource"
 
+	"github.com/BurntSushi/toml"
 	"github.com/clarketm/json"
+	"github.com/containers/image/v5/pkg/sysregistriesv2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"k8s.io/klog/v2"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/diff"
 	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeinformers "k8s.io/client-go/informers"
 	k8sfake "k8s.io/client-go/kubernetes/fake"
 	core "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 
 	ign3types "github.com/coreos/ignition/v2/config/v3_4/types"
 	apicfgv1 "github.com/openshift/api/config/v1"
@@ -42,6 +52,7 @@ import (
 	operatorinformer "github.com/openshift/client-go/operator/informers/externalversions"
 	"github.com/openshift/library-go/pkg/operator/configobserver/featuregates"
 	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	mtmpl "github.com/openshift/machine-config-operator/pkg/controller/template"
 	"github.com/openshift/machine-config-operator/pkg/daemon/constants"
 	"github.com/openshift/machine-config-operator/pkg/version"
 	"github.com/openshift/machine-config-operator/test/helpers"
@@ -58,6 +69,8 @@ var (
 
 const (
 	templateDir = "../../../templates"
+	// testPauseImage is the pause image of the ControllerConfig returned by newControllerConfig
+	testPauseImage = "pause-reg.io/openshift/pause:test"
 )
 
 type fixture struct {
@@ -77,11 +90,17 @@ type fixture struct {
 	itmsLister               []*apicfgv1.ImageTagMirrorSet
 	clusterImagePolicyLister []*apicfgv1alpha1.ClusterImagePolicy
 	imagePolicyLister        []*apicfgv1alpha1.ImagePolicy
+	nodeLister               []*corev1.Node
 
 	actions               []core.Action
 	skipActionsValidation bool
 
-	fgAccess featuregates.FeatureGateAccess
+	fgAccess    featuregates.FeatureGateAccess
+	retryConfig RetryConfig
+
+	mcfgInformers     informers.SharedInformerFactory
+	configInformers   configv1informer.SharedInformerFactory
+	operatorInformers operatorinformer.SharedInformerFactory
 
 	objects         []runtime.Object
 	imgObjects      []runtime.Object
@@ -143,6 +162,7 @@ func newControllerConfig(name string, platform apicfgv1.PlatformType) *mcfgv1.Co
 				},
 			},
 			ReleaseImage: "release-reg.io/myuser/myimage:test",
+			Images:       map[string]string{mtmpl.InfraImageKey: testPauseImage},
 		},
 	}
 	return cc
@@ -160,6 +180,18 @@ func newContainerRuntimeConfig(name string, ctrconf *mcfgv1.ContainerRuntimeConf
 	}
 }
 
+// acknowledgeMasterChanges sets the annotation a ContainerRuntimeConfig selecting the master pool needs to be applied
+func acknowledgeMasterChanges(cfg *mcfgv1.ContainerRuntimeConfig) {
+	metav1.SetMetaDataAnnotation(&cfg.ObjectMeta, ctrlcommon.ContainerRuntimeConfigAllowMasterChangesAnnotationKey, "true")
+}
+
+// rollOutMachineConfigs makes the pool report the MachineConfigs as part of its current rendered config
+func rollOutMachineConfigs(pool *mcfgv1.MachineConfigPool, mcNames ...string) {
+	for _, name := range mcNames {
+		pool.Status.Configuration.Source = append(pool.Status.Configuration.Source, corev1.ObjectReference{Kind: "MachineConfig", Name: name})
+	}
+}
+
 func newImageConfig(name string, regconf *apicfgv1.RegistrySources) *apicfgv1.Image {
 	return &apicfgv1.Image{
 		TypeMeta:   metav1.TypeMeta{APIVersion: apicfgv1.SchemeGroupVersion.String()},
@@ -264,6 +296,11 @@ func (f *fixture) newController() *Controller {
 	i := informers.NewSharedInformerFactory(f.client, noResyncPeriodFunc())
 	ci := configv1informer.NewSharedInformerFactory(f.imgClient, noResyncPeriodFunc())
 	oi := operatorinformer.NewSharedInformerFactory(f.operatorClient, noResyncPeriodFunc())
+	kubeClient := k8sfake.NewSimpleClientset()
+	ki := kubeinformers.NewSharedInformerFactory(kubeClient, noResyncPeriodFunc())
+	f.mcfgInformers = i
+	f.configInformers = ci
+	f.operatorInformers = oi
 	c := New(templateDir,
 		i.Machineconfiguration().V1().MachineConfigPools(),
 		i.Machineconfiguration().V1().ControllerConfigs(),
@@ -274,8 +311,10 @@ func (f *fixture) newController() *Controller {
 		ci,
 		oi.Operator().V1alpha1().ImageContentSourcePolicies(),
 		ci.Config().V1().ClusterVersions(),
-		k8sfake.NewSimpleClientset(), f.client, f.imgClient,
+		ki.Core().V1().Nodes(),
+		kubeClient, f.client, f.imgClient,
 		f.fgAccess,
+		f.retryConfig,
 	)
 
 	c.mcpListerSynced = alwaysReady
@@ -288,6 +327,7 @@ func (f *fixture) newController() *Controller {
 	c.clusterImagePolicyListerSynced = alwaysReady
 	c.imagePolicyListerSynced = alwaysReady
 	c.clusterVersionListerSynced = alwaysReady
+	c.nodeListerSynced = alwaysReady
 	c.eventRecorder = &record.FakeRecorder{}
 
 	stopCh := make(chan struct{})
@@ -298,6 +338,8 @@ func (f *fixture) newController() *Controller {
 	ci.WaitForCacheSync(stopCh)
 	oi.Start(stopCh)
 	oi.WaitForCacheSync(stopCh)
+	ki.Start(stopCh)
+	ki.WaitForCacheSync(stopCh)
 
 	for _, c := range f.ccLister {
 		i.Machineconfiguration().V1().ControllerConfigs().Informer().GetIndexer().Add(c)
@@ -329,6 +371,9 @@ func (f *fixture) newController() *Controller {
 	for _, c := range f.imagePolicyLister {
 		ci.Config().V1alpha1().ImagePolicies().Informer().GetIndexer().Add(c)
 	}
+	for _, c := range f.nodeLister {
+		ki.Core().V1().Nodes().Informer().GetIndexer().Add(c)
+	}
 
 	return c
 }
@@ -364,6 +409,76 @@ func (f *fixture) runController(mcpname string, expectError bool) {
 	f.validateActions()
 }
 
+// AssertSyncIdempotent creates a controller, syncs key with sync twice and fails the test if the second sync writes
+// anything, e.g. for AssertSyncIdempotent((*Controller).syncImageConfig, "cluster"). This catches resyncs of an
+// unchanged configuration churning MachineConfigs or statuses. The listers follow the writes of the fake clients as
+// the informers of a running controller would, so that the second sync sees the objects as the first one left them.
+// The controller is returned for further checks.
+func (f *fixture) AssertSyncIdempotent(sync func(c *Controller, key string) error, key string) *Controller {
+	f.t.Helper()
+	c := f.newController()
+	f.followWrites()
+
+	kubeClient := c.kubeClient.(*k8sfake.Clientset)
+	require.NoError(f.t, sync(c, key), "first sync of %s", key)
+	f.client.ClearActions()
+	f.imgClient.ClearActions()
+	kubeClient.ClearActions()
+	require.NoError(f.t, sync(c, key), "second sync of %s", key)
+
+	actions := append(append(f.client.Actions(), f.imgClient.Actions()...), kubeClient.Actions()...)
+	writes := filterWriteActions(actions)
+	for _, action := range writes {
+		f.t.Errorf("second sync of %s is expected to write nothing, got %s %s", key, action.GetVerb(), action.GetResource().Resource)
+	}
+	return c
+}
+
+// followWrites makes the listers of the resources the syncs write to pick up the writes of the fake client
+// as soon as they are made
+func (f *fixture) followWrites() {
+	indexers := map[string]cache.Indexer{
+		"containerruntimeconfigs": f.mcfgInformers.Machineconfiguration().V1().ContainerRuntimeConfigs().Informer().GetIndexer(),
+		"machineconfigpools":      f.mcfgInformers.Machineconfiguration().V1().MachineConfigPools().Informer().GetIndexer(),
+	}
+	f.client.PrependReactor("*", "*", func(action core.Action) (bool, runtime.Object, error) {
+		handled, obj, err := core.ObjectReaction(f.client.Tracker())(action)
+		indexer, ok := indexers[action.GetResource().Resource]
+		if err != nil || !ok {
+			return handled, obj, err
+		}
+		switch action.GetVerb() {
+		case "create", "update", "patch":
+			if err := indexer.Update(obj); err != nil {
+				return true, nil, err
+			}
+		case "delete":
+			cached, exists, err := indexer.GetByKey(action.(core.DeleteAction).GetName())
+			if err != nil {
+				return true, nil, err
+			}
+			if exists {
+				if err := indexer.Delete(cached); err != nil {
+					return true, nil, err
+				}
+			}
+		}
+		return handled, obj, err
+	})
+}
+
+// filterWriteActions returns the actions that change the state of a resource
+func filterWriteActions(actions []core.Action) []core.Action {
+	ret := []core.Action{}
+	for _, action := range actions {
+		switch action.GetVerb() {
+		case "create", "update", "patch", "delete", "delete-collection":
+			ret = append(ret, action)
+		}
+	}
+	return ret
+}
+
 // filterInformerActions filters list and watch actions for testing resources.
 // Since list and watch don't change resource state we can filter it to lower
 // noise level in our tests.
@@ -504,10 +619,10 @@ func verifyRegistriesConfigAndPolicyJSONContents(t *testing.T, mc *mcfgv1.Machin
 	// This is not testing updateRegistriesConfig, which has its own tests; this verifies the created object contains the expected
 	// configuration file.
 	// First get the valid blocked registries to ensure we don't block the registry where the release image is from
-	registriesBlocked, policyBlocked, allowed, _ := getValidBlockedAndAllowedRegistries(releaseImageReg, &imgcfg.Spec, icsps, idmss)
+	registriesBlocked, policyBlocked, allowed, _ := getValidBlockedAndAllowedRegistries(releaseImageReg, getPauseImage(newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)), &imgcfg.Spec, icsps, idmss)
 	expectedRegistriesConf, err := updateRegistriesConfig(templateRegistriesConfig,
 		imgcfg.Spec.RegistrySources.InsecureRegistries,
-		registriesBlocked, icsps, idmss, itmss)
+		registriesBlocked, registryMirrorRules{icsp: icsps, idms: idmss, itms: itmss})
 	require.NoError(t, err)
 	assert.Equal(t, mcName, mc.ObjectMeta.Name)
 
@@ -538,9 +653,12 @@ func verifyRegistriesConfigAndPolicyJSONContents(t *testing.T, mc *mcfgv1.Machin
 		regfile = ignCfg.Storage.Files[1]
 	}
 	assert.Equal(t, registriesConfigPath, regfile.Node.Path)
+	require.NotNil(t, regfile.Mode)
+	assert.Equal(t, defaultConfigFileMode, *regfile.Mode)
 	registriesConf, err := ctrlcommon.DecodeIgnitionFileContents(regfile.Contents.Source, regfile.Contents.Compression)
 	require.NoError(t, err)
 	assert.Equal(t, string(expectedRegistriesConf), string(registriesConf))
+	requireRegistriesConfAcceptedByCRIO(t, registriesConf)
 
 	clusterScopePolicies, scopeNamespacePolicies, err := getValidScopePolicies(clusterImagePolicies, imagePolicies, nil)
 	require.NoError(t, err)
@@ -557,6 +675,8 @@ func verifyRegistriesConfigAndPolicyJSONContents(t *testing.T, mc *mcfgv1.Machin
 			policyfile = ignCfg.Storage.Files[0]
 		}
 		assert.Equal(t, policyConfigPath, policyfile.Node.Path)
+		require.NotNil(t, policyfile.Mode)
+		assert.Equal(t, defaultConfigFileMode, *policyfile.Mode)
 		policyJSON, err := ctrlcommon.DecodeIgnitionFileContents(policyfile.Contents.Source, policyfile.Contents.Compression)
 		require.NoError(t, err)
 		assert.Equal(t, string(expectedPolicyJSON), string(policyJSON))
@@ -650,13 +770,13 @@ func TestContainerRuntimeConfigCreate(t *testing.T) {
 			f.ccLister = append(f.ccLister, cc)
 			f.mcpLister = append(f.mcpLister, mcp)
 			f.mcpLister = append(f.mcpLister, mcp2)
+			acknowledgeMasterChanges(ctrcfg1)
 			f.mccrLister = append(f.mccrLister, ctrcfg1)
 			f.objects = append(f.objects, ctrcfg1)
 
 			f.expectGetMachineConfigAction(mcs2)
 			f.expectGetMachineConfigAction(mcs1)
 			f.expectGetMachineConfigAction(mcs1)
-			f.expectUpdateContainerRuntimeConfig(ctrcfg1)
 			f.expectUpdateContainerRuntimeConfigRoot(ctrcfg1)
 			f.expectCreateMachineConfigAction(mcs1)
 			f.expectPatchContainerRuntimeConfig(ctrcfg1, ctrcfgPatchBytes)
@@ -690,13 +810,13 @@ func TestContainerRuntimeConfigUpdate(t *testing.T) {
 			f.ccLister = append(f.ccLister, cc)
 			f.mcpLister = append(f.mcpLister, mcp)
 			f.mcpLister = append(f.mcpLister, mcp2)
+			acknowledgeMasterChanges(ctrcfg1)
 			f.mccrLister = append(f.mccrLister, ctrcfg1)
 			f.objects = append(f.objects, ctrcfg1)
 
 			f.expectGetMachineConfigAction(mcsUpdate)
 			f.expectGetMachineConfigAction(mcs)
 			f.expectGetMachineConfigAction(mcs)
-			f.expectUpdateContainerRuntimeConfig(ctrcfg1)
 			f.expectUpdateContainerRuntimeConfigRoot(ctrcfg1)
 			f.expectCreateMachineConfigAction(mcs)
 			f.expectPatchContainerRuntimeConfig(ctrcfg1, ctrcfgPatchBytes)
@@ -739,7 +859,6 @@ func TestContainerRuntimeConfigUpdate(t *testing.T) {
 
 			f.expectGetMachineConfigAction(mcsUpdate)
 			f.expectGetMachineConfigAction(mcsUpdate)
-			f.expectUpdateContainerRuntimeConfig(ctrcfgUpdate)
 			f.expectUpdateMachineConfigAction(mcsUpdate)
 			f.expectPatchContainerRuntimeConfig(ctrcfgUpdate, ctrcfgPatchBytes)
 			f.expectUpdateContainerRuntimeConfig(ctrcfgUpdate)
@@ -1012,6 +1131,55 @@ func TestICSPUpdate(t *testing.T) {
 	}
 }
 
+// TestIDMSPreferredOverICSP ensures that ImageDigestMirrorSet rules replace ImageContentSourcePolicy rules configuring the
+// same source, while the other ICSP rules are still rendered.
+func TestIDMSPreferredOverICSP(t *testing.T) {
+	verifyOpts := registriesConfigAndPolicyVerifyOptions{
+		verifyPolicyJSON:                    false,
+		verifySearchRegsDropin:              false,
+		verifyImagePoliciesRegistriesConfig: false,
+	}
+
+	f := newFixture(t)
+	f.skipActionsValidation = true
+
+	cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+	mcp := helpers.NewMachineConfigPool("master", nil, helpers.MasterSelector, "v0")
+	mcp2 := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "v0")
+	imgcfg := newImageConfig("cluster", &apicfgv1.RegistrySources{InsecureRegistries: []string{"blah.io"}})
+	cvcfg := newClusterVersionConfig("version", "test.io/myuser/myimage:test")
+	icsp := newICSP("legacy", []apioperatorsv1alpha1.RepositoryDigestMirrors{
+		{Source: "shared-source.example.com", Mirrors: []string{"icsp-mirror.example.com"}},
+		{Source: "icsp-source.example.com", Mirrors: []string{"icsp-only-mirror.example.com"}},
+	})
+	idms := newIDMS("current", []apicfgv1.ImageDigestMirrors{
+		{Source: "shared-source.example.com", Mirrors: []apicfgv1.ImageMirror{"idms-mirror.example.com"}},
+	})
+
+	f.ccLister = append(f.ccLister, cc)
+	f.mcpLister = append(f.mcpLister, mcp, mcp2)
+	f.imgLister = append(f.imgLister, imgcfg)
+	f.icspLister = append(f.icspLister, icsp)
+	f.idmsLister = append(f.idmsLister, idms)
+	f.cvLister = append(f.cvLister, cvcfg)
+	f.imgObjects = append(f.imgObjects, imgcfg)
+	f.operatorObjects = append(f.operatorObjects, icsp)
+
+	c := f.newController()
+	require.NoError(t, c.syncImgHandler("cluster"))
+
+	// Only the ICSP rule for the source not configured by the IDMS remains
+	expectedICSP := newICSP("legacy", []apioperatorsv1alpha1.RepositoryDigestMirrors{
+		{Source: "icsp-source.example.com", Mirrors: []string{"icsp-only-mirror.example.com"}},
+	})
+	for _, pool := range []*mcfgv1.MachineConfigPool{mcp, mcp2} {
+		keyReg, _ := getManagedKeyReg(pool, nil)
+		f.verifyRegistriesConfigAndPolicyJSONContents(t, keyReg, imgcfg, expectedICSP, idms, nil, nil, nil, cc.Spec.ReleaseImage, verifyOpts)
+	}
+	// The lister objects must not be modified
+	assert.Len(t, icsp.Spec.RepositoryDigestMirrors, 2)
+}
+
 func TestIDMSUpdate(t *testing.T) {
 	verifyOpts := registriesConfigAndPolicyVerifyOptions{
 		verifyPolicyJSON:                    false,
@@ -1290,7 +1458,7 @@ func TestRunImageBootstrap(t *testing.T) {
 				// set FeatureGateSigstoreImageVerification enabled for testing
 				fgAccess := featuregates.NewHardcodedFeatureGateAccess([]apicfgv1.FeatureGateName{features.FeatureGateSigstoreImageVerification}, []apicfgv1.FeatureGateName{})
 
-				mcs, err := RunImageBootstrap("../../../templates", cc, pools, tc.icspRules, tc.idmsRules, tc.itmsRules, imgCfg, tc.clusterImagePolicies, tc.imagePolicies, fgAccess)
+				mcs, err := RunImageBootstrap("../../../templates", cc, pools, nil, tc.icspRules, tc.idmsRules, tc.itmsRules, imgCfg, tc.clusterImagePolicies, tc.imagePolicies, fgAccess)
 				require.NoError(t, err)
 
 				require.Len(t, mcs, len(pools))
@@ -1315,6 +1483,105 @@ func TestRunImageBootstrap(t *testing.T) {
 	}
 }
 
+// TestImageConfigMirrorArchitecture ensures that the registries.conf of each pool only configures the mirror sets
+// for the architecture its nodes report, along with the mirror sets for every architecture
+func TestImageConfigMirrorArchitecture(t *testing.T) {
+	withArch := func(obj metav1.Object, arch string) {
+		obj.SetAnnotations(map[string]string{ctrlcommon.MirrorSetArchitectureAnnotationKey: arch})
+	}
+	allIDMS := newIDMS("all", []apicfgv1.ImageDigestMirrors{
+		{Source: "shared.example.com", Mirrors: []apicfgv1.ImageMirror{"shared-mirror.example.com"}},
+	})
+	amdIDMS := newIDMS("amd64", []apicfgv1.ImageDigestMirrors{
+		{Source: "source.example.com", Mirrors: []apicfgv1.ImageMirror{"amd64-mirror.example.com"}},
+	})
+	withArch(amdIDMS, "amd64")
+	armIDMS := newIDMS("arm64", []apicfgv1.ImageDigestMirrors{
+		{Source: "source.example.com", Mirrors: []apicfgv1.ImageMirror{"arm64-mirror.example.com"}},
+	})
+	withArch(armIDMS, "arm64")
+	armITMS := newITMS("arm64-tags", []apicfgv1.ImageTagMirrors{
+		{Source: "tags.example.com", Mirrors: []apicfgv1.ImageMirror{"arm64-tag-mirror.example.com"}},
+	})
+	withArch(armITMS, "arm64")
+
+	// mirrorsByPrefix returns the mirror locations configured for each registry of the registries.conf of mc
+	mirrorsByPrefix := func(mc *mcfgv1.MachineConfig) map[string][]string {
+		data, err := DecodeGeneratedConfigFile(mc, registriesConfigPath)
+		require.NoError(t, err)
+		mirrors := map[string][]string{}
+		for _, reg := range requireRegistriesConfAcceptedByCRIO(t, data) {
+			for _, mirror := range reg.Mirrors {
+				mirrors[reg.Prefix] = append(mirrors[reg.Prefix], mirror.Location)
+			}
+		}
+		return mirrors
+	}
+	newNode := func(name, role, arch string) *corev1.Node {
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"node-role/" + role: ""}}}
+		node.Status.NodeInfo.Architecture = arch
+		return node
+	}
+
+	f := newFixture(t)
+	f.skipActionsValidation = true
+
+	cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+	masterPool := helpers.NewMachineConfigPool("master", nil, helpers.MasterSelector, "v0")
+	workerPool := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "v0")
+	infraPool := helpers.NewMachineConfigPool("infra", nil, helpers.InfraSelector, "v0")
+	imgcfg := newImageConfig("cluster", &apicfgv1.RegistrySources{})
+	cvcfg := newClusterVersionConfig("version", "test.io/myuser/myimage:test")
+
+	f.ccLister = append(f.ccLister, cc)
+	f.mcpLister = append(f.mcpLister, masterPool, workerPool, infraPool)
+	f.imgLister = append(f.imgLister, imgcfg)
+	f.cvLister = append(f.cvLister, cvcfg)
+	f.idmsLister = append(f.idmsLister, allIDMS, amdIDMS, armIDMS)
+	f.itmsLister = append(f.itmsLister, armITMS)
+	f.imgObjects = append(f.imgObjects, imgcfg, allIDMS, amdIDMS, armIDMS, armITMS)
+	// The infra pool mixes architectures, so only the mirror sets for every architecture can be configured for it
+	f.nodeLister = append(f.nodeLister,
+		newNode("master-0", "master", "amd64"),
+		newNode("worker-0", "worker", "arm64"),
+		newNode("worker-1", "worker", "arm64"),
+		newNode("infra-0", "infra", "amd64"),
+		newNode("infra-1", "infra", "arm64"),
+	)
+
+	c := f.newController()
+	require.NoError(t, c.syncImgHandler("cluster"))
+
+	getMC := func(name string) *mcfgv1.MachineConfig {
+		mc, err := c.client.MachineconfigurationV1().MachineConfigs().Get(context.TODO(), name, metav1.GetOptions{})
+		require.NoError(t, err)
+		return mc
+	}
+	assert.Equal(t, map[string][]string{
+		"shared.example.com": {"shared-mirror.example.com"},
+		"source.example.com": {"amd64-mirror.example.com"},
+	}, mirrorsByPrefix(getMC("99-master-generated-registries")))
+	assert.Equal(t, map[string][]string{
+		"shared.example.com": {"shared-mirror.example.com"},
+		"source.example.com": {"arm64-mirror.example.com"},
+		"tags.example.com":   {"arm64-tag-mirror.example.com"},
+	}, mirrorsByPrefix(getMC("99-worker-generated-registries")))
+	assert.Equal(t, map[string][]string{
+		"shared.example.com": {"shared-mirror.example.com"},
+	}, mirrorsByPrefix(getMC("99-infra-generated-registries")))
+
+	// The nodes are not known at bootstrap, the architecture of the pools is given instead. Only the mirror sets for
+	// every architecture are configured for the pools without one.
+	fgAccess := featuregates.NewHardcodedFeatureGateAccess(nil, []apicfgv1.FeatureGateName{features.FeatureGateSigstoreImageVerification})
+	mcs, err := RunImageBootstrap("../../../templates", cc, []*mcfgv1.MachineConfigPool{masterPool, workerPool, infraPool}, map[string]string{"master": "amd64", "worker": "arm64"}, nil,
+		[]*apicfgv1.ImageDigestMirrorSet{allIDMS, amdIDMS, armIDMS}, []*apicfgv1.ImageTagMirrorSet{armITMS}, nil, nil, nil, fgAccess)
+	require.NoError(t, err)
+	require.Len(t, mcs, 3)
+	for _, mc := range mcs {
+		assert.Equal(t, mirrorsByPrefix(getMC(mc.Name)), mirrorsByPrefix(mc), "MachineConfig %s", mc.Name)
+	}
+}
+
 // TestRegistriesValidation tests the validity of registries allowed to be listed
 // under blocked registries
 func TestRegistriesValidation(t *testing.T) {
@@ -1407,7 +1674,7 @@ func TestRegistriesValidation(t *testing.T) {
 	for _, test := range failureTests {
 		imgcfg := newImageConfig(test.name, test.config)
 		cvcfg := newClusterVersionConfig("version", "blah.io/payload/myimage@sha256:4207ba569ff014931f1b5d125fe3751936a768e119546683c899eb09f3cdceb0")
-		registriesBlocked, _, _, err := getValidBlockedAndAllowedRegistries(cvcfg.Status.Desired.Image, &imgcfg.Spec, nil, test.idmsRules)
+		registriesBlocked, _, _, err := getValidBlockedAndAllowedRegistries(cvcfg.Status.Desired.Image, "", &imgcfg.Spec, nil, test.idmsRules)
 		if err == nil {
 			t.Errorf("%s: failed", test.name)
 		}
@@ -1422,7 +1689,7 @@ func TestRegistriesValidation(t *testing.T) {
 	for _, test := range successTests {
 		imgcfg := newImageConfig(test.name, test.config)
 		cvcfg := newClusterVersionConfig("version", "blah.io/payload/myimage@sha256:4207ba569ff014931f1b5d125fe3751936a768e119546683c899eb09f3cdceb0")
-		registriesBlocked, policyBlocked, allowed, err := getValidBlockedAndAllowedRegistries(cvcfg.Status.Desired.Image, &imgcfg.Spec, nil, test.idmsRules)
+		registriesBlocked, policyBlocked, allowed, err := getValidBlockedAndAllowedRegistries(cvcfg.Status.Desired.Image, "", &imgcfg.Spec, nil, test.idmsRules)
 		if err != nil {
 			t.Errorf("%s: failed", test.name)
 		}
@@ -1437,11 +1704,14 @@ func TestRegistriesValidation(t *testing.T) {
 func TestContainerRuntimeConfigOptions(t *testing.T) {
 	var (
 		invalidPidsLimit int64 = 10
+		tinyPidsLimit    int64 = 1
 		validPidsLimit   int64 = 2048
 		validZerolimit   int64 = 0
 		invalidNegLimit  int64 = -10
 		three                  = resource.MustParse("3k")
 		ten                    = resource.MustParse("10k")
+		zeroLogSize            = resource.MustParse("0")
+		negativeLogSize        = resource.MustParse("-1")
 	)
 	failureTests := []struct {
 		name   string
@@ -1453,6 +1723,12 @@ func TestContainerRuntimeConfigOptions(t *testing.T) {
 				PidsLimit: &invalidPidsLimit,
 			},
 		},
+		{
+			name: "pids limit of a single process",
+			config: &mcfgv1.ContainerRuntimeConfiguration{
+				PidsLimit: &tinyPidsLimit,
+			},
+		},
 		{
 			name: "invalid negative pids limit",
 			config: &mcfgv1.ContainerRuntimeConfiguration{
@@ -1465,6 +1741,12 @@ func TestContainerRuntimeConfigOptions(t *testing.T) {
 				LogSizeMax: &three,
 			},
 		},
+		{
+			name: "zero max log size",
+			config: &mcfgv1.ContainerRuntimeConfiguration{
+				LogSizeMax: &zeroLogSize,
+			},
+		},
 		{
 			name: "inalid value of log level",
 			config: &mcfgv1.ContainerRuntimeConfiguration{
@@ -1477,6 +1759,12 @@ func TestContainerRuntimeConfigOptions(t *testing.T) {
 				DefaultRuntime: "invalid",
 			},
 		},
+		{
+			name: "default runtime that is not defined",
+			config: &mcfgv1.ContainerRuntimeConfiguration{
+				DefaultRuntime: "kata",
+			},
+		},
 	}
 
 	successTests := []struct {
@@ -1501,6 +1789,12 @@ func TestContainerRuntimeConfigOptions(t *testing.T) {
 				LogSizeMax: &ten,
 			},
 		},
+		{
+			name: "deprecated negative max log size",
+			config: &mcfgv1.ContainerRuntimeConfiguration{
+				LogSizeMax: &negativeLogSize,
+			},
+		},
 		{
 			name: "valid log level",
 			config: &mcfgv1.ContainerRuntimeConfiguration{
@@ -1522,6 +1816,11 @@ func TestContainerRuntimeConfigOptions(t *testing.T) {
 		if err == nil {
 			t.Errorf("%s: failed", test.name)
 		}
+		assert.Equal(t, err, ValidateContainerRuntimeConfigSpec(ctrcfg.Spec), test.name)
+		var fieldErr *FieldValidationError
+		if err != nil && !goerrs.As(err, &fieldErr) {
+			t.Errorf("%s: expected a FieldValidationError, got %v", test.name, err)
+		}
 	}
 
 	// Successful Tests
@@ -1531,6 +1830,7 @@ func TestContainerRuntimeConfigOptions(t *testing.T) {
 		if err != nil {
 			t.Errorf("%s: failed with %v. should have succeeded", test.name, err)
 		}
+		assert.NoError(t, ValidateContainerRuntimeConfigSpec(ctrcfg.Spec), test.name)
 	}
 }
 
@@ -1680,6 +1980,8 @@ func TestContainerruntimeConfigResync(t *testing.T) {
 			f.ccLister = append(f.ccLister, cc)
 			f.mcpLister = append(f.mcpLister, mcp)
 			f.mcpLister = append(f.mcpLister, mcp2)
+			acknowledgeMasterChanges(ccr1)
+			acknowledgeMasterChanges(ccr2)
 			f.mccrLister = append(f.mccrLister, ccr1)
 			f.objects = append(f.objects, ccr1)
 
@@ -1746,6 +2048,8 @@ func TestAddAnnotationExistingContainerRuntimeConfig(t *testing.T) {
 			f.ccLister = append(f.ccLister, cc)
 			f.mcpLister = append(f.mcpLister, mcp)
 			f.mcpLister = append(f.mcpLister, mcp2)
+			acknowledgeMasterChanges(ctrc)
+			acknowledgeMasterChanges(ctrc1)
 			f.mccrLister = append(f.mccrLister, ctrc, ctrc1)
 			f.objects = append(f.objects, ctrc, ctrc1, ctrcfgMC)
 
@@ -1806,6 +2110,7 @@ func TestCleanUpDuplicatedMC(t *testing.T) {
 			ccr1.SetAnnotations(map[string]string{
 				ctrlcommon.MCNameSuffixAnnotationKey: "1",
 			})
+			acknowledgeMasterChanges(ccr1)
 			f.mccrLister = append(f.mccrLister, ccr1)
 			f.objects = append(f.objects, ccr1)
 
@@ -1861,6 +2166,175 @@ func TestCleanUpDuplicatedMC(t *testing.T) {
 	}
 }
 
+// TestCleanUpDuplicatedMCPoolUpdating ensures a degraded MC that is part of the config a pool is still rolling out
+// is kept without failing the ContainerRuntimeConfig, and deleted once no pool references it anymore.
+func TestCleanUpDuplicatedMCPoolUpdating(t *testing.T) {
+	v := version.Hash
+	version.Hash = "3.2.0"
+	defer func() {
+		version.Hash = v
+	}()
+
+	f := newFixture(t)
+	f.skipActionsValidation = true
+
+	cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+	mcp := helpers.NewMachineConfigPool("master", nil, helpers.MasterSelector, "v0")
+	oldMCName := "99-master-generated-containerruntime"
+	// The pool is rolling out a new rendered config, both the old and the new one include the degraded MC
+	mcp.Spec.Configuration.Name = "rendered-master-new"
+	mcp.Spec.Configuration.Source = []corev1.ObjectReference{{Name: "00-master"}, {Name: oldMCName}}
+	mcp.Status.Configuration.Name = "rendered-master-old"
+	mcp.Status.Configuration.Source = []corev1.ObjectReference{{Name: "00-master"}, {Name: oldMCName}}
+	ccr := newContainerRuntimeConfig("log-level-1", &mcfgv1.ContainerRuntimeConfiguration{LogLevel: "debug"}, metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/master", ""))
+	ccr.SetAnnotations(map[string]string{
+		ctrlcommon.MCNameSuffixAnnotationKey: "1",
+	})
+
+	f.ccLister = append(f.ccLister, cc)
+	f.mcpLister = append(f.mcpLister, mcp)
+	acknowledgeMasterChanges(ccr)
+	f.mccrLister = append(f.mccrLister, ccr)
+	f.objects = append(f.objects, ccr)
+
+	ctrl := f.newController()
+
+	oldMC := mcfgv1.MachineConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        oldMCName,
+			UID:         types.UID(utilrand.String(5)),
+			Annotations: map[string]string{ctrlcommon.GeneratedByControllerVersionAnnotationKey: "3.1.0"},
+		},
+	}
+	_, err := ctrl.client.MachineconfigurationV1().MachineConfigs().Create(context.TODO(), &oldMC, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	// The degraded MC is kept while the pool is updating, without failing the ContainerRuntimeConfig
+	require.NoError(t, ctrl.syncHandler(getKey(ccr, t)))
+	_, err = ctrl.client.MachineconfigurationV1().MachineConfigs().Get(context.TODO(), oldMCName, metav1.GetOptions{})
+	require.NoError(t, err, "the MC used by the updating pool should be preserved")
+	latest, err := ctrl.mccrLister.Get(ccr.Name)
+	require.NoError(t, err)
+	lastCondition := latest.Status.Conditions[len(latest.Status.Conditions)-1]
+	assert.NotEqual(t, mcfgv1.ContainerRuntimeConfigFailure, lastCondition.Type)
+
+	// Once the pool no longer references it, the degraded MC is deleted
+	mcp.Spec.Configuration.Source = []corev1.ObjectReference{{Name: "00-master"}, {Name: "99-master-generated-containerruntime-1"}}
+	mcp.Status.Configuration = mcp.Spec.Configuration
+	require.NoError(t, ctrl.syncHandler(getKey(ccr, t)))
+	_, err = ctrl.client.MachineconfigurationV1().MachineConfigs().Get(context.TODO(), oldMCName, metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err), "the MC should be deleted once no pool references it")
+	_, err = ctrl.client.MachineconfigurationV1().MachineConfigs().Get(context.TODO(), "99-master-generated-containerruntime-1", metav1.GetOptions{})
+	assert.NoError(t, err)
+}
+
+// TestCleanUpDuplicatedMCReferencedByPool ensures a degraded MC referenced by the spec or the status configuration of
+// a pool is kept, whether or not the pool is updating, and that the other degraded MCs are still deleted.
+func TestCleanUpDuplicatedMCReferencedByPool(t *testing.T) {
+	v := version.Hash
+	version.Hash = "3.2.0"
+	defer func() {
+		version.Hash = v
+	}()
+
+	oldMCName := "99-worker-generated-containerruntime"
+	unreferencedMCName := "99-infra-generated-containerruntime"
+	tests := []struct {
+		name   string
+		spec   []corev1.ObjectReference
+		status []corev1.ObjectReference
+	}{
+		{
+			name:   "referenced by spec and status of an updated pool",
+			spec:   []corev1.ObjectReference{{Name: "00-worker"}, {Name: oldMCName}},
+			status: []corev1.ObjectReference{{Name: "00-worker"}, {Name: oldMCName}},
+		},
+		{
+			name:   "referenced by spec only",
+			spec:   []corev1.ObjectReference{{Name: "00-worker"}, {Name: oldMCName}},
+			status: []corev1.ObjectReference{{Name: "00-worker"}},
+		},
+		{
+			name:   "referenced by status only",
+			spec:   []corev1.ObjectReference{{Name: "00-worker"}},
+			status: []corev1.ObjectReference{{Name: "00-worker"}, {Name: oldMCName}},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.newController()
+			mcp := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "v0")
+			mcp.Spec.Configuration.Source = tc.spec
+			mcp.Status.Configuration.Source = tc.status
+			f.mcpLister = append(f.mcpLister, mcp)
+			ctrl := f.newController()
+
+			for _, name := range []string{oldMCName, unreferencedMCName} {
+				mc := mcfgv1.MachineConfig{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        name,
+						Annotations: map[string]string{ctrlcommon.GeneratedByControllerVersionAnnotationKey: "3.1.0"},
+					},
+				}
+				_, err := ctrl.client.MachineconfigurationV1().MachineConfigs().Create(context.TODO(), &mc, metav1.CreateOptions{})
+				require.NoError(t, err)
+			}
+
+			kept, err := ctrl.cleanUpDuplicatedMC()
+			require.NoError(t, err)
+			assert.Equal(t, []string{oldMCName}, kept)
+			_, err = ctrl.client.MachineconfigurationV1().MachineConfigs().Get(context.TODO(), oldMCName, metav1.GetOptions{})
+			assert.NoError(t, err)
+			_, err = ctrl.client.MachineconfigurationV1().MachineConfigs().Get(context.TODO(), unreferencedMCName, metav1.GetOptions{})
+			assert.True(t, errors.IsNotFound(err))
+		})
+	}
+}
+
+// TestCleanUpDuplicatedMCKeptReportsSuccess ensures that keeping a degraded MC referenced by a pool that rolled out
+// the ContainerRuntimeConfig still reports Success.
+func TestCleanUpDuplicatedMCKeptReportsSuccess(t *testing.T) {
+	v := version.Hash
+	version.Hash = "3.2.0"
+	defer func() {
+		version.Hash = v
+	}()
+
+	f := newFixture(t)
+	f.skipActionsValidation = true
+	cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+	mcp := helpers.NewMachineConfigPool("infra", nil, helpers.InfraSelector, "v0")
+	ccr := newContainerRuntimeConfig("log-level", &mcfgv1.ContainerRuntimeConfiguration{LogLevel: "debug"}, metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/infra", ""))
+	f.ccLister = append(f.ccLister, cc)
+	f.mcpLister = append(f.mcpLister, mcp)
+	f.mccrLister = append(f.mccrLister, ccr)
+	f.objects = append(f.objects, ccr)
+	ctrl := f.newController()
+
+	oldMCName := "99-infra-generated-containerruntime-old"
+	oldMC := mcfgv1.MachineConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        oldMCName,
+			Annotations: map[string]string{ctrlcommon.GeneratedByControllerVersionAnnotationKey: "3.1.0"},
+		},
+	}
+	_, err := ctrl.client.MachineconfigurationV1().MachineConfigs().Create(context.TODO(), &oldMC, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	managedKey, err := getManagedKeyCtrCfg(mcp, ctrl.client, ccr)
+	require.NoError(t, err)
+	mcp.Spec.Configuration.Source = []corev1.ObjectReference{{Name: oldMCName}}
+	rollOutMachineConfigs(mcp, oldMCName, managedKey)
+
+	require.NoError(t, ctrl.syncHandler(getKey(ccr, t)))
+	_, err = ctrl.client.MachineconfigurationV1().MachineConfigs().Get(context.TODO(), oldMCName, metav1.GetOptions{})
+	require.NoError(t, err)
+	latest, err := ctrl.mccrLister.Get(ccr.Name)
+	require.NoError(t, err)
+	assert.Equal(t, mcfgv1.ContainerRuntimeConfigSuccess, latest.Status.Conditions[len(latest.Status.Conditions)-1].Type)
+}
+
 func TestClusterImagePolicyCreate(t *testing.T) {
 	verifyOpts := registriesConfigAndPolicyVerifyOptions{
 		verifyPolicyJSON:                    true,
@@ -2023,3 +2497,2426 @@ func TestImagePolicyCreate(t *testing.T) {
 		})
 	}
 }
+
+// TestMirrorRulesWarningThreshold ensures that a warning event is emitted on the image config when the
+// total number of mirror rules goes over mirrorRulesWarningThreshold, and that the sync still succeeds.
+func TestMirrorRulesWarningThreshold(t *testing.T) {
+	newMirrorRules := func(n int) []apicfgv1.ImageDigestMirrors {
+		var rules []apicfgv1.ImageDigestMirrors
+		for i := 0; i < n; i++ {
+			rules = append(rules, apicfgv1.ImageDigestMirrors{
+				Source:  fmt.Sprintf("source-%d.example.com", i),
+				Mirrors: []apicfgv1.ImageMirror{apicfgv1.ImageMirror(fmt.Sprintf("mirror-%d.example.com", i))},
+			})
+		}
+		return rules
+	}
+
+	tests := []struct {
+		name        string
+		numRules    int
+		expectEvent bool
+	}{
+		{
+			name:        "at the threshold",
+			numRules:    mirrorRulesWarningThreshold,
+			expectEvent: false,
+		},
+		{
+			name:        "over the threshold",
+			numRules:    mirrorRulesWarningThreshold + 1,
+			expectEvent: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.skipActionsValidation = true
+
+			cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+			mcp := helpers.NewMachineConfigPool("master", nil, helpers.MasterSelector, "v0")
+			imgcfg := newImageConfig("cluster", &apicfgv1.RegistrySources{})
+			cvcfg := newClusterVersionConfig("version", "test.io/myuser/myimage:test")
+			// Split the rules across an IDMS and an ICSP to make sure both are counted
+			idms := newIDMS("many-rules", newMirrorRules(test.numRules-1))
+			icsp := newICSP("one-rule", []apioperatorsv1alpha1.RepositoryDigestMirrors{
+				{Source: "icsp-source.example.com", Mirrors: []string{"icsp-mirror.example.com"}},
+			})
+
+			f.ccLister = append(f.ccLister, cc)
+			f.mcpLister = append(f.mcpLister, mcp)
+			f.imgLister = append(f.imgLister, imgcfg)
+			f.cvLister = append(f.cvLister, cvcfg)
+			f.idmsLister = append(f.idmsLister, idms)
+			f.icspLister = append(f.icspLister, icsp)
+			f.imgObjects = append(f.imgObjects, imgcfg)
+
+			c := f.newController()
+			recorder := record.NewFakeRecorder(10)
+			c.eventRecorder = recorder
+
+			require.NoError(t, c.syncImgHandler("cluster"))
+
+			select {
+			case event := <-recorder.Events:
+				require.True(t, test.expectEvent, "unexpected event: %s", event)
+				require.Contains(t, event, "MirrorRulesLimitExceeded")
+				require.Contains(t, event, fmt.Sprintf("%d mirror rules", test.numRules))
+			default:
+				require.False(t, test.expectEvent, "expected a MirrorRulesLimitExceeded event")
+			}
+		})
+	}
+}
+
+// TestDigestOnlyMirrorConflict ensures that an ImageTagMirrorSet mirror also configured by an ImageContentSourcePolicy
+// for the same source is only used for pulls by digest, and that a warning event is emitted on the image config.
+func TestDigestOnlyMirrorConflict(t *testing.T) {
+	f := newFixture(t)
+	f.skipActionsValidation = true
+
+	cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+	mcp := helpers.NewMachineConfigPool("master", nil, helpers.MasterSelector, "v0")
+	imgcfg := newImageConfig("cluster", &apicfgv1.RegistrySources{})
+	cvcfg := newClusterVersionConfig("version", "test.io/myuser/myimage:test")
+	icsp := newICSP("digest-only", []apioperatorsv1alpha1.RepositoryDigestMirrors{
+		{Source: "conflict.example.com", Mirrors: []string{"mirror.example.com"}},
+	})
+	itms := newITMS("tags", []apicfgv1.ImageTagMirrors{
+		{Source: "conflict.example.com", Mirrors: []apicfgv1.ImageMirror{"mirror.example.com"}},
+	})
+
+	f.ccLister = append(f.ccLister, cc)
+	f.mcpLister = append(f.mcpLister, mcp)
+	f.imgLister = append(f.imgLister, imgcfg)
+	f.cvLister = append(f.cvLister, cvcfg)
+	f.icspLister = append(f.icspLister, icsp)
+	f.itmsLister = append(f.itmsLister, itms)
+	f.imgObjects = append(f.imgObjects, imgcfg)
+
+	c := f.newController()
+	recorder := record.NewFakeRecorder(10)
+	c.eventRecorder = recorder
+
+	require.NoError(t, c.syncImgHandler("cluster"))
+
+	select {
+	case event := <-recorder.Events:
+		assert.Contains(t, event, "DigestOnlyMirrorConflict")
+		assert.Contains(t, event, "conflict.example.com")
+	default:
+		t.Fatal("expected a DigestOnlyMirrorConflict event")
+	}
+
+	keyReg, err := getManagedKeyReg(mcp, nil)
+	require.NoError(t, err)
+	mc, err := c.client.MachineconfigurationV1().MachineConfigs().Get(context.TODO(), keyReg, metav1.GetOptions{})
+	require.NoError(t, err)
+	ignCfg, err := ctrlcommon.ParseAndConvertConfig(mc.Spec.Config.Raw)
+	require.NoError(t, err)
+	registriesConf := ""
+	for _, file := range ignCfg.Storage.Files {
+		if file.Node.Path == registriesConfigPath {
+			contents, err := ctrlcommon.DecodeIgnitionFileContents(file.Contents.Source, file.Contents.Compression)
+			require.NoError(t, err)
+			registriesConf = string(contents)
+		}
+	}
+	assert.Contains(t, registriesConf, `pull-from-mirror = "digest-only"`)
+	assert.NotContains(t, registriesConf, `pull-from-mirror = "tag-only"`)
+	assert.NotContains(t, registriesConf, `pull-from-mirror = "all"`)
+}
+
+// TestImageConfigEmptyDesiredReleaseImage ensures that registries.conf is not rendered until the ClusterVersion desired
+// release image is known, so that the payload registry is never blocked.
+func TestImageConfigEmptyDesiredReleaseImage(t *testing.T) {
+	verifyOpts := registriesConfigAndPolicyVerifyOptions{
+		verifyPolicyJSON:                    true,
+		verifySearchRegsDropin:              false,
+		verifyImagePoliciesRegistriesConfig: false,
+	}
+
+	f := newFixture(t)
+	f.skipActionsValidation = true
+
+	cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+	mcp := helpers.NewMachineConfigPool("master", nil, helpers.MasterSelector, "v0")
+	imgcfg := newImageConfig("cluster", &apicfgv1.RegistrySources{BlockedRegistries: []string{"blocked.io", "test.io"}})
+	cvcfg := newClusterVersionConfig("version", "")
+
+	f.ccLister = append(f.ccLister, cc)
+	f.mcpLister = append(f.mcpLister, mcp)
+	f.imgLister = append(f.imgLister, imgcfg)
+	f.cvLister = append(f.cvLister, cvcfg)
+	f.imgObjects = append(f.imgObjects, imgcfg)
+
+	c := f.newController()
+
+	// While the desired release image is unknown, everything is rendered and the sync does not fail, but the payload
+	// registry cannot be excluded from the blocked registries yet
+	require.NoError(t, c.syncImgHandler("cluster"))
+	keyReg, _ := getManagedKeyReg(mcp, nil)
+	f.verifyRegistriesConfigAndPolicyJSONContents(t, keyReg, imgcfg, nil, nil, nil, nil, nil, "", verifyOpts)
+	registriesBlocked, _, _, err := getValidBlockedAndAllowedRegistries("", getPauseImage(cc), &imgcfg.Spec, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"blocked.io", "test.io"}, registriesBlocked)
+
+	// Once the desired release image is set, its registry is excluded from the blocked registries
+	cvcfg.Status.Desired.Image = "test.io/myuser/myimage:test"
+	require.NoError(t, c.syncImgHandler("cluster"))
+	f.verifyRegistriesConfigAndPolicyJSONContents(t, keyReg, imgcfg, nil, nil, nil, nil, nil, cvcfg.Status.Desired.Image, verifyOpts)
+	registriesBlocked, _, _, err = getValidBlockedAndAllowedRegistries(cvcfg.Status.Desired.Image, getPauseImage(cc), &imgcfg.Spec, nil, nil)
+	require.Error(t, err)
+	assert.Equal(t, []string{"blocked.io"}, registriesBlocked)
+}
+
+// TestPayloadRegistryInSearchRegistriesWarning ensures that a warning event is emitted on the image config only
+// when the payload registry is listed in the search registries, and that the sync still succeeds.
+func TestPayloadRegistryInSearchRegistriesWarning(t *testing.T) {
+	tests := []struct {
+		name        string
+		searchRegs  []string
+		expectEvent bool
+	}{
+		{
+			name:        "no search registries",
+			expectEvent: false,
+		},
+		{
+			name:        "other search registries",
+			searchRegs:  []string{"quay.io", "registry.example.com"},
+			expectEvent: false,
+		},
+		{
+			name:        "repository of the payload registry",
+			searchRegs:  []string{"test.io/myuser"},
+			expectEvent: false,
+		},
+		{
+			name:        "payload registry",
+			searchRegs:  []string{"quay.io", "test.io"},
+			expectEvent: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.skipActionsValidation = true
+
+			cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+			mcp := helpers.NewMachineConfigPool("master", nil, helpers.MasterSelector, "v0")
+			imgcfg := newImageConfig("cluster", &apicfgv1.RegistrySources{ContainerRuntimeSearchRegistries: test.searchRegs})
+			cvcfg := newClusterVersionConfig("version", "test.io/myuser/myimage:test")
+
+			f.ccLister = append(f.ccLister, cc)
+			f.mcpLister = append(f.mcpLister, mcp)
+			f.imgLister = append(f.imgLister, imgcfg)
+			f.cvLister = append(f.cvLister, cvcfg)
+			f.imgObjects = append(f.imgObjects, imgcfg)
+
+			c := f.newController()
+			recorder := record.NewFakeRecorder(10)
+			c.eventRecorder = recorder
+
+			require.NoError(t, c.syncImgHandler("cluster"))
+
+			select {
+			case event := <-recorder.Events:
+				require.True(t, test.expectEvent, "unexpected event: %s", event)
+				require.Contains(t, event, "PayloadRegistryInSearchRegistries")
+				require.Contains(t, event, `"test.io"`)
+			default:
+				require.False(t, test.expectEvent, "expected a PayloadRegistryInSearchRegistries event")
+			}
+		})
+	}
+}
+
+// TestInsecureRegistryNotAllowedWarning ensures that a warning event is emitted on the image config when an insecure
+// registry is excluded by the allowed registries, and that the sync still succeeds.
+func TestInsecureRegistryNotAllowedWarning(t *testing.T) {
+	tests := []struct {
+		name        string
+		insecure    []string
+		allowed     []string
+		expectEvent bool
+	}{
+		{
+			name:        "no allowed registries",
+			insecure:    []string{"insecure.example.com"},
+			expectEvent: false,
+		},
+		{
+			name:        "insecure registry allowed",
+			insecure:    []string{"insecure.example.com"},
+			allowed:     []string{"test.io", "insecure.example.com"},
+			expectEvent: false,
+		},
+		{
+			name:        "insecure registry not allowed",
+			insecure:    []string{"insecure.example.com"},
+			allowed:     []string{"test.io", "allowed.example.com"},
+			expectEvent: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.skipActionsValidation = true
+
+			cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+			mcp := helpers.NewMachineConfigPool("master", nil, helpers.MasterSelector, "v0")
+			imgcfg := newImageConfig("cluster", &apicfgv1.RegistrySources{InsecureRegistries: test.insecure, AllowedRegistries: test.allowed})
+			cvcfg := newClusterVersionConfig("version", "test.io/myuser/myimage:test")
+
+			f.ccLister = append(f.ccLister, cc)
+			f.mcpLister = append(f.mcpLister, mcp)
+			f.imgLister = append(f.imgLister, imgcfg)
+			f.cvLister = append(f.cvLister, cvcfg)
+			f.imgObjects = append(f.imgObjects, imgcfg)
+
+			c := f.newController()
+			recorder := record.NewFakeRecorder(10)
+			c.eventRecorder = recorder
+
+			require.NoError(t, c.syncImgHandler("cluster"))
+
+			select {
+			case event := <-recorder.Events:
+				require.True(t, test.expectEvent, "unexpected event: %s", event)
+				require.Contains(t, event, "InsecureRegistryNotAllowed")
+				require.Contains(t, event, "insecure.example.com")
+			default:
+				require.False(t, test.expectEvent, "expected an InsecureRegistryNotAllowed event")
+			}
+		})
+	}
+}
+
+// TestContainerRuntimeConfigPendingRollout ensures that a ContainerRuntimeConfig is Pending until the selected pool
+// rolls out its MachineConfig, and that the pool rolling out a new rendered config queues it again.
+func TestContainerRuntimeConfigPendingRollout(t *testing.T) {
+	f := newFixture(t)
+	f.skipActionsValidation = true
+
+	cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+	mcp := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "v0")
+	ctrcfg := newContainerRuntimeConfig("log-level", &mcfgv1.ContainerRuntimeConfiguration{LogLevel: "debug"}, metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/worker", ""))
+
+	f.ccLister = append(f.ccLister, cc)
+	f.mcpLister = append(f.mcpLister, mcp)
+	f.mccrLister = append(f.mccrLister, ctrcfg)
+	f.objects = append(f.objects, ctrcfg)
+
+	c := f.newController()
+	var queued []string
+	c.enqueueContainerRuntimeConfig = func(cfg *mcfgv1.ContainerRuntimeConfig) {
+		queued = append(queued, cfg.Name)
+	}
+
+	// The MachineConfig is created, but the pool has not rolled it out yet
+	require.NoError(t, c.syncHandler(getKey(ctrcfg, t)))
+	_, err := c.client.MachineconfigurationV1().MachineConfigs().Get(context.TODO(), "99-worker-generated-containerruntime", metav1.GetOptions{})
+	require.NoError(t, err)
+	latest, err := c.mccrLister.Get(ctrcfg.Name)
+	require.NoError(t, err)
+	lastCondition := latest.Status.Conditions[len(latest.Status.Conditions)-1]
+	assert.Equal(t, ContainerRuntimeConfigPending, lastCondition.Type)
+	assert.Contains(t, lastCondition.Message, "[worker]")
+	assert.Contains(t, lastCondition.Message, "99-worker-generated-containerruntime")
+
+	// A pool update that does not change the rendered config does not queue anything
+	oldPool := mcp.DeepCopy()
+	c.updateMachineConfigPool(oldPool, mcp)
+	assert.Empty(t, queued)
+
+	// The pool rolls out a rendered config including the MachineConfig
+	mcp.Status.Configuration.Name = "rendered-worker-1"
+	rollOutMachineConfigs(mcp, "99-worker-generated-containerruntime")
+	c.updateMachineConfigPool(oldPool, mcp)
+	assert.Equal(t, []string{ctrcfg.Name}, queued)
+
+	require.NoError(t, c.syncHandler(getKey(ctrcfg, t)))
+	latest, err = c.mccrLister.Get(ctrcfg.Name)
+	require.NoError(t, err)
+	assert.Equal(t, mcfgv1.ContainerRuntimeConfigSuccess, latest.Status.Conditions[len(latest.Status.Conditions)-1].Type)
+
+	// Once Success is recorded, pool updates no longer queue the ContainerRuntimeConfig
+	queued = nil
+	newPool := mcp.DeepCopy()
+	newPool.Status.Configuration.Name = "rendered-worker-2"
+	c.updateMachineConfigPool(mcp, newPool)
+	assert.Empty(t, queued)
+}
+
+// TestContainerRuntimeConfigPendingResync ensures that the syncs of a Pending ContainerRuntimeConfig whose MachineConfig
+// is up to date do not render or update it again, and only refresh the condition from the status of the pool.
+func TestContainerRuntimeConfigPendingResync(t *testing.T) {
+	f := newFixture(t)
+	f.skipActionsValidation = true
+
+	cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+	mcp := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "v0")
+	mcp.Annotations = map[string]string{ctrlcommon.MachineConfigPoolExternalLogRotationAnnotationKey: "true"}
+	logSizeMax := resource.MustParse("10Mi")
+	ctrcfg := newContainerRuntimeConfig("log-size-max", &mcfgv1.ContainerRuntimeConfiguration{LogSizeMax: &logSizeMax}, metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/worker", ""))
+
+	f.ccLister = append(f.ccLister, cc)
+	f.mcpLister = append(f.mcpLister, mcp)
+	f.mccrLister = append(f.mccrLister, ctrcfg)
+	f.objects = append(f.objects, ctrcfg)
+
+	c := f.newController()
+	require.NoError(t, c.syncHandler(getKey(ctrcfg, t)))
+	latest, err := c.mccrLister.Get(ctrcfg.Name)
+	require.NoError(t, err)
+	pendingCondition := latest.Status.Conditions[len(latest.Status.Conditions)-1]
+	require.Equal(t, ContainerRuntimeConfigPending, pendingCondition.Type)
+	require.Contains(t, pendingCondition.Message, "LogSizeMax")
+
+	mcUpdates := func() int {
+		updates := 0
+		for _, action := range f.client.Actions() {
+			if action.GetVerb() == "update" && action.GetResource().Resource == "machineconfigs" {
+				updates++
+			}
+		}
+		return updates
+	}
+
+	// The pool has not rolled out the MachineConfig yet
+	f.client.ClearActions()
+	require.NoError(t, c.syncHandler(getKey(ctrcfg, t)))
+	assert.Zero(t, mcUpdates())
+	latest, err = c.mccrLister.Get(ctrcfg.Name)
+	require.NoError(t, err)
+	lastCondition := latest.Status.Conditions[len(latest.Status.Conditions)-1]
+	assert.Equal(t, ContainerRuntimeConfigPending, lastCondition.Type)
+	assert.Equal(t, pendingCondition.Message, lastCondition.Message)
+
+	// The pool rolled out the MachineConfig
+	rollOutMachineConfigs(mcp, "99-worker-generated-containerruntime")
+	f.client.ClearActions()
+	require.NoError(t, c.syncHandler(getKey(ctrcfg, t)))
+	assert.Zero(t, mcUpdates())
+	latest, err = c.mccrLister.Get(ctrcfg.Name)
+	require.NoError(t, err)
+	lastCondition = latest.Status.Conditions[len(latest.Status.Conditions)-1]
+	assert.Equal(t, mcfgv1.ContainerRuntimeConfigSuccess, lastCondition.Type)
+	assert.Contains(t, lastCondition.Message, "LogSizeMax")
+}
+
+// TestPoolNodesChangeQueuesImageSync ensures that the registries are rendered again when the nodes of a pool change,
+// as their architecture selects the mirror sets of its registries.conf
+func TestPoolNodesChangeQueuesImageSync(t *testing.T) {
+	f := newFixture(t)
+	mcp := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "v0")
+	f.mcpLister = append(f.mcpLister, mcp)
+	c := f.newController()
+
+	newPool := mcp.DeepCopy()
+	newPool.Status.Configuration.Name = "rendered-worker-1"
+	c.updateMachineConfigPool(mcp, newPool)
+	assert.Equal(t, 0, c.imgQueue.Len())
+
+	newPool.Status.MachineCount = mcp.Status.MachineCount + 1
+	c.updateMachineConfigPool(mcp, newPool)
+	assert.Equal(t, 1, c.imgQueue.Len())
+}
+
+// TestContainerRuntimeConfigExternalLogRotation ensures that setting logSizeMax on a pool rotating the container logs with
+// an external tool is applied, but warned about in an event and in the condition.
+func TestContainerRuntimeConfigExternalLogRotation(t *testing.T) {
+	f := newFixture(t)
+	f.skipActionsValidation = true
+
+	logSizeMax := resource.MustParse("10Ki")
+	cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+	mcp := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "v0")
+	metav1.SetMetaDataAnnotation(&mcp.ObjectMeta, ctrlcommon.MachineConfigPoolExternalLogRotationAnnotationKey, "true")
+	rollOutMachineConfigs(mcp, "99-worker-generated-containerruntime")
+	ctrcfg := newContainerRuntimeConfig("log-size", &mcfgv1.ContainerRuntimeConfiguration{LogSizeMax: &logSizeMax}, metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/worker", ""))
+
+	f.ccLister = append(f.ccLister, cc)
+	f.mcpLister = append(f.mcpLister, mcp)
+	f.mccrLister = append(f.mccrLister, ctrcfg)
+	f.objects = append(f.objects, ctrcfg)
+
+	c := f.newController()
+	recorder := record.NewFakeRecorder(10)
+	c.eventRecorder = recorder
+
+	require.NoError(t, c.syncHandler(getKey(ctrcfg, t)))
+
+	_, err := c.client.MachineconfigurationV1().MachineConfigs().Get(context.TODO(), "99-worker-generated-containerruntime", metav1.GetOptions{})
+	require.NoError(t, err)
+	latest, err := c.mccrLister.Get(ctrcfg.Name)
+	require.NoError(t, err)
+	lastCondition := latest.Status.Conditions[len(latest.Status.Conditions)-1]
+	assert.Equal(t, mcfgv1.ContainerRuntimeConfigSuccess, lastCondition.Type)
+	assert.Contains(t, lastCondition.Message, "Warning: LogSizeMax 10Ki makes conmon rotate the container logs")
+
+	select {
+	case event := <-recorder.Events:
+		assert.Contains(t, event, "ExternalLogRotation")
+	default:
+		t.Fatal("expected an ExternalLogRotation event")
+	}
+}
+
+// TestContainerRuntimeConfigFieldValidationErrors ensures that the failure condition of an invalid ContainerRuntimeConfig
+// names the invalid field in its Reason, and explains the problem in its Message
+func TestContainerRuntimeConfigFieldValidationErrors(t *testing.T) {
+	pidsLimit := int64(10)
+	tests := []struct {
+		name        string
+		config      *mcfgv1.ContainerRuntimeConfiguration
+		wantReason  string
+		wantMessage string
+	}{
+		{
+			name:        "pids limit",
+			config:      &mcfgv1.ContainerRuntimeConfiguration{PidsLimit: &pidsLimit},
+			wantReason:  "InvalidPidsLimit",
+			wantMessage: "Error: invalid PidsLimit 10, cannot be less than 20",
+		},
+		{
+			name:        "log level",
+			config:      &mcfgv1.ContainerRuntimeConfiguration{LogLevel: "verbose"},
+			wantReason:  "InvalidLogLevel",
+			wantMessage: `Error: invalid LogLevel "verbose", must be one of error, fatal, panic, warn, info, debug, or trace`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.skipActionsValidation = true
+
+			cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+			mcp := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "v0")
+			ctrcfg := newContainerRuntimeConfig("invalid", test.config, metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/worker", ""))
+
+			f.ccLister = append(f.ccLister, cc)
+			f.mcpLister = append(f.mcpLister, mcp)
+			f.mccrLister = append(f.mccrLister, ctrcfg)
+			f.objects = append(f.objects, ctrcfg)
+
+			c := f.newController()
+			err := c.syncHandler(getKey(ctrcfg, t))
+			require.Error(t, err)
+			var fieldErr *FieldValidationError
+			require.True(t, goerrs.As(err, &fieldErr), "expected a FieldValidationError, got %v", err)
+
+			latest, err := c.client.MachineconfigurationV1().ContainerRuntimeConfigs().Get(context.TODO(), ctrcfg.Name, metav1.GetOptions{})
+			require.NoError(t, err)
+			lastCondition := latest.Status.Conditions[len(latest.Status.Conditions)-1]
+			assert.Equal(t, mcfgv1.ContainerRuntimeConfigFailure, lastCondition.Type)
+			assert.Equal(t, test.wantReason, lastCondition.Reason)
+			assert.Equal(t, test.wantMessage, lastCondition.Message)
+		})
+	}
+
+	// Other failures leave the Reason empty
+	condition := wrapErrorWithCondition(fmt.Errorf("could not get ControllerConfig"))
+	assert.Empty(t, condition.Reason)
+}
+
+// TestContainerRuntimeConfigConflictingConfigs ensures that a ContainerRuntimeConfig setting PidsLimit differently than
+// another one applying to the same pool is still applied, but warned about in an event and in the condition.
+func TestContainerRuntimeConfigConflictingConfigs(t *testing.T) {
+	f := newFixture(t)
+	f.skipActionsValidation = true
+
+	lowPidsLimit := int64(2048)
+	highPidsLimit := int64(4096)
+	cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+	mcp := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "v0")
+	workerSelector := metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/worker", "")
+	low := newContainerRuntimeConfig("low", &mcfgv1.ContainerRuntimeConfiguration{PidsLimit: &lowPidsLimit}, workerSelector)
+	high := newContainerRuntimeConfig("high", &mcfgv1.ContainerRuntimeConfiguration{PidsLimit: &highPidsLimit, LogLevel: "debug"}, workerSelector)
+
+	f.ccLister = append(f.ccLister, cc)
+	f.mcpLister = append(f.mcpLister, mcp)
+	f.mccrLister = append(f.mccrLister, low, high)
+	f.objects = append(f.objects, low, high)
+
+	c := f.newController()
+	recorder := record.NewFakeRecorder(10)
+	c.eventRecorder = recorder
+
+	require.NoError(t, c.syncHandler(getKey(high, t)))
+
+	latest, err := c.mccrLister.Get(high.Name)
+	require.NoError(t, err)
+	lastCondition := latest.Status.Conditions[len(latest.Status.Conditions)-1]
+	assert.Contains(t, lastCondition.Message, "Warning: PidsLimit is set to 4096, but ContainerRuntimeConfig low also applying to MachineConfigPool worker sets it to 2048")
+	assert.NotContains(t, lastCondition.Message, "LogLevel")
+
+	select {
+	case event := <-recorder.Events:
+		assert.Contains(t, event, "ConflictingContainerRuntimeConfig")
+		assert.Contains(t, event, "ContainerRuntimeConfig low")
+	default:
+		t.Fatal("expected a ConflictingContainerRuntimeConfig event")
+	}
+}
+
+// TestContainerRuntimeConfigMasterAcknowledgement ensures that a ContainerRuntimeConfig selecting the master pool is
+// only applied when it carries the acknowledgement annotation.
+func TestContainerRuntimeConfigMasterAcknowledgement(t *testing.T) {
+	tests := []struct {
+		name         string
+		pool         string
+		acknowledged bool
+		expectErr    bool
+	}{
+		{
+			name:      "master pool without acknowledgement",
+			pool:      "master",
+			expectErr: true,
+		},
+		{
+			name:         "master pool with acknowledgement",
+			pool:         "master",
+			acknowledged: true,
+		},
+		{
+			name: "worker pool without acknowledgement",
+			pool: "worker",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.skipActionsValidation = true
+
+			cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+			mcp := helpers.NewMachineConfigPool("master", nil, helpers.MasterSelector, "v0")
+			mcp2 := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "v0")
+			rollOutMachineConfigs(mcp, "99-master-generated-containerruntime")
+			rollOutMachineConfigs(mcp2, "99-worker-generated-containerruntime")
+			ctrcfg := newContainerRuntimeConfig("log-level", &mcfgv1.ContainerRuntimeConfiguration{LogLevel: "debug"}, metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/"+test.pool, ""))
+			if test.acknowledged {
+				acknowledgeMasterChanges(ctrcfg)
+			}
+
+			f.ccLister = append(f.ccLister, cc)
+			f.mcpLister = append(f.mcpLister, mcp, mcp2)
+			f.mccrLister = append(f.mccrLister, ctrcfg)
+			f.objects = append(f.objects, ctrcfg)
+
+			c := f.newController()
+			err := c.syncHandler(getKey(ctrcfg, t))
+
+			latest, getErr := c.mccrLister.Get(ctrcfg.Name)
+			require.NoError(t, getErr)
+			lastCondition := latest.Status.Conditions[len(latest.Status.Conditions)-1]
+			mcs, listErr := c.client.MachineconfigurationV1().MachineConfigs().List(context.TODO(), metav1.ListOptions{})
+			require.NoError(t, listErr)
+			if test.expectErr {
+				require.Error(t, err)
+				assert.Equal(t, mcfgv1.ContainerRuntimeConfigFailure, lastCondition.Type)
+				assert.Contains(t, lastCondition.Message, ctrlcommon.ContainerRuntimeConfigAllowMasterChangesAnnotationKey)
+				assert.Empty(t, mcs.Items)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, mcfgv1.ContainerRuntimeConfigSuccess, lastCondition.Type)
+			require.Len(t, mcs.Items, 1)
+			assert.Equal(t, fmt.Sprintf("99-%s-generated-containerruntime", test.pool), mcs.Items[0].Name)
+			if test.acknowledged {
+				// Recording the MC name suffix keeps the acknowledgement
+				assert.Equal(t, "true", latest.Annotations[ctrlcommon.ContainerRuntimeConfigAllowMasterChangesAnnotationKey])
+				assert.Contains(t, latest.Annotations, ctrlcommon.MCNameSuffixAnnotationKey)
+			}
+		})
+	}
+}
+
+// TestContainerRuntimeConfigMasterAcknowledgementUpgrade ensures that a ContainerRuntimeConfig applied to the master
+// pool before the acknowledgement was required keeps being applied after an upgrade, until it is changed.
+func TestContainerRuntimeConfigMasterAcknowledgementUpgrade(t *testing.T) {
+	v := version.Hash
+	version.Hash = "3.2.0"
+	defer func() {
+		version.Hash = v
+	}()
+
+	tests := []struct {
+		name      string
+		changed   bool
+		expectErr bool
+	}{
+		{
+			name: "unchanged since it was applied",
+		},
+		{
+			name:      "changed since it was applied",
+			changed:   true,
+			expectErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.skipActionsValidation = true
+
+			cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+			mcp := helpers.NewMachineConfigPool("master", nil, helpers.MasterSelector, "v0")
+			mcName := "99-master-generated-containerruntime"
+			rollOutMachineConfigs(mcp, mcName)
+			ctrcfg := newContainerRuntimeConfig("log-level", &mcfgv1.ContainerRuntimeConfiguration{LogLevel: "debug"}, metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/master", ""))
+			metav1.SetMetaDataAnnotation(&ctrcfg.ObjectMeta, ctrlcommon.MCNameSuffixAnnotationKey, "")
+			ctrcfg.Status.ObservedGeneration = ctrcfg.Generation
+			ctrcfg.Status.Conditions = []mcfgv1.ContainerRuntimeConfigCondition{{Type: mcfgv1.ContainerRuntimeConfigSuccess, Status: corev1.ConditionTrue}}
+			if test.changed {
+				ctrcfg.Generation++
+			}
+			// The MC rendered by the previous version of the controller
+			mc := helpers.NewMachineConfig(mcName, map[string]string{"node-role": "master"}, "dummy://", []ign3types.File{{}})
+			mc.Annotations = map[string]string{ctrlcommon.GeneratedByControllerVersionAnnotationKey: "3.1.0"}
+			mc.SetOwnerReferences([]metav1.OwnerReference{*metav1.NewControllerRef(ctrcfg, controllerKind)})
+
+			f.ccLister = append(f.ccLister, cc)
+			f.mcpLister = append(f.mcpLister, mcp)
+			f.mccrLister = append(f.mccrLister, ctrcfg)
+			f.objects = append(f.objects, ctrcfg, mc)
+
+			c := f.newController()
+			// The second sync sees the observed generation recorded by the first one, which must not grandfather a
+			// changed ContainerRuntimeConfig
+			for i := 0; i < 2; i++ {
+				err := c.syncHandler(getKey(ctrcfg, t))
+
+				latest, getErr := c.client.MachineconfigurationV1().ContainerRuntimeConfigs().Get(context.TODO(), ctrcfg.Name, metav1.GetOptions{})
+				require.NoError(t, getErr)
+				lastCondition := latest.Status.Conditions[len(latest.Status.Conditions)-1]
+				updatedMC, getErr := c.client.MachineconfigurationV1().MachineConfigs().Get(context.TODO(), mcName, metav1.GetOptions{})
+				require.NoError(t, getErr)
+				if test.expectErr {
+					require.Error(t, err)
+					assert.Equal(t, latest.Generation, latest.Status.ObservedGeneration)
+					assert.Equal(t, mcfgv1.ContainerRuntimeConfigFailure, lastCondition.Type)
+					assert.Contains(t, lastCondition.Message, ctrlcommon.ContainerRuntimeConfigAllowMasterChangesAnnotationKey)
+					assert.Equal(t, "3.1.0", updatedMC.Annotations[ctrlcommon.GeneratedByControllerVersionAnnotationKey])
+					continue
+				}
+				require.NoError(t, err)
+				assert.Equal(t, mcfgv1.ContainerRuntimeConfigSuccess, lastCondition.Type)
+				assert.Equal(t, version.Hash, updatedMC.Annotations[ctrlcommon.GeneratedByControllerVersionAnnotationKey])
+				assert.Equal(t, strconv.FormatInt(ctrcfg.Generation, 10), updatedMC.Annotations[ctrlcommon.ContainerRuntimeConfigGenerationAnnotationKey])
+			}
+		})
+	}
+}
+
+// TestContainerRuntimeConfigPidsLimitBounds ensures that a PidsLimit below minPidsLimit is rejected in the status,
+// and that one below recommendedMinPidsLimit is applied with a warning event.
+func TestContainerRuntimeConfigPidsLimitBounds(t *testing.T) {
+	tests := []struct {
+		name        string
+		pidsLimit   int64
+		expectErr   bool
+		expectEvent bool
+	}{
+		{
+			name:      "below the minimum",
+			pidsLimit: 1,
+			expectErr: true,
+		},
+		{
+			name:        "between the minimum and the recommended minimum",
+			pidsLimit:   100,
+			expectEvent: true,
+		},
+		{
+			name:      "at the recommended minimum",
+			pidsLimit: recommendedMinPidsLimit,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.skipActionsValidation = true
+
+			cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+			mcp := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "v0")
+			rollOutMachineConfigs(mcp, "99-worker-generated-containerruntime")
+			pidsLimit := test.pidsLimit
+			ctrcfg := newContainerRuntimeConfig("pids-limit", &mcfgv1.ContainerRuntimeConfiguration{PidsLimit: &pidsLimit}, metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/worker", ""))
+
+			f.ccLister = append(f.ccLister, cc)
+			f.mcpLister = append(f.mcpLister, mcp)
+			f.mccrLister = append(f.mccrLister, ctrcfg)
+			f.objects = append(f.objects, ctrcfg)
+
+			c := f.newController()
+			recorder := record.NewFakeRecorder(10)
+			c.eventRecorder = recorder
+
+			err := c.syncHandler(getKey(ctrcfg, t))
+			latest, getErr := c.mccrLister.Get(ctrcfg.Name)
+			require.NoError(t, getErr)
+			lastCondition := latest.Status.Conditions[len(latest.Status.Conditions)-1]
+			if test.expectErr {
+				require.Error(t, err)
+				assert.Equal(t, mcfgv1.ContainerRuntimeConfigFailure, lastCondition.Type)
+				assert.Contains(t, lastCondition.Message, fmt.Sprintf("cannot be less than %d", minPidsLimit))
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, mcfgv1.ContainerRuntimeConfigSuccess, lastCondition.Type)
+			}
+
+			select {
+			case event := <-recorder.Events:
+				require.True(t, test.expectEvent, "unexpected event: %s", event)
+				require.Contains(t, event, "ContainerRuntimeConfigWarning")
+				require.Contains(t, event, "below the recommended minimum")
+			default:
+				require.False(t, test.expectEvent, "expected a ContainerRuntimeConfigWarning event")
+			}
+		})
+	}
+}
+
+func TestContainerRuntimeConfigUnreconciledMetric(t *testing.T) {
+	f := newFixture(t)
+	f.skipActionsValidation = true
+
+	ctrcfg := newContainerRuntimeConfig("unreconciled", &mcfgv1.ContainerRuntimeConfiguration{LogLevel: "debug"}, metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/worker", ""))
+	ctrcfg.Generation = 2
+	ctrcfg.Status.ObservedGeneration = 1
+
+	f.mccrLister = append(f.mccrLister, ctrcfg)
+	f.objects = append(f.objects, ctrcfg)
+
+	c := f.newController()
+	gauge := ctrlcommon.MCCContainerRuntimeConfigUnreconciled.WithLabelValues(ctrcfg.Name)
+
+	// A failed sync of a new generation starts the clock
+	before := time.Now().Unix()
+	require.Error(t, c.syncStatusOnly(ctrcfg, fmt.Errorf("sync failed")))
+	since := testutil.ToFloat64(gauge)
+	assert.GreaterOrEqual(t, since, float64(before))
+
+	// Failing again on the same generation keeps the original timestamp
+	c.updateUnreconciledMetric(ctrcfg.Name, false, false)
+	assert.Equal(t, since, testutil.ToFloat64(gauge))
+
+	// A successful sync resets it
+	require.NoError(t, c.syncStatusOnly(ctrcfg, nil))
+	assert.Equal(t, float64(0), testutil.ToFloat64(gauge))
+
+	c.clearUnreconciledMetric(ctrcfg.Name)
+	assert.Empty(t, c.unreconciledSince)
+}
+
+func TestContainerRuntimeConfigPoolRenamed(t *testing.T) {
+	f := newFixture(t)
+	f.skipActionsValidation = true
+
+	cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+	// The "worker" pool was deleted and recreated as "worker-new" with the same labels
+	mcp := helpers.NewMachineConfigPool("worker-new", nil, helpers.WorkerSelector, "v0")
+	mcp.ObjectMeta.Labels["pools.operator.machineconfiguration.openshift.io/worker"] = ""
+	ctrcfg := newContainerRuntimeConfig("log-level", &mcfgv1.ContainerRuntimeConfiguration{LogLevel: "debug"}, metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/worker", ""))
+	oldMC := helpers.NewMachineConfig("99-worker-generated-containerruntime", map[string]string{"node-role": "worker"}, "dummy://", []ign3types.File{{}})
+	oldMC.Annotations = map[string]string{ctrlcommon.GeneratedByControllerVersionAnnotationKey: version.Hash}
+	ctrcfg.SetFinalizers([]string{oldMC.Name, "example.com/other-finalizer"})
+
+	f.ccLister = append(f.ccLister, cc)
+	f.mcpLister = append(f.mcpLister, mcp)
+	f.mccrLister = append(f.mccrLister, ctrcfg)
+	f.objects = append(f.objects, ctrcfg, oldMC)
+
+	c := f.newController()
+	require.NoError(t, c.syncHandler(getKey(ctrcfg, t)))
+
+	// The MC of the old pool is gone and one was generated for the new pool
+	_, err := c.client.MachineconfigurationV1().MachineConfigs().Get(context.TODO(), oldMC.Name, metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err), "expected %s to be deleted, got %v", oldMC.Name, err)
+	managedKey, err := getManagedKeyCtrCfg(mcp, c.client, ctrcfg)
+	require.NoError(t, err)
+	_, err = c.client.MachineconfigurationV1().MachineConfigs().Get(context.TODO(), managedKey, metav1.GetOptions{})
+	require.NoError(t, err)
+
+	// Only the finalizer of the old MC was dropped. The fake client's status update at the end of the sync writes
+	// back the cached object, so look at the last finalizer patch instead of the stored object.
+	var finalizers []string
+	for _, action := range f.client.Actions() {
+		patch, ok := action.(core.PatchAction)
+		if !ok || action.GetResource().Resource != "containerruntimeconfigs" {
+			continue
+		}
+		patched := &mcfgv1.ContainerRuntimeConfig{}
+		require.NoError(t, json.Unmarshal(patch.GetPatch(), patched))
+		finalizers = patched.Finalizers
+	}
+	assert.ElementsMatch(t, []string{"example.com/other-finalizer", managedKey}, finalizers)
+}
+
+func TestContainerRuntimeConfigCascadeDelete(t *testing.T) {
+	workerMC := helpers.NewMachineConfig("99-worker-generated-containerruntime", map[string]string{"node-role": "worker"}, "dummy://", []ign3types.File{{}})
+	masterMC := helpers.NewMachineConfig("99-master-generated-containerruntime", map[string]string{"node-role": "master"}, "dummy://", []ign3types.File{{}})
+
+	tests := []struct {
+		name       string
+		finalizers []string
+		mcs        []runtime.Object
+	}{
+		{
+			name:       "MachineConfig already deleted",
+			finalizers: []string{workerMC.Name},
+		},
+		{
+			name:       "two finalizers",
+			finalizers: []string{workerMC.Name, masterMC.Name},
+			mcs:        []runtime.Object{workerMC, masterMC},
+		},
+		{
+			name:       "one of two MachineConfigs already deleted",
+			finalizers: []string{workerMC.Name, masterMC.Name},
+			mcs:        []runtime.Object{masterMC},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.skipActionsValidation = true
+
+			ctrcfg := newContainerRuntimeConfig("log-level", &mcfgv1.ContainerRuntimeConfiguration{LogLevel: "debug"}, metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/worker", ""))
+			ctrcfg.SetFinalizers(append(test.finalizers, "example.com/other-finalizer"))
+			now := metav1.Now()
+			ctrcfg.DeletionTimestamp = &now
+
+			f.mccrLister = append(f.mccrLister, ctrcfg)
+			f.objects = append(f.objects, ctrcfg)
+			f.objects = append(f.objects, test.mcs...)
+
+			c := f.newController()
+			require.NoError(t, c.syncHandler(getKey(ctrcfg, t)))
+
+			for _, mcName := range test.finalizers {
+				_, err := c.client.MachineconfigurationV1().MachineConfigs().Get(context.TODO(), mcName, metav1.GetOptions{})
+				assert.True(t, errors.IsNotFound(err), "expected %s to be deleted, got %v", mcName, err)
+			}
+			// Only the finalizers of the generated MachineConfigs are removed
+			latest, err := c.client.MachineconfigurationV1().ContainerRuntimeConfigs().Get(context.TODO(), ctrcfg.Name, metav1.GetOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, []string{"example.com/other-finalizer"}, latest.Finalizers)
+		})
+	}
+}
+
+// TestRegistriesConfigIgnitionDeterministic ensures that the same registries, listed in another order or with duplicates,
+// render the same files
+func TestRegistriesConfigIgnitionDeterministic(t *testing.T) {
+	cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+	idms := newIDMS("idms", []apicfgv1.ImageDigestMirrors{
+		{Source: "source.example.com", Mirrors: []apicfgv1.ImageMirror{"mirror.example.com"}},
+	})
+
+	render := func(insecure, blocked, allowed, search []string) *ign3types.Config {
+		ignCfg, err := registriesConfigIgnition(templateDir, cc, "worker", "amd64", "", insecure, blocked, blocked, allowed, search,
+			nil, []*apicfgv1.ImageDigestMirrorSet{idms}, nil, nil, nil)
+		require.NoError(t, err)
+		return ignCfg
+	}
+
+	want := render([]string{"a.insecure.com", "b.insecure.com"}, []string{"a.blocked.com", "b.blocked.com", "c.blocked.com"}, nil, []string{"quay.io", "docker.io"})
+	got := render([]string{"b.insecure.com", "a.insecure.com", "b.insecure.com"}, []string{"c.blocked.com", "a.blocked.com", "b.blocked.com", "a.blocked.com"}, nil, []string{"quay.io", "docker.io", "quay.io"})
+	assert.Equal(t, want, got)
+
+	want = render(nil, nil, []string{"a.allowed.com", "b.allowed.com"}, nil)
+	got = render(nil, nil, []string{"b.allowed.com", "a.allowed.com", "b.allowed.com"}, nil)
+	assert.Equal(t, want, got)
+
+	// The search registries keep their order, as it is the order they are tried in
+	reordered := render(nil, nil, nil, []string{"docker.io", "quay.io"})
+	ordered := render(nil, nil, nil, []string{"quay.io", "docker.io"})
+	assert.NotEqual(t, ordered, reordered)
+}
+
+// TestRegistriesConfigIgnitionAcceptedByCRIO ensures that the registries.conf rendered for mirrors, blocked and insecure
+// registries is loaded by CRI-O's parser with the intended semantics
+func TestRegistriesConfigIgnitionAcceptedByCRIO(t *testing.T) {
+	cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+	icsp := newICSP("icsp", []apioperatorsv1alpha1.RepositoryDigestMirrors{
+		{Source: "icsp-source.example.com/ns", Mirrors: []string{"icsp-mirror.example.com/ns"}},
+	})
+	idms := newIDMS("idms", []apicfgv1.ImageDigestMirrors{
+		{Source: "digest-source.example.com", Mirrors: []apicfgv1.ImageMirror{"digest-mirror-1.example.com", "digest-mirror-2.example.com"}, MirrorSourcePolicy: apicfgv1.NeverContactSource},
+	})
+	itms := newITMS("itms", []apicfgv1.ImageTagMirrors{
+		{Source: "tag-source.example.com", Mirrors: []apicfgv1.ImageMirror{"tag-mirror.example.com"}},
+	})
+	insecure := []string{"insecure.example.com", "*.insecure-wildcard.example.com"}
+	blocked := []string{"blocked.example.com"}
+
+	ignCfg, err := registriesConfigIgnition(templateDir, cc, "worker", "amd64", "", insecure, blocked, blocked, nil, nil,
+		[]*apioperatorsv1alpha1.ImageContentSourcePolicy{icsp}, []*apicfgv1.ImageDigestMirrorSet{idms}, []*apicfgv1.ImageTagMirrorSet{itms}, nil, nil)
+	require.NoError(t, err)
+	var registriesConf []byte
+	for _, file := range ignCfg.Storage.Files {
+		if file.Node.Path == registriesConfigPath {
+			registriesConf, err = ctrlcommon.DecodeIgnitionFileContents(file.Contents.Source, file.Contents.Compression)
+			require.NoError(t, err)
+		}
+	}
+	require.NotNil(t, registriesConf)
+
+	registries := map[string]sysregistriesv2.Registry{}
+	for _, reg := range requireRegistriesConfAcceptedByCRIO(t, registriesConf) {
+		registries[reg.Prefix] = reg
+	}
+	mirrorLocations := func(reg sysregistriesv2.Registry) []string {
+		var locations []string
+		for _, mirror := range reg.Mirrors {
+			locations = append(locations, mirror.Location)
+		}
+		return locations
+	}
+
+	require.Contains(t, registries, "icsp-source.example.com/ns")
+	assert.Equal(t, []string{"icsp-mirror.example.com/ns"}, mirrorLocations(registries["icsp-source.example.com/ns"]))
+	require.Contains(t, registries, "digest-source.example.com")
+	assert.Equal(t, []string{"digest-mirror-1.example.com", "digest-mirror-2.example.com"}, mirrorLocations(registries["digest-source.example.com"]))
+	assert.True(t, registries["digest-source.example.com"].Blocked, "a NeverContactSource source is blocked")
+	require.Contains(t, registries, "tag-source.example.com")
+	assert.Equal(t, []string{"tag-mirror.example.com"}, mirrorLocations(registries["tag-source.example.com"]))
+	require.Contains(t, registries, "blocked.example.com")
+	assert.True(t, registries["blocked.example.com"].Blocked)
+	require.Contains(t, registries, "insecure.example.com")
+	assert.True(t, registries["insecure.example.com"].Insecure)
+	require.Contains(t, registries, "*.insecure-wildcard.example.com")
+	assert.True(t, registries["*.insecure-wildcard.example.com"].Insecure)
+}
+
+// TestRegistriesConfigIgnitionUnchangedPolicy ensures that a policy.json identical to the default one is not written
+func TestRegistriesConfigIgnitionUnchangedPolicy(t *testing.T) {
+	cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+
+	hasPolicyJSON := func(allowed []string) bool {
+		ignCfg, err := registriesConfigIgnition(templateDir, cc, "worker", "amd64", "", nil, nil, nil, allowed, nil, nil, nil, nil, nil, nil)
+		require.NoError(t, err)
+		for _, file := range ignCfg.Storage.Files {
+			if file.Node.Path == policyConfigPath {
+				return true
+			}
+		}
+		return false
+	}
+
+	assert.False(t, hasPolicyJSON([]string{}), "an empty allowed list leaves the default policy.json unchanged")
+	assert.True(t, hasPolicyJSON([]string{"allowed.example.com"}), "an allowed list changes the default policy.json")
+}
+
+func TestRenderedRegistriesConfigHash(t *testing.T) {
+	syncHash := func(insecureRegs []string) string {
+		f := newFixture(t)
+		f.skipActionsValidation = true
+
+		cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+		mcp := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "v0")
+		mcp.ObjectMeta.Labels["pools.operator.machineconfiguration.openshift.io/worker"] = ""
+		imgcfg := newImageConfig("cluster", &apicfgv1.RegistrySources{InsecureRegistries: insecureRegs})
+		cvcfg := newClusterVersionConfig("version", "test.io/myuser/myimage:test")
+		ctrcfg := newContainerRuntimeConfig("log-level", &mcfgv1.ContainerRuntimeConfiguration{LogLevel: "debug"}, metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/worker", ""))
+
+		f.ccLister = append(f.ccLister, cc)
+		f.mcpLister = append(f.mcpLister, mcp)
+		f.imgLister = append(f.imgLister, imgcfg)
+		f.cvLister = append(f.cvLister, cvcfg)
+		f.imgObjects = append(f.imgObjects, imgcfg)
+		f.mccrLister = append(f.mccrLister, ctrcfg)
+		f.objects = append(f.objects, ctrcfg)
+
+		c := f.newController()
+		require.NoError(t, c.syncImgHandler("cluster"))
+
+		cm, err := c.kubeClient.CoreV1().ConfigMaps(ctrlcommon.MCONamespace).Get(context.TODO(), registriesMachineConfigsConfigMapName, metav1.GetOptions{})
+		require.NoError(t, err)
+		return cm.Data["worker"+registriesConfigHashKeySuffix]
+	}
+
+	hash1 := syncHash([]string{"insecure-1.example.com"})
+	hash2 := syncHash([]string{"insecure-1.example.com", "insecure-2.example.com"})
+	require.Len(t, hash1, 64)
+	require.Len(t, hash2, 64)
+	assert.NotEqual(t, hash1, hash2)
+	assert.Equal(t, hash1, syncHash([]string{"insecure-1.example.com"}))
+}
+
+// TestGenerateOriginalStorageAndCRIOConfigs ensures that the configs rendered at once for a role are the ones rendered by
+// generateOriginalContainerRuntimeConfigs and the default CRI-O config of the role
+func TestGenerateOriginalStorageAndCRIOConfigs(t *testing.T) {
+	cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+
+	for _, role := range []string{"master", "worker"} {
+		storageIgn, tmpl, err := generateOriginalStorageAndCRIOConfigs(templateDir, cc, role)
+		require.NoError(t, err, role)
+		wantStorageIgn, _, _, err := generateOriginalContainerRuntimeConfigs(templateDir, cc, role)
+		require.NoError(t, err, role)
+		assert.Equal(t, wantStorageIgn, storageIgn, role)
+
+		assert.NotEmpty(t, tmpl.runtimes, role)
+	}
+}
+
+func TestValidateDefaultRuntimeForRole(t *testing.T) {
+	cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+
+	for _, role := range []string{"master", "worker"} {
+		t.Run(role, func(t *testing.T) {
+			_, tmpl, err := generateOriginalStorageAndCRIOConfigs(templateDir, cc, role)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"crun", "runc"}, tmpl.runtimes)
+
+			for _, runtime := range []mcfgv1.ContainerRuntimeDefaultRuntime{"", mcfgv1.ContainerRuntimeDefaultRuntimeCrun, mcfgv1.ContainerRuntimeDefaultRuntimeRunc} {
+				ctrcfg := newContainerRuntimeConfig("default-runtime", &mcfgv1.ContainerRuntimeConfiguration{DefaultRuntime: runtime}, metav1.AddLabelToSelector(&metav1.LabelSelector{}, "", ""))
+				assert.NoError(t, validateDefaultRuntimeForRole(tmpl, role, ctrcfg), "runtime %q", runtime)
+			}
+
+			ctrcfg := newContainerRuntimeConfig("default-runtime", &mcfgv1.ContainerRuntimeConfiguration{DefaultRuntime: "kata"}, metav1.AddLabelToSelector(&metav1.LabelSelector{}, "", ""))
+			err = validateDefaultRuntimeForRole(tmpl, role, ctrcfg)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), `invalid DefaultRuntime "kata"`)
+		})
+	}
+}
+
+func TestImageConfigAllowedAndBlockedRegistries(t *testing.T) {
+	f := newFixture(t)
+	f.skipActionsValidation = true
+
+	cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+	mcp := helpers.NewMachineConfigPool("master", nil, helpers.MasterSelector, "v0")
+	imgcfg := newImageConfig("cluster", &apicfgv1.RegistrySources{AllowedRegistries: []string{"allow.io"}, BlockedRegistries: []string{"block.io"}})
+	cvcfg := newClusterVersionConfig("version", "test.io/myuser/myimage:test")
+
+	f.ccLister = append(f.ccLister, cc)
+	f.mcpLister = append(f.mcpLister, mcp)
+	f.imgLister = append(f.imgLister, imgcfg)
+	f.cvLister = append(f.cvLister, cvcfg)
+	f.imgObjects = append(f.imgObjects, imgcfg)
+
+	c := f.newController()
+	err := c.syncImgHandler("cluster")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "allowedRegistries [allow.io]")
+	assert.Contains(t, err.Error(), "blockedRegistries [block.io]")
+
+	mcs, err := c.client.MachineconfigurationV1().MachineConfigs().List(context.TODO(), metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, mcs.Items)
+}
+
+func TestSelectOverlaySizeConfig(t *testing.T) {
+	small := resource.MustParse("10G")
+	large := resource.MustParse("20G")
+	zero := resource.MustParse("0")
+
+	worker := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "v0")
+	worker.ObjectMeta.Labels["pools.operator.machineconfiguration.openshift.io/worker"] = ""
+	workerLarge := helpers.NewMachineConfigPool("worker-large", nil, helpers.WorkerSelector, "v0")
+	workerLarge.ObjectMeta.Labels["pools.operator.machineconfiguration.openshift.io/worker"] = ""
+	workerLarge.ObjectMeta.Labels["pools.operator.machineconfiguration.openshift.io/worker-large"] = ""
+	pools := []*mcfgv1.MachineConfigPool{worker, workerLarge}
+
+	broad := newContainerRuntimeConfig("broad", &mcfgv1.ContainerRuntimeConfiguration{OverlaySize: &small}, metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/worker", ""))
+	specific := newContainerRuntimeConfig("specific", &mcfgv1.ContainerRuntimeConfiguration{OverlaySize: &large}, metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/worker-large", ""))
+	// Selects the same pools as specific, but with two requirements
+	narrower := newContainerRuntimeConfig("narrower", &mcfgv1.ContainerRuntimeConfiguration{OverlaySize: &large}, metav1.AddLabelToSelector(metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/worker-large", ""), "pools.operator.machineconfiguration.openshift.io/worker", ""))
+	sameAsSpecific := newContainerRuntimeConfig("a-same-as-specific", &mcfgv1.ContainerRuntimeConfiguration{OverlaySize: &large}, metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/worker-large", ""))
+	noOverlay := newContainerRuntimeConfig("no-overlay", &mcfgv1.ContainerRuntimeConfiguration{LogLevel: "debug"}, metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/worker-large", ""))
+	zeroOverlay := newContainerRuntimeConfig("zero-overlay", &mcfgv1.ContainerRuntimeConfiguration{OverlaySize: &zero}, metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/worker-large", ""))
+
+	tests := []struct {
+		name           string
+		pool           *mcfgv1.MachineConfigPool
+		ctrcfgs        []*mcfgv1.ContainerRuntimeConfig
+		wantName       string
+		wantCandidates int
+	}{
+		{
+			name:     "no overlaySize set",
+			pool:     workerLarge,
+			ctrcfgs:  []*mcfgv1.ContainerRuntimeConfig{noOverlay, zeroOverlay},
+			wantName: "",
+		},
+		{
+			name:           "only the broad config applies to the parent pool",
+			pool:           worker,
+			ctrcfgs:        []*mcfgv1.ContainerRuntimeConfig{broad, specific},
+			wantName:       "broad",
+			wantCandidates: 1,
+		},
+		{
+			name:           "the config selecting fewer pools wins",
+			pool:           workerLarge,
+			ctrcfgs:        []*mcfgv1.ContainerRuntimeConfig{broad, specific, noOverlay},
+			wantName:       "specific",
+			wantCandidates: 2,
+		},
+		{
+			name:           "the config with more selector requirements wins",
+			pool:           workerLarge,
+			ctrcfgs:        []*mcfgv1.ContainerRuntimeConfig{specific, narrower},
+			wantName:       "narrower",
+			wantCandidates: 2,
+		},
+		{
+			name:           "the first config by name wins otherwise",
+			pool:           workerLarge,
+			ctrcfgs:        []*mcfgv1.ContainerRuntimeConfig{specific, sameAsSpecific},
+			wantName:       "a-same-as-specific",
+			wantCandidates: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, candidates, err := selectOverlaySizeConfig(test.pool, pools, test.ctrcfgs)
+			require.NoError(t, err)
+			assert.Equal(t, test.wantCandidates, candidates)
+			if test.wantName == "" {
+				assert.Nil(t, got)
+				return
+			}
+			require.NotNil(t, got)
+			assert.Equal(t, test.wantName, got.Name)
+		})
+	}
+}
+
+func TestContainerRuntimeConfigOverlaySizeOverride(t *testing.T) {
+	small := resource.MustParse("10G")
+	large := resource.MustParse("20G")
+
+	f := newFixture(t)
+	f.skipActionsValidation = true
+
+	cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+	worker := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "v0")
+	worker.ObjectMeta.Labels["pools.operator.machineconfiguration.openshift.io/worker"] = ""
+	workerLarge := helpers.NewMachineConfigPool("worker-large", nil, helpers.WorkerSelector, "v0")
+	workerLarge.ObjectMeta.Labels["pools.operator.machineconfiguration.openshift.io/worker"] = ""
+	workerLarge.ObjectMeta.Labels["pools.operator.machineconfiguration.openshift.io/worker-large"] = ""
+	broad := newContainerRuntimeConfig("broad", &mcfgv1.ContainerRuntimeConfiguration{OverlaySize: &small}, metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/worker", ""))
+	specific := newContainerRuntimeConfig("specific", &mcfgv1.ContainerRuntimeConfiguration{OverlaySize: &large}, metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/worker-large", ""))
+
+	f.ccLister = append(f.ccLister, cc)
+	f.mcpLister = append(f.mcpLister, worker, workerLarge)
+	f.mccrLister = append(f.mccrLister, broad, specific)
+	f.objects = append(f.objects, broad, specific)
+
+	c := f.newController()
+	require.NoError(t, c.syncHandler(getKey(broad, t)))
+	require.NoError(t, c.syncHandler(getKey(specific, t)))
+
+	mcs, err := c.client.MachineconfigurationV1().MachineConfigs().List(context.TODO(), metav1.ListOptions{})
+	require.NoError(t, err)
+	// broad renders one MC for each worker pool, specific one for worker-large
+	require.Len(t, mcs.Items, 3)
+	for _, mc := range mcs.Items {
+		ignCfg, err := ctrlcommon.ParseAndConvertConfig(mc.Spec.Config.Raw)
+		require.NoError(t, err)
+		storageConf := ""
+		for _, file := range ignCfg.Storage.Files {
+			if file.Node.Path == storageConfigPath {
+				contents, err := ctrlcommon.DecodeIgnitionFileContents(file.Contents.Source, file.Contents.Compression)
+				require.NoError(t, err)
+				storageConf = string(contents)
+			}
+		}
+		want := `size = "10G"`
+		if strings.HasPrefix(mc.Name, "99-worker-large-") {
+			want = `size = "20G"`
+		}
+		assert.Contains(t, storageConf, want, "unexpected storage.conf in %s", mc.Name)
+	}
+}
+
+// TestContainerRuntimeConfigLegacyFieldsIgnition ensures that a ContainerRuntimeConfig only setting the fields supported
+// by the first releases of ctrcfg renders the same Ignition config as these releases, so that upgrading does not update
+// the MachineConfig and reboot the nodes of the pool
+func TestContainerRuntimeConfigLegacyFieldsIgnition(t *testing.T) {
+	pidsLimit := int64(2048)
+	logSizeMax := resource.MustParse("50Mi")
+	overlaySize := resource.MustParse("10G")
+
+	f := newFixture(t)
+	f.skipActionsValidation = true
+
+	cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+	mcp := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "v0")
+	ctrcfg := newContainerRuntimeConfig("legacy", &mcfgv1.ContainerRuntimeConfiguration{
+		LogLevel:       "debug",
+		PidsLimit:      &pidsLimit,
+		LogSizeMax:     &logSizeMax,
+		DefaultRuntime: mcfgv1.ContainerRuntimeDefaultRuntimeCrun,
+		OverlaySize:    &overlaySize,
+	}, metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/worker", ""))
+	f.ccLister = append(f.ccLister, cc)
+	f.mcpLister = append(f.mcpLister, mcp)
+	f.mccrLister = append(f.mccrLister, ctrcfg)
+	f.objects = append(f.objects, ctrcfg)
+
+	c := f.newController()
+	require.NoError(t, c.syncHandler(getKey(ctrcfg, t)))
+	mc, err := c.client.MachineconfigurationV1().MachineConfigs().Get(context.TODO(), "99-worker-generated-containerruntime", metav1.GetOptions{})
+	require.NoError(t, err)
+
+	originalStorageIgn, _, _, err := generateOriginalContainerRuntimeConfigs(templateDir, cc, "worker")
+	require.NoError(t, err)
+	storageTOML, err := mergeConfigChanges(originalStorageIgn, ctrcfg, updateStorageConfig)
+	require.NoError(t, err)
+	wantIgn, err := createNewIgnition([]generatedConfigFile{
+		{filePath: storageConfigPath, data: storageTOML},
+		{filePath: CRIODropInFilePathLogLevel, data: []byte("[crio]\n  [crio.runtime]\n    log_level = \"debug\"\n")},
+		{filePath: crioDropInFilePathPidsLimit, data: []byte("[crio]\n  [crio.runtime]\n    pids_limit = 2048\n")},
+		{filePath: crioDropInFilePathLogSizeMax, data: []byte("[crio]\n  [crio.runtime]\n    log_size_max = 52428800\n")},
+		{filePath: CRIODropInFilePathDefaultRuntime, data: []byte("[crio]\n  [crio.runtime]\n    default_runtime = \"crun\"\n")},
+	})
+	require.NoError(t, err)
+	wantRaw, err := json.Marshal(wantIgn)
+	require.NoError(t, err)
+	assert.Equal(t, string(wantRaw), string(mc.Spec.Config.Raw))
+}
+
+func TestContainerRuntimeConfigSingleMachineConfig(t *testing.T) {
+	overlaySize := resource.MustParse("10G")
+	pidsLimit := int64(2048)
+
+	tests := []struct {
+		name      string
+		spec      *mcfgv1.ContainerRuntimeConfiguration
+		wantFiles []string
+	}{
+		{
+			name:      "storage only",
+			spec:      &mcfgv1.ContainerRuntimeConfiguration{OverlaySize: &overlaySize},
+			wantFiles: []string{storageConfigPath},
+		},
+		{
+			name:      "crio only",
+			spec:      &mcfgv1.ContainerRuntimeConfiguration{LogLevel: "debug", PidsLimit: &pidsLimit},
+			wantFiles: []string{CRIODropInFilePathLogLevel, crioDropInFilePathPidsLimit},
+		},
+		{
+			name:      "storage and crio",
+			spec:      &mcfgv1.ContainerRuntimeConfiguration{OverlaySize: &overlaySize, LogLevel: "debug", PidsLimit: &pidsLimit},
+			wantFiles: []string{storageConfigPath, CRIODropInFilePathLogLevel, crioDropInFilePathPidsLimit},
+		},
+	}
+
+	for _, platform := range []apicfgv1.PlatformType{apicfgv1.AWSPlatformType, apicfgv1.NonePlatformType} {
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("%s/%s", platform, test.name), func(t *testing.T) {
+				f := newFixture(t)
+				f.skipActionsValidation = true
+
+				cc := newControllerConfig(ctrlcommon.ControllerConfigName, platform)
+				mcp := helpers.NewMachineConfigPool("master", nil, helpers.MasterSelector, "v0")
+				mcp2 := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "v0")
+				ctrcfg := newContainerRuntimeConfig("set-ctrcfg", test.spec, metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/master", ""))
+
+				f.ccLister = append(f.ccLister, cc)
+				f.mcpLister = append(f.mcpLister, mcp, mcp2)
+				acknowledgeMasterChanges(ctrcfg)
+				f.mccrLister = append(f.mccrLister, ctrcfg)
+				f.objects = append(f.objects, ctrcfg)
+
+				c := f.newController()
+				require.NoError(t, c.syncHandler(getKey(ctrcfg, t)))
+
+				mcs, err := c.client.MachineconfigurationV1().MachineConfigs().List(context.TODO(), metav1.ListOptions{})
+				require.NoError(t, err)
+				require.Len(t, mcs.Items, 1)
+				assert.Equal(t, "99-master-generated-containerruntime", mcs.Items[0].Name)
+
+				ignCfg, err := ctrlcommon.ParseAndConvertConfig(mcs.Items[0].Spec.Config.Raw)
+				require.NoError(t, err)
+				var gotFiles []string
+				for _, file := range ignCfg.Storage.Files {
+					gotFiles = append(gotFiles, file.Node.Path)
+				}
+				assert.Equal(t, test.wantFiles, gotFiles)
+			})
+		}
+	}
+}
+
+func TestContainerRuntimeConfigDryRun(t *testing.T) {
+	f := newFixture(t)
+	f.skipActionsValidation = true
+
+	cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+	mcp := helpers.NewMachineConfigPool("master", nil, helpers.MasterSelector, "v0")
+	mcp2 := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "v0")
+	ctrcfg := newContainerRuntimeConfig("dry-run", &mcfgv1.ContainerRuntimeConfiguration{LogLevel: "debug"}, metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/master", ""))
+	ctrcfg.SetAnnotations(map[string]string{ctrlcommon.ContainerRuntimeConfigDryRunAnnotationKey: "true"})
+
+	f.ccLister = append(f.ccLister, cc)
+	f.mcpLister = append(f.mcpLister, mcp, mcp2)
+	f.mccrLister = append(f.mccrLister, ctrcfg)
+	f.objects = append(f.objects, ctrcfg)
+
+	c := f.newController()
+	recorder := record.NewFakeRecorder(10)
+	c.eventRecorder = recorder
+	require.NoError(t, c.syncHandler(getKey(ctrcfg, t)))
+
+	mcs, err := c.client.MachineconfigurationV1().MachineConfigs().List(context.TODO(), metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, mcs.Items, "no MachineConfig should be created in dry-run mode")
+
+	var status *mcfgv1.ContainerRuntimeConfigStatus
+	for _, action := range f.client.Actions() {
+		assert.False(t, action.Matches("patch", "containerruntimeconfigs"), "no finalizer should be added in dry-run mode")
+		if action.Matches("update", "containerruntimeconfigs") && action.GetSubresource() == "status" {
+			status = &action.(core.UpdateAction).GetObject().(*mcfgv1.ContainerRuntimeConfig).Status
+		}
+	}
+	require.NotNil(t, status)
+	lastCondition := status.Conditions[len(status.Conditions)-1]
+	assert.Equal(t, mcfgv1.ContainerRuntimeConfigSuccess, lastCondition.Type)
+	assert.Contains(t, lastCondition.Message, "Dry run")
+
+	// The rendered Ignition config of each pool is summarized in an event
+	require.Len(t, recorder.Events, 2)
+	ignCfg, err := createNewIgnition([]generatedConfigFile{
+		{filePath: CRIODropInFilePathLogLevel, data: []byte("[crio]\n  [crio.runtime]\n    log_level = \"debug\"\n")},
+	})
+	require.NoError(t, err)
+	rawIgn, err := json.Marshal(ignCfg)
+	require.NoError(t, err)
+	assert.Equal(t, "Normal ContainerRuntimeConfigDryRunPreview MachineConfig 99-master-generated-containerruntime of MachineConfigPool master would be rendered with files "+
+		CRIODropInFilePathLogLevel+" and Ignition config sha256 "+getIgnitionConfigHash(rawIgn), <-recorder.Events)
+	assert.Contains(t, <-recorder.Events, "ContainerRuntimeConfigDryRun")
+}
+
+func TestContainerRuntimeConfigForceRegenerate(t *testing.T) {
+	f := newFixture(t)
+	f.skipActionsValidation = true
+
+	cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+	mcp := helpers.NewMachineConfigPool("infra", nil, helpers.InfraSelector, "v0")
+	rollOutMachineConfigs(mcp, "99-infra-generated-containerruntime")
+	ctrcfg := newContainerRuntimeConfig("force-regenerate", &mcfgv1.ContainerRuntimeConfiguration{LogLevel: "debug"}, metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/infra", ""))
+
+	f.ccLister = append(f.ccLister, cc)
+	f.mcpLister = append(f.mcpLister, mcp)
+	f.mccrLister = append(f.mccrLister, ctrcfg)
+	f.objects = append(f.objects, ctrcfg)
+
+	c := f.newController()
+	// sync runs the handler with the status recorded by the previous sync, and returns the updated MachineConfig if any
+	sync := func() *mcfgv1.MachineConfig {
+		f.client.ClearActions()
+		require.NoError(t, c.syncHandler(getKey(ctrcfg, t)))
+		var mc *mcfgv1.MachineConfig
+		for _, action := range f.client.Actions() {
+			switch {
+			case action.Matches("update", "containerruntimeconfigs") && action.GetSubresource() == "status":
+				ctrcfg.Status = action.(core.UpdateAction).GetObject().(*mcfgv1.ContainerRuntimeConfig).Status
+			case action.Matches("update", "machineconfigs"), action.Matches("create", "machineconfigs"):
+				mc = action.(core.CreateAction).GetObject().(*mcfgv1.MachineConfig)
+			}
+		}
+		return mc
+	}
+
+	require.NotNil(t, sync())
+	assert.Nil(t, sync(), "an up to date MachineConfig should not be regenerated")
+
+	metav1.SetMetaDataAnnotation(&ctrcfg.ObjectMeta, ctrlcommon.ContainerRuntimeConfigForceRegenerateAnnotationKey, "1")
+	mc := sync()
+	require.NotNil(t, mc, "a new force regenerate value should regenerate the MachineConfig")
+	assert.Equal(t, "1", mc.Annotations[ctrlcommon.ContainerRuntimeConfigForceRegenerateAnnotationKey])
+	assert.Nil(t, sync(), "the same force regenerate value should not regenerate the MachineConfig again")
+
+	metav1.SetMetaDataAnnotation(&ctrcfg.ObjectMeta, ctrlcommon.ContainerRuntimeConfigForceRegenerateAnnotationKey, "2")
+	mc = sync()
+	require.NotNil(t, mc)
+	assert.Equal(t, "2", mc.Annotations[ctrlcommon.ContainerRuntimeConfigForceRegenerateAnnotationKey])
+}
+
+// TestContainerRuntimeConfigSingleCRIODropin ensures that the CRI-O settings of a ContainerRuntimeConfig are rendered
+// in a single drop-in file when asked to, and that adding or removing the annotation regenerates the MachineConfig
+func TestContainerRuntimeConfigSingleCRIODropin(t *testing.T) {
+	f := newFixture(t)
+	f.skipActionsValidation = true
+
+	cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+	mcp := helpers.NewMachineConfigPool("infra", nil, helpers.InfraSelector, "v0")
+	rollOutMachineConfigs(mcp, "99-infra-generated-containerruntime")
+	var pidsLimit int64 = 2048
+	ctrcfg := newContainerRuntimeConfig("single-dropin", &mcfgv1.ContainerRuntimeConfiguration{
+		LogLevel:  "debug",
+		PidsLimit: &pidsLimit,
+	}, metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/infra", ""))
+	metav1.SetMetaDataAnnotation(&ctrcfg.ObjectMeta, ctrlcommon.ContainerRuntimeConfigSingleCRIODropinAnnotationKey, "true")
+
+	f.ccLister = append(f.ccLister, cc)
+	f.mcpLister = append(f.mcpLister, mcp)
+	f.mccrLister = append(f.mccrLister, ctrcfg)
+	f.objects = append(f.objects, ctrcfg)
+
+	c := f.newController()
+	// sync runs the handler with the status recorded by the previous sync, and returns the updated MachineConfig if any
+	sync := func() *mcfgv1.MachineConfig {
+		f.client.ClearActions()
+		require.NoError(t, c.syncHandler(getKey(ctrcfg, t)))
+		var mc *mcfgv1.MachineConfig
+		for _, action := range f.client.Actions() {
+			switch {
+			case action.Matches("update", "containerruntimeconfigs") && action.GetSubresource() == "status":
+				ctrcfg.Status = action.(core.UpdateAction).GetObject().(*mcfgv1.ContainerRuntimeConfig).Status
+			case action.Matches("update", "machineconfigs"), action.Matches("create", "machineconfigs"):
+				mc = action.(core.CreateAction).GetObject().(*mcfgv1.MachineConfig)
+			}
+		}
+		return mc
+	}
+	crioDropins := func(mc *mcfgv1.MachineConfig) []string {
+		ignCfg, err := ctrlcommon.ParseAndConvertConfig(mc.Spec.Config.Raw)
+		require.NoError(t, err)
+		var paths []string
+		for _, file := range ignCfg.Storage.Files {
+			if strings.HasPrefix(file.Path, crioDropInDir) {
+				paths = append(paths, file.Path)
+			}
+		}
+		return paths
+	}
+
+	mc := sync()
+	require.NotNil(t, mc)
+	assert.Equal(t, []string{crioDropInFilePathSingle}, crioDropins(mc))
+	assert.Equal(t, "true", mc.Annotations[ctrlcommon.ContainerRuntimeConfigSingleCRIODropinAnnotationKey])
+	data, err := DecodeGeneratedConfigFile(mc, crioDropInFilePathSingle)
+	require.NoError(t, err)
+	assert.Equal(t, `[crio]
+  [crio.runtime]
+    log_level = "debug"
+    pids_limit = 2048
+`, string(data))
+	assert.Nil(t, sync(), "an up to date MachineConfig should not be regenerated")
+
+	delete(ctrcfg.Annotations, ctrlcommon.ContainerRuntimeConfigSingleCRIODropinAnnotationKey)
+	mc = sync()
+	require.NotNil(t, mc, "removing the annotation should regenerate the MachineConfig")
+	assert.Equal(t, []string{CRIODropInFilePathLogLevel, crioDropInFilePathPidsLimit}, crioDropins(mc))
+	assert.NotContains(t, mc.Annotations, ctrlcommon.ContainerRuntimeConfigSingleCRIODropinAnnotationKey)
+}
+
+// TestValidateContainerRuntimeConfigSpecMatchesSync ensures that the spec an admission webhook would reject with
+// ValidateContainerRuntimeConfigSpec is reported invalid by the controller with the same error, and the other way around
+func TestValidateContainerRuntimeConfigSpecMatchesSync(t *testing.T) {
+	var pidsLimit int64 = 10
+	tests := []struct {
+		name   string
+		config *mcfgv1.ContainerRuntimeConfiguration
+	}{
+		{
+			name:   "valid",
+			config: &mcfgv1.ContainerRuntimeConfiguration{LogLevel: "debug"},
+		},
+		{
+			name:   "invalid log level",
+			config: &mcfgv1.ContainerRuntimeConfiguration{LogLevel: "verbose"},
+		},
+		{
+			name:   "pids limit too low",
+			config: &mcfgv1.ContainerRuntimeConfiguration{PidsLimit: &pidsLimit},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.skipActionsValidation = true
+
+			cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+			mcp := helpers.NewMachineConfigPool("infra", nil, helpers.InfraSelector, "v0")
+			ctrcfg := newContainerRuntimeConfig("admission", test.config, metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/infra", ""))
+			f.ccLister = append(f.ccLister, cc)
+			f.mcpLister = append(f.mcpLister, mcp)
+			f.mccrLister = append(f.mccrLister, ctrcfg)
+			f.objects = append(f.objects, ctrcfg)
+
+			specErr := ValidateContainerRuntimeConfigSpec(ctrcfg.Spec)
+			c := f.newController()
+			syncErr := c.syncHandler(getKey(ctrcfg, t))
+			assert.Equal(t, specErr, syncErr)
+
+			latest, err := c.client.MachineconfigurationV1().ContainerRuntimeConfigs().Get(context.TODO(), ctrcfg.Name, metav1.GetOptions{})
+			require.NoError(t, err)
+			require.NotEmpty(t, latest.Status.Conditions)
+			condition := latest.Status.Conditions[len(latest.Status.Conditions)-1]
+			if specErr == nil {
+				assert.NotEqual(t, mcfgv1.ContainerRuntimeConfigFailure, condition.Type)
+				return
+			}
+			var fieldErr *FieldValidationError
+			require.ErrorAs(t, specErr, &fieldErr)
+			assert.Equal(t, mcfgv1.ContainerRuntimeConfigFailure, condition.Type)
+			assert.Equal(t, "Invalid"+fieldErr.Field, condition.Reason)
+			assert.Equal(t, "Error: "+specErr.Error(), condition.Message)
+		})
+	}
+}
+
+// TestGeneratedIgnitionDecodeGuard ensures that a MachineConfig whose marshaled Ignition config does not decode is
+// never written, for both the ContainerRuntimeConfig and the Image config MachineConfigs
+func TestGeneratedIgnitionDecodeGuard(t *testing.T) {
+	corruptMarshalers := map[string]func(interface{}) ([]byte, error){
+		"truncated JSON": func(v interface{}) ([]byte, error) {
+			raw, err := json.Marshal(v)
+			return raw[:len(raw)/2], err
+		},
+		"unsupported Ignition version": func(interface{}) ([]byte, error) {
+			return []byte(`{"ignition":{"version":"9.9.9"}}`), nil
+		},
+	}
+	mcWrites := func(f *fixture) int {
+		writes := 0
+		for _, action := range f.client.Actions() {
+			if action.Matches("create", "machineconfigs") || action.Matches("update", "machineconfigs") {
+				writes++
+			}
+		}
+		return writes
+	}
+
+	for name, marshal := range corruptMarshalers {
+		t.Run(name+"/ContainerRuntimeConfig", func(t *testing.T) {
+			f := newFixture(t)
+			f.skipActionsValidation = true
+
+			cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+			mcp := helpers.NewMachineConfigPool("infra", nil, helpers.InfraSelector, "v0")
+			ctrcfg := newContainerRuntimeConfig("corrupt", &mcfgv1.ContainerRuntimeConfiguration{LogLevel: "debug"}, metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/infra", ""))
+			f.ccLister = append(f.ccLister, cc)
+			f.mcpLister = append(f.mcpLister, mcp)
+			f.mccrLister = append(f.mccrLister, ctrcfg)
+			f.objects = append(f.objects, ctrcfg)
+
+			c := f.newController()
+			c.marshalIgnition = marshal
+			require.Error(t, c.syncHandler(getKey(ctrcfg, t)))
+			assert.Equal(t, 0, mcWrites(f))
+
+			latest, err := c.client.MachineconfigurationV1().ContainerRuntimeConfigs().Get(context.TODO(), ctrcfg.Name, metav1.GetOptions{})
+			require.NoError(t, err)
+			require.NotEmpty(t, latest.Status.Conditions)
+			condition := latest.Status.Conditions[len(latest.Status.Conditions)-1]
+			assert.Equal(t, mcfgv1.ContainerRuntimeConfigFailure, condition.Type)
+			assert.Contains(t, condition.Message, "generated Ignition config does not decode")
+		})
+
+		t.Run(name+"/Image config", func(t *testing.T) {
+			f := newFixture(t)
+			f.skipActionsValidation = true
+
+			cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+			mcp := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "v0")
+			mcp.ObjectMeta.Labels[builtInLabelKey] = ""
+			imgcfg := newImageConfig("cluster", &apicfgv1.RegistrySources{InsecureRegistries: []string{"insecure.io"}})
+			cvcfg := newClusterVersionConfig("version", "test.io/myuser/myimage:test")
+			f.ccLister = append(f.ccLister, cc)
+			f.mcpLister = append(f.mcpLister, mcp)
+			f.imgLister = append(f.imgLister, imgcfg)
+			f.cvLister = append(f.cvLister, cvcfg)
+			f.imgObjects = append(f.imgObjects, imgcfg)
+
+			c := f.newController()
+			c.marshalIgnition = marshal
+			err := c.syncImgHandler("cluster")
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "generated Ignition config does not decode")
+			assert.Equal(t, 0, mcWrites(f))
+		})
+	}
+}
+
+// TestImageConfigNoOpResync ensures that resyncing an unchanged Image config does not render the registries again
+func TestImageConfigNoOpResync(t *testing.T) {
+	f := newFixture(t)
+	f.skipActionsValidation = true
+
+	cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+	mcp := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "v0")
+	mcp.ObjectMeta.Labels[builtInLabelKey] = ""
+	imgcfg := newImageConfig("cluster", &apicfgv1.RegistrySources{InsecureRegistries: []string{"insecure.io"}})
+	imgcfg.Generation = 1
+	cvcfg := newClusterVersionConfig("version", "test.io/myuser/myimage:test")
+	icsp := newICSP("icsp", []apioperatorsv1alpha1.RepositoryDigestMirrors{
+		{Source: "source.example.com", Mirrors: []string{"mirror.example.com"}},
+	})
+	icsp.ResourceVersion = "1"
+
+	f.ccLister = append(f.ccLister, cc)
+	f.mcpLister = append(f.mcpLister, mcp)
+	f.imgLister = append(f.imgLister, imgcfg)
+	f.cvLister = append(f.cvLister, cvcfg)
+	f.icspLister = append(f.icspLister, icsp)
+	f.imgObjects = append(f.imgObjects, imgcfg)
+
+	c := f.newController()
+	require.NoError(t, c.syncImgHandler("cluster"))
+	managedKey, err := getManagedKeyReg(mcp, nil)
+	require.NoError(t, err)
+	_, err = c.client.MachineconfigurationV1().MachineConfigs().Get(context.TODO(), managedKey, metav1.GetOptions{})
+	require.NoError(t, err)
+
+	mcWrites := func() int {
+		writes := 0
+		for _, action := range f.client.Actions() {
+			if action.Matches("create", "machineconfigs") || action.Matches("update", "machineconfigs") || action.Matches("patch", "machineconfigs") {
+				writes++
+			}
+		}
+		return writes
+	}
+
+	// Nothing changed, the resync only checks the registries MC
+	noops := ctrlcommon.MCCImageConfigSyncs.WithLabelValues(syncResultNoop)
+	applied := ctrlcommon.MCCImageConfigSyncs.WithLabelValues(syncResultApplied)
+	noopsBefore, appliedBefore := testutil.ToFloat64(noops), testutil.ToFloat64(applied)
+	f.client.ClearActions()
+	require.NoError(t, c.syncImgHandler("cluster"))
+	assert.Equal(t, 0, mcWrites())
+	assert.Equal(t, noopsBefore+1, testutil.ToFloat64(noops))
+	assert.Equal(t, appliedBefore, testutil.ToFloat64(applied))
+	for _, action := range f.client.Actions() {
+		assert.True(t, action.Matches("get", "machineconfigs"), "unexpected action %v", action)
+	}
+
+	// A registries MC edited out of band is rendered again
+	mc, err := c.client.MachineconfigurationV1().MachineConfigs().Get(context.TODO(), managedKey, metav1.GetOptions{})
+	require.NoError(t, err)
+	renderedIgn := mc.Spec.Config.Raw
+	mc.Spec.Config.Raw = helpers.NewMachineConfig(managedKey, nil, "dummy://", []ign3types.File{{}}).Spec.Config.Raw
+	_, err = c.client.MachineconfigurationV1().MachineConfigs().Update(context.TODO(), mc, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	f.client.ClearActions()
+	require.NoError(t, c.syncImgHandler("cluster"))
+	assert.Equal(t, 1, mcWrites())
+	mc, err = c.client.MachineconfigurationV1().MachineConfigs().Get(context.TODO(), managedKey, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, renderedIgn, mc.Spec.Config.Raw)
+
+	// So is a registries MC deleted out of band
+	require.NoError(t, c.client.MachineconfigurationV1().MachineConfigs().Delete(context.TODO(), managedKey, metav1.DeleteOptions{}))
+	f.client.ClearActions()
+	require.NoError(t, c.syncImgHandler("cluster"))
+	assert.Equal(t, 1, mcWrites())
+	_, err = c.client.MachineconfigurationV1().MachineConfigs().Get(context.TODO(), managedKey, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, appliedBefore+2, testutil.ToFloat64(applied))
+	appliedBefore = testutil.ToFloat64(applied)
+
+	// A new resource version of the ICSP renders the registries again, even though the Image config is unchanged
+	icsp = icsp.DeepCopy()
+	icsp.ResourceVersion = "2"
+	icsp.Spec.RepositoryDigestMirrors[0].Mirrors = []string{"other-mirror.example.com"}
+	require.NoError(t, f.operatorInformers.Operator().V1alpha1().ImageContentSourcePolicies().Informer().GetIndexer().Update(icsp))
+	f.client.ClearActions()
+	require.NoError(t, c.syncImgHandler("cluster"))
+	assert.Equal(t, 1, mcWrites())
+	assert.Equal(t, appliedBefore+1, testutil.ToFloat64(applied))
+
+	// So does a new generation of the Image config
+	imgcfg = imgcfg.DeepCopy()
+	imgcfg.Generation = 2
+	imgcfg.Spec.RegistrySources.InsecureRegistries = []string{"other-insecure.io"}
+	require.NoError(t, f.configInformers.Config().V1().Images().Informer().GetIndexer().Update(imgcfg))
+	f.client.ClearActions()
+	require.NoError(t, c.syncImgHandler("cluster"))
+	assert.Equal(t, 1, mcWrites())
+}
+
+func TestImageConfigReleaseImageChange(t *testing.T) {
+	f := newFixture(t)
+	f.skipActionsValidation = true
+
+	cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+	cc.Spec.ReleaseImage = "old-release.io/ocp/release:4.18"
+	mcp := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "v0")
+	mcp.ObjectMeta.Labels[builtInLabelKey] = ""
+	imgcfg := newImageConfig("cluster", &apicfgv1.RegistrySources{BlockedRegistries: []string{"old-release.io", "new-release.io"}})
+	cvcfg := newClusterVersionConfig("version", cc.Spec.ReleaseImage)
+
+	f.ccLister = append(f.ccLister, cc)
+	f.mcpLister = append(f.mcpLister, mcp)
+	f.imgLister = append(f.imgLister, imgcfg)
+	f.cvLister = append(f.cvLister, cvcfg)
+
+	c := f.newController()
+	managedKey, err := getManagedKeyReg(mcp, nil)
+	require.NoError(t, err)
+	blockedRegistries := func() []string {
+		mc, err := c.client.MachineconfigurationV1().MachineConfigs().Get(context.TODO(), managedKey, metav1.GetOptions{})
+		require.NoError(t, err)
+		data, err := DecodeGeneratedConfigFile(mc, registriesConfigPath)
+		require.NoError(t, err)
+		registriesConf := struct {
+			Registries []struct {
+				Location string `toml:"location"`
+				Blocked  bool   `toml:"blocked"`
+			} `toml:"registry"`
+		}{}
+		_, err = toml.Decode(string(data), &registriesConf)
+		require.NoError(t, err)
+		var blocked []string
+		for _, reg := range registriesConf.Registries {
+			if reg.Blocked {
+				blocked = append(blocked, reg.Location)
+			}
+		}
+		return blocked
+	}
+
+	require.NoError(t, c.syncImgHandler("cluster"))
+	assert.Equal(t, []string{"new-release.io"}, blockedRegistries())
+
+	// An unrelated change of the ControllerConfig does not sync the Image config
+	newCC := cc.DeepCopy()
+	newCC.Spec.EtcdDiscoveryDomain = "other.tt.testing"
+	c.controllerConfigUpdated(cc, newCC)
+	assert.Equal(t, 0, c.imgQueue.Len())
+
+	// Upgrade to a release image from the other registry
+	newCC.Spec.ReleaseImage = "new-release.io/ocp/release:4.19"
+	newCC.Generation++
+	newCV := cvcfg.DeepCopy()
+	newCV.Status.Desired.Image = newCC.Spec.ReleaseImage
+	require.NoError(t, f.mcfgInformers.Machineconfiguration().V1().ControllerConfigs().Informer().GetIndexer().Update(newCC))
+	require.NoError(t, f.configInformers.Config().V1().ClusterVersions().Informer().GetIndexer().Update(newCV))
+	c.controllerConfigUpdated(cc, newCC)
+	require.Equal(t, 1, c.imgQueue.Len())
+	require.True(t, c.processNextImgWorkItem())
+	assert.Equal(t, []string{"old-release.io"}, blockedRegistries())
+}
+
+func TestImageConfigDuplicateRegistriesMC(t *testing.T) {
+	f := newFixture(t)
+	f.skipActionsValidation = true
+
+	cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+	mcp := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "v0")
+	mcp.ObjectMeta.Labels["pools.operator.machineconfiguration.openshift.io/worker"] = ""
+	imgcfg := newImageConfig("cluster", &apicfgv1.RegistrySources{InsecureRegistries: []string{"insecure.io"}})
+	cvcfg := newClusterVersionConfig("version", "test.io/myuser/myimage:test")
+	ctrcfg := newContainerRuntimeConfig("log-level", &mcfgv1.ContainerRuntimeConfiguration{LogLevel: "debug"}, metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/worker", ""))
+
+	// Both the deprecated and the current registries MCs of the pool exist, and both are owned by the ContainerRuntimeConfig
+	ctrcfgOwner := *metav1.NewControllerRef(ctrcfg, controllerKind)
+	staleMC := helpers.NewMachineConfig(getManagedKeyRegDeprecated(mcp), map[string]string{"node-role": "worker"}, "dummy://", []ign3types.File{{}})
+	staleMC.OwnerReferences = []metav1.OwnerReference{ctrcfgOwner}
+	managedMC := helpers.NewMachineConfig("99-worker-generated-registries", map[string]string{"node-role": "worker"}, "dummy://", []ign3types.File{{}})
+	managedMC.OwnerReferences = []metav1.OwnerReference{ctrcfgOwner}
+
+	f.ccLister = append(f.ccLister, cc)
+	f.mcpLister = append(f.mcpLister, mcp)
+	f.imgLister = append(f.imgLister, imgcfg)
+	f.cvLister = append(f.cvLister, cvcfg)
+	f.imgObjects = append(f.imgObjects, imgcfg)
+	f.objects = append(f.objects, staleMC, managedMC)
+
+	c := f.newController()
+	require.NoError(t, c.syncImgHandler("cluster"))
+	// A second sync must leave the single registries MC alone
+	require.NoError(t, c.syncImgHandler("cluster"))
+
+	_, err := c.client.MachineconfigurationV1().MachineConfigs().Get(context.TODO(), staleMC.Name, metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err), "the duplicate registries MachineConfig should be deleted")
+
+	mcs, err := c.client.MachineconfigurationV1().MachineConfigs().List(context.TODO(), metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, mcs.Items, 1)
+	mc := mcs.Items[0]
+	assert.Equal(t, managedMC.Name, mc.Name)
+	require.Len(t, mc.OwnerReferences, 1)
+	assert.Equal(t, "Image", mc.OwnerReferences[0].Kind)
+	assert.Equal(t, imgcfg.UID, mc.OwnerReferences[0].UID)
+
+	updates := 0
+	for _, action := range f.client.Actions() {
+		if action.Matches("update", "machineconfigs") {
+			updates++
+		}
+	}
+	assert.Equal(t, 1, updates, "the registries MachineConfig should only be updated once")
+}
+
+func TestImageConfigDeleted(t *testing.T) {
+	cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+	mcp := helpers.NewMachineConfigPool("master", nil, helpers.MasterSelector, "v0")
+	mcp.ObjectMeta.Labels[builtInLabelKey] = ""
+	mcp2 := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "v0")
+	mcp2.ObjectMeta.Labels[builtInLabelKey] = ""
+	imgcfg := newImageConfig("cluster", &apicfgv1.RegistrySources{InsecureRegistries: []string{"insecure.io"}})
+	cvcfg := newClusterVersionConfig("version", "test.io/myuser/myimage:test")
+
+	// Render the registries MCs from the Image config
+	f := newFixture(t)
+	f.skipActionsValidation = true
+	f.ccLister = append(f.ccLister, cc)
+	f.mcpLister = append(f.mcpLister, mcp, mcp2)
+	f.imgLister = append(f.imgLister, imgcfg)
+	f.cvLister = append(f.cvLister, cvcfg)
+	f.imgObjects = append(f.imgObjects, imgcfg)
+
+	c := f.newController()
+	require.NoError(t, c.syncImgHandler("cluster"))
+	mcs, err := c.client.MachineconfigurationV1().MachineConfigs().List(context.TODO(), metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, mcs.Items, 2)
+
+	// A MC with the deprecated registries name of the worker pool that is not owned by the Image config
+	unownedMC := helpers.NewMachineConfig(getManagedKeyRegDeprecated(mcp2), map[string]string{"node-role": "worker"}, "dummy://", []ign3types.File{{}})
+
+	// Delete the Image config
+	f = newFixture(t)
+	f.skipActionsValidation = true
+	f.ccLister = append(f.ccLister, cc)
+	f.mcpLister = append(f.mcpLister, mcp, mcp2)
+	f.cvLister = append(f.cvLister, cvcfg)
+	f.objects = append(f.objects, &mcs.Items[0], &mcs.Items[1], unownedMC)
+
+	c = f.newController()
+	_, err = c.kubeClient.CoreV1().ConfigMaps(ctrlcommon.MCONamespace).Create(context.TODO(), &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: registriesMachineConfigsConfigMapName, Namespace: ctrlcommon.MCONamespace},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+	c.imageConfDeleted(imgcfg)
+	require.Equal(t, 1, c.imgQueue.Len())
+	key, _ := c.imgQueue.Get()
+	require.Equal(t, imageConfigDeletedKey, key)
+	require.NoError(t, c.syncImgHandler(key))
+
+	mcs, err = c.client.MachineconfigurationV1().MachineConfigs().List(context.TODO(), metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, mcs.Items, 1, "only the MachineConfig not owned by the Image config should be left")
+	assert.Equal(t, unownedMC.Name, mcs.Items[0].Name)
+
+	// The listing of the registries MCs went away with them
+	_, err = c.kubeClient.CoreV1().ConfigMaps(ctrlcommon.MCONamespace).Get(context.TODO(), registriesMachineConfigsConfigMapName, metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err), "expected the ConfigMap to be deleted, got %v", err)
+}
+
+// TestRegistriesMCStillRequired ensures that the clean up paths do not delete the last registries MC of a pool while
+// the Image config still requires it
+func TestRegistriesMCStillRequired(t *testing.T) {
+	cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+	mcp := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "v0")
+	mcp.ObjectMeta.Labels[builtInLabelKey] = ""
+	imgcfg := newImageConfig("cluster", &apicfgv1.RegistrySources{InsecureRegistries: []string{"insecure.io"}})
+
+	t.Run("Image config recreated", func(t *testing.T) {
+		f := newFixture(t)
+		f.skipActionsValidation = true
+
+		managedMC := helpers.NewMachineConfig("99-worker-generated-registries", map[string]string{"node-role": "worker"}, "dummy://", []ign3types.File{{}})
+		managedMC.OwnerReferences = []metav1.OwnerReference{ownerReferenceImageConfig(imgcfg)}
+
+		// The lister has not seen the Image config being recreated yet
+		f.ccLister = append(f.ccLister, cc)
+		f.mcpLister = append(f.mcpLister, mcp)
+		f.imgObjects = append(f.imgObjects, imgcfg)
+		f.objects = append(f.objects, managedMC)
+
+		c := f.newController()
+		require.NoError(t, c.syncImgHandler(imageConfigDeletedKey))
+
+		_, err := c.client.MachineconfigurationV1().MachineConfigs().Get(context.TODO(), managedMC.Name, metav1.GetOptions{})
+		assert.NoError(t, err, "the registries MachineConfig still required by the Image config should be kept")
+	})
+
+	t.Run("current registries MC missing", func(t *testing.T) {
+		f := newFixture(t)
+		f.skipActionsValidation = true
+
+		deprecatedMC := helpers.NewMachineConfig(getManagedKeyRegDeprecated(mcp), map[string]string{"node-role": "worker"}, "dummy://", []ign3types.File{{}})
+		deprecatedMC.OwnerReferences = []metav1.OwnerReference{ownerReferenceImageConfig(imgcfg)}
+
+		f.ccLister = append(f.ccLister, cc)
+		f.mcpLister = append(f.mcpLister, mcp)
+		f.imgLister = append(f.imgLister, imgcfg)
+		f.imgObjects = append(f.imgObjects, imgcfg)
+		f.objects = append(f.objects, deprecatedMC)
+
+		c := f.newController()
+		require.Error(t, c.removeDuplicateRegistriesMC(mcp, "99-worker-generated-registries"))
+
+		_, err := c.client.MachineconfigurationV1().MachineConfigs().Get(context.TODO(), deprecatedMC.Name, metav1.GetOptions{})
+		assert.NoError(t, err, "the only registries MachineConfig of the pool should be kept")
+	})
+}
+
+// TestImageConfigRegistriesMachineConfigsConfigMap ensures that the registries MCs generated from the Image config
+// and the hashes of the registries.conf they render are listed in a ConfigMap, which follows the Image config updates.
+func TestImageConfigRegistriesMachineConfigsConfigMap(t *testing.T) {
+	f := newFixture(t)
+	f.skipActionsValidation = true
+
+	cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+	mcp := helpers.NewMachineConfigPool("master", nil, helpers.MasterSelector, "v0")
+	mcp2 := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "v0")
+	imgcfg := newImageConfig("cluster", &apicfgv1.RegistrySources{InsecureRegistries: []string{"insecure.io"}})
+	cvcfg := newClusterVersionConfig("version", "test.io/myuser/myimage:test")
+
+	f.ccLister = append(f.ccLister, cc)
+	f.mcpLister = append(f.mcpLister, mcp, mcp2)
+	f.imgLister = append(f.imgLister, imgcfg)
+	f.cvLister = append(f.cvLister, cvcfg)
+	f.imgObjects = append(f.imgObjects, imgcfg)
+
+	c := f.newController()
+
+	verifyListing := func() {
+		t.Helper()
+		mcs, err := c.client.MachineconfigurationV1().MachineConfigs().List(context.TODO(), metav1.ListOptions{})
+		require.NoError(t, err)
+		require.Len(t, mcs.Items, 2)
+		want := map[string]string{}
+		for _, mc := range mcs.Items {
+			ignCfg, err := ctrlcommon.ParseAndConvertConfig(mc.Spec.Config.Raw)
+			require.NoError(t, err)
+			hash, err := getRenderedRegistriesConfigHash(&ignCfg)
+			require.NoError(t, err)
+			require.NotEmpty(t, hash)
+			pool := mc.Labels["machineconfiguration.openshift.io/role"]
+			want[pool+registriesMachineConfigKeySuffix] = mc.Name
+			want[pool+registriesConfigHashKeySuffix] = hash
+		}
+		cm, err := c.kubeClient.CoreV1().ConfigMaps(ctrlcommon.MCONamespace).Get(context.TODO(), registriesMachineConfigsConfigMapName, metav1.GetOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, want, cm.Data)
+	}
+
+	require.NoError(t, c.syncImgHandler("cluster"))
+	verifyListing()
+
+	imgcfg.Spec.RegistrySources.InsecureRegistries = []string{"other-insecure.io"}
+	require.NoError(t, c.syncImgHandler("cluster"))
+	verifyListing()
+}
+
+func TestContainerRuntimeConfigSyncFailureMetrics(t *testing.T) {
+	f := newFixture(t)
+	f.skipActionsValidation = true
+	f.retryConfig = RetryConfig{MaxRetries: 2}
+
+	ctrcfg := newContainerRuntimeConfig("failing", &mcfgv1.ContainerRuntimeConfiguration{LogLevel: "debug"}, metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/worker", ""))
+	f.mccrLister = append(f.mccrLister, ctrcfg)
+	f.objects = append(f.objects, ctrcfg)
+
+	c := f.newController()
+	defer c.queue.ShutDown()
+	failures := ctrlcommon.MCCContainerRuntimeConfigSyncFailures.WithLabelValues(ctrcfg.Name)
+	failing := ctrlcommon.MCCContainerRuntimeConfigFailing.WithLabelValues(ctrcfg.Name)
+
+	syncErr := fmt.Errorf("sync failed")
+	require.Error(t, c.syncStatusOnly(ctrcfg, syncErr))
+	assert.Equal(t, float64(1), testutil.ToFloat64(failing))
+
+	// The counter only goes up when the config is dropped out of the queue, once per maxRetries failures
+	before := testutil.ToFloat64(failures)
+	for drops := 1; drops <= 2; drops++ {
+		for i := 0; i <= c.maxRetries; i++ {
+			c.handleErr(syncErr, ctrcfg.Name)
+		}
+		assert.Equal(t, before+float64(drops), testutil.ToFloat64(failures))
+	}
+
+	require.NoError(t, c.syncStatusOnly(ctrcfg, nil))
+	assert.Equal(t, float64(0), testutil.ToFloat64(failing))
+
+	c.clearUnreconciledMetric(ctrcfg.Name)
+	assert.Equal(t, float64(0), testutil.ToFloat64(ctrlcommon.MCCContainerRuntimeConfigSyncFailures.WithLabelValues(ctrcfg.Name)))
+}
+
+// TestContainerRuntimeConfigSyncMetrics ensures that syncs writing the MachineConfigs and syncs finding them up to date
+// are counted separately
+func TestContainerRuntimeConfigSyncMetrics(t *testing.T) {
+	f := newFixture(t)
+	f.skipActionsValidation = true
+
+	cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+	mcp := helpers.NewMachineConfigPool("infra", nil, helpers.InfraSelector, "v0")
+	rollOutMachineConfigs(mcp, "99-infra-generated-containerruntime")
+	ctrcfg := newContainerRuntimeConfig("sync-metrics", &mcfgv1.ContainerRuntimeConfiguration{LogLevel: "debug"}, metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/infra", ""))
+
+	f.ccLister = append(f.ccLister, cc)
+	f.mcpLister = append(f.mcpLister, mcp)
+	f.mccrLister = append(f.mccrLister, ctrcfg)
+	f.objects = append(f.objects, ctrcfg)
+
+	c := f.newController()
+	applied := ctrlcommon.MCCContainerRuntimeConfigSyncs.WithLabelValues(ctrcfg.Name, syncResultApplied)
+	noops := ctrlcommon.MCCContainerRuntimeConfigSyncs.WithLabelValues(ctrcfg.Name, syncResultNoop)
+	sync := func() {
+		f.client.ClearActions()
+		require.NoError(t, c.syncHandler(getKey(ctrcfg, t)))
+		for _, action := range f.client.Actions() {
+			if action.Matches("update", "containerruntimeconfigs") && action.GetSubresource() == "status" {
+				ctrcfg.Status = action.(core.UpdateAction).GetObject().(*mcfgv1.ContainerRuntimeConfig).Status
+			}
+		}
+	}
+
+	sync()
+	assert.Equal(t, float64(1), testutil.ToFloat64(applied))
+	assert.Equal(t, float64(0), testutil.ToFloat64(noops))
+
+	// Nothing changed, the MachineConfig is up to date
+	sync()
+	sync()
+	assert.Equal(t, float64(1), testutil.ToFloat64(applied))
+	assert.Equal(t, float64(2), testutil.ToFloat64(noops))
+
+	c.clearUnreconciledMetric(ctrcfg.Name)
+	assert.Equal(t, float64(0), testutil.ToFloat64(ctrlcommon.MCCContainerRuntimeConfigSyncs.WithLabelValues(ctrcfg.Name, syncResultNoop)))
+}
+
+func TestRetryConfig(t *testing.T) {
+	f := newFixture(t)
+	c := f.newController()
+	assert.Equal(t, defaultMaxRetries, c.maxRetries)
+	assert.Equal(t, defaultUpdateBackoff, c.updateBackoff)
+
+	backoff := wait.Backoff{Steps: 1, Duration: time.Millisecond}
+	f = newFixture(t)
+	f.retryConfig = RetryConfig{MaxRetries: 2, UpdateBackoff: backoff}
+	c = f.newController()
+	assert.Equal(t, 2, c.maxRetries)
+	assert.Equal(t, backoff, c.updateBackoff)
+
+	syncErr := fmt.Errorf("sync failed")
+	for _, tc := range []struct {
+		name      string
+		queue     workqueue.TypedRateLimitingInterface[string]
+		handleErr func(error, string)
+	}{
+		{name: "containerruntimeconfig queue", queue: c.queue, handleErr: c.handleErr},
+		{name: "image queue", queue: c.imgQueue, handleErr: c.handleImgErr},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			key := "cluster"
+			// Requeued with rate limiting until maxRetries is reached
+			for i := 1; i <= c.maxRetries; i++ {
+				tc.handleErr(syncErr, key)
+				assert.Equal(t, i, tc.queue.NumRequeues(key))
+			}
+			// Then dropped out of the rate limited queue
+			tc.handleErr(syncErr, key)
+			assert.Equal(t, 0, tc.queue.NumRequeues(key))
+
+			tc.handleErr(syncErr, key)
+			assert.Equal(t, 1, tc.queue.NumRequeues(key))
+			tc.handleErr(nil, key)
+			assert.Equal(t, 0, tc.queue.NumRequeues(key))
+			tc.queue.ShutDown()
+		})
+	}
+}
+
+func TestRetryRateLimiter(t *testing.T) {
+	limiter := newRetryRateLimiter(RetryConfig{})
+	assert.Equal(t, defaultRetryBaseDelay, limiter.When("default"))
+	assert.Equal(t, 2*defaultRetryBaseDelay, limiter.When("default"))
+
+	limiter = newRetryRateLimiter(RetryConfig{BaseDelay: time.Second, MaxDelay: 3 * time.Second})
+	for _, want := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second} {
+		assert.Equal(t, want, limiter.When("key"))
+	}
+	limiter.Forget("key")
+	assert.Equal(t, time.Second, limiter.When("key"))
+
+	// Both queues requeue the failed keys with the configured delays
+	f := newFixture(t)
+	f.retryConfig = RetryConfig{BaseDelay: time.Hour, MaxDelay: time.Hour}
+	c := f.newController()
+	syncErr := fmt.Errorf("sync failed")
+	for _, tc := range []struct {
+		name      string
+		queue     workqueue.TypedRateLimitingInterface[string]
+		handleErr func(error, string)
+	}{
+		{name: "containerruntimeconfig queue", queue: c.queue, handleErr: c.handleErr},
+		{name: "image queue", queue: c.imgQueue, handleErr: c.handleImgErr},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.handleErr(syncErr, "cluster")
+			assert.Equal(t, 1, tc.queue.NumRequeues("cluster"))
+			// The default base delay is a few milliseconds, the key would already be queued again with it
+			time.Sleep(100 * time.Millisecond)
+			assert.Equal(t, 0, tc.queue.Len())
+			tc.queue.ShutDown()
+		})
+	}
+}
+
+// delayRecordingQueue records the delays of the keys added with AddAfter
+type delayRecordingQueue struct {
+	workqueue.TypedRateLimitingInterface[string]
+	delays map[string]time.Duration
+}
+
+func (q *delayRecordingQueue) AddAfter(key string, duration time.Duration) {
+	q.delays[key] = duration
+	q.TypedRateLimitingInterface.AddAfter(key, duration)
+}
+
+func TestThrottledSyncBackoff(t *testing.T) {
+	f := newFixture(t)
+	f.retryConfig = RetryConfig{MaxRetries: 2}
+	c := f.newController()
+	queue := &delayRecordingQueue{TypedRateLimitingInterface: c.queue, delays: map[string]time.Duration{}}
+	imgQueue := &delayRecordingQueue{TypedRateLimitingInterface: c.imgQueue, delays: map[string]time.Duration{}}
+	c.queue = queue
+	c.imgQueue = imgQueue
+
+	throttledErr := fmt.Errorf("could not create MachineConfig: %w", errors.NewTooManyRequests("slow down", 0))
+	retryAfterErr := fmt.Errorf("could not update MachineConfig: %w", errors.NewTooManyRequests("slow down", 120))
+	for _, tc := range []struct {
+		name      string
+		queue     *delayRecordingQueue
+		handleErr func(error, string)
+	}{
+		{name: "containerruntimeconfig queue", queue: queue, handleErr: c.handleErr},
+		{name: "image queue", queue: imgQueue, handleErr: c.handleImgErr},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			key := "cluster"
+			// Throttled syncs wait longer and are not counted against maxRetries
+			for i := 0; i <= c.maxRetries; i++ {
+				tc.handleErr(throttledErr, key)
+				assert.Equal(t, throttledRequeueDelay, tc.queue.delays[key])
+				assert.Equal(t, 0, tc.queue.NumRequeues(key))
+			}
+			// The delay asked by the API server is honored when longer
+			tc.handleErr(retryAfterErr, key)
+			assert.Equal(t, 2*time.Minute, tc.queue.delays[key])
+
+			// Other errors still take the rate limited path
+			delete(tc.queue.delays, key)
+			tc.handleErr(errors.NewConflict(mcfgv1.Resource("machineconfigs"), "99-worker-generated-registries", fmt.Errorf("conflict")), key)
+			assert.Equal(t, 1, tc.queue.NumRequeues(key))
+			assert.NotContains(t, tc.queue.delays, key)
+			tc.queue.ShutDown()
+		})
+	}
+}
+
+func TestContainerRuntimeConfigStatusGenerationGating(t *testing.T) {
+	f := newFixture(t)
+	f.skipActionsValidation = true
+
+	ctrcfg := newContainerRuntimeConfig("gated", &mcfgv1.ContainerRuntimeConfiguration{LogLevel: "debug"}, metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/master", ""))
+	f.mccrLister = append(f.mccrLister, ctrcfg)
+	f.objects = append(f.objects, ctrcfg)
+
+	c := f.newController()
+
+	statusUpdates := func() int {
+		n := 0
+		for _, action := range f.client.Actions() {
+			if action.Matches("update", "containerruntimeconfigs") && action.GetSubresource() == "status" {
+				n++
+			}
+		}
+		return n
+	}
+
+	// Two syncs start from generation 1 and 2, and the spec is edited again to generation 3
+	// before either of them records its result
+	gen1 := ctrcfg.DeepCopy()
+	gen2 := ctrcfg.DeepCopy()
+	gen2.Generation = 2
+	ctrcfg.Generation = 3
+
+	require.NoError(t, c.syncStatusOnly(gen1, nil))
+	require.NoError(t, c.syncStatusOnly(gen2, nil))
+	assert.Equal(t, 0, statusUpdates(), "results computed for stale generations should be discarded")
+	assert.Empty(t, ctrcfg.Status.Conditions)
+	assert.Equal(t, int64(0), ctrcfg.Status.ObservedGeneration)
+
+	// The sync of the latest generation is recorded against it
+	gen3 := ctrcfg.DeepCopy()
+	syncErr := fmt.Errorf("invalid config")
+	require.Equal(t, syncErr, c.syncStatusOnly(gen3, syncErr))
+	assert.Equal(t, 1, statusUpdates())
+	latest, err := c.mccrLister.Get(ctrcfg.Name)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), latest.Status.ObservedGeneration)
+	require.Len(t, latest.Status.Conditions, 1)
+	assert.Equal(t, mcfgv1.ContainerRuntimeConfigFailure, latest.Status.Conditions[0].Type)
+
+	// A late result for an older generation does not overwrite it
+	require.NoError(t, c.syncStatusOnly(gen1, nil))
+	assert.Equal(t, 1, statusUpdates())
+	require.Len(t, latest.Status.Conditions, 1)
+	assert.Equal(t, mcfgv1.ContainerRuntimeConfigFailure, latest.Status.Conditions[0].Type)
+}
+
+// TestContainerRuntimeConfigSyncIdempotent ensures that resyncing an applied ContainerRuntimeConfig writes nothing
+func TestContainerRuntimeConfigSyncIdempotent(t *testing.T) {
+	pidsLimit := int64(2048)
+	overlaySize := resource.MustParse("9G")
+	workerSelector := metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/worker", "")
+
+	tests := []struct {
+		name   string
+		config *mcfgv1.ContainerRuntimeConfiguration
+	}{
+		{
+			name:   "crio drop-in",
+			config: &mcfgv1.ContainerRuntimeConfiguration{PidsLimit: &pidsLimit, LogLevel: "debug"},
+		},
+		{
+			name:   "storage config",
+			config: &mcfgv1.ContainerRuntimeConfiguration{OverlaySize: &overlaySize},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := newFixture(t)
+
+			cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+			mcp := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "v0")
+			rollOutMachineConfigs(mcp, "99-worker-generated-containerruntime")
+			ctrcfg := newContainerRuntimeConfig("set-config", test.config, workerSelector)
+
+			f.ccLister = append(f.ccLister, cc)
+			f.mcpLister = append(f.mcpLister, mcp)
+			f.mccrLister = append(f.mccrLister, ctrcfg)
+			f.objects = append(f.objects, ctrcfg)
+
+			c := f.AssertSyncIdempotent((*Controller).syncContainerRuntimeConfig, getKey(ctrcfg, t))
+
+			latest, err := c.mccrLister.Get(ctrcfg.Name)
+			require.NoError(t, err)
+			assert.Equal(t, mcfgv1.ContainerRuntimeConfigSuccess, latest.Status.Conditions[len(latest.Status.Conditions)-1].Type)
+			assert.Equal(t, []string{"99-worker-generated-containerruntime"}, latest.Finalizers)
+		})
+	}
+}
+
+// TestContainerRuntimeConfigRestoresChangedMachineConfig ensures that a resync of an already observed generation
+// renders the MachineConfig again when its contents no longer match the ContainerRuntimeConfig
+func TestContainerRuntimeConfigRestoresChangedMachineConfig(t *testing.T) {
+	f := newFixture(t)
+	f.skipActionsValidation = true
+
+	cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+	mcp := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "v0")
+	rollOutMachineConfigs(mcp, "99-worker-generated-containerruntime")
+	ctrcfg := newContainerRuntimeConfig("set-config", &mcfgv1.ContainerRuntimeConfiguration{LogLevel: "debug"},
+		metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/worker", ""))
+
+	f.ccLister = append(f.ccLister, cc)
+	f.mcpLister = append(f.mcpLister, mcp)
+	f.mccrLister = append(f.mccrLister, ctrcfg)
+	f.objects = append(f.objects, ctrcfg)
+
+	c := f.newController()
+	f.followWrites()
+	require.NoError(t, c.syncContainerRuntimeConfig(getKey(ctrcfg, t)))
+
+	mcs := c.client.MachineconfigurationV1().MachineConfigs()
+	mc, err := mcs.Get(context.TODO(), "99-worker-generated-containerruntime", metav1.GetOptions{})
+	require.NoError(t, err)
+	rendered := mc.Spec.Config.Raw
+	mc.Spec.Config.Raw = helpers.MarshalOrDie(ctrlcommon.NewIgnConfig())
+	_, err = mcs.Update(context.TODO(), mc, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	latest, err := c.mccrLister.Get(ctrcfg.Name)
+	require.NoError(t, err)
+	require.Equal(t, latest.Generation, latest.Status.ObservedGeneration)
+	require.NoError(t, c.syncContainerRuntimeConfig(getKey(ctrcfg, t)))
+
+	mc, err = mcs.Get(context.TODO(), "99-worker-generated-containerruntime", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, rendered, mc.Spec.Config.Raw)
+}
+
+// TestContainerRuntimeConfigSkipsObservedGeneration ensures that the MachineConfig of an already applied generation is
+// not rendered again, unless an input the generation does not track changes, e.g. the release image
+func TestContainerRuntimeConfigSkipsObservedGeneration(t *testing.T) {
+	f := newFixture(t)
+	f.skipActionsValidation = true
+
+	cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+	mcp := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "v0")
+	rollOutMachineConfigs(mcp, "99-worker-generated-containerruntime")
+	ctrcfg := newContainerRuntimeConfig("set-config", &mcfgv1.ContainerRuntimeConfiguration{LogLevel: "debug"},
+		metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/worker", ""))
+
+	f.ccLister = append(f.ccLister, cc)
+	f.mcpLister = append(f.mcpLister, mcp)
+	f.mccrLister = append(f.mccrLister, ctrcfg)
+	f.objects = append(f.objects, ctrcfg)
+
+	c := f.newController()
+	f.followWrites()
+	require.NoError(t, c.syncContainerRuntimeConfig(getKey(ctrcfg, t)))
+	mc, err := c.client.MachineconfigurationV1().MachineConfigs().Get(context.TODO(), "99-worker-generated-containerruntime", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, mc.Annotations[ctrlcommon.ContainerRuntimeConfigInputsAnnotationKey])
+
+	// Rendering fails without templates, so a successful sync did not render the MachineConfig again
+	c.templatesDir = t.TempDir()
+	require.NoError(t, c.syncContainerRuntimeConfig(getKey(ctrcfg, t)))
+
+	newCC := cc.DeepCopy()
+	newCC.Spec.ReleaseImage = "release-image:v2"
+	require.NoError(t, f.mcfgInformers.Machineconfiguration().V1().ControllerConfigs().Informer().GetIndexer().Update(newCC))
+	assert.Error(t, c.syncContainerRuntimeConfig(getKey(ctrcfg, t)), "a new release image should render the MachineConfig again")
+}
+
+// TestImageConfigSyncIdempotent ensures that resyncing an applied Image config writes nothing
+func TestImageConfigSyncIdempotent(t *testing.T) {
+	f := newFixture(t)
+
+	cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+	mcp := helpers.NewMachineConfigPool("master", nil, helpers.MasterSelector, "v0")
+	mcp2 := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "v0")
+	imgcfg := newImageConfig("cluster", &apicfgv1.RegistrySources{
+		InsecureRegistries:               []string{"insecure.io", "another-insecure.io"},
+		BlockedRegistries:                []string{"blocked.io", "another-blocked.io"},
+		ContainerRuntimeSearchRegistries: []string{"search-reg.io", "another-search-reg.io"},
+	})
+	cvcfg := newClusterVersionConfig("version", "test.io/myuser/myimage:test")
+	idms := newIDMS("idms", []apicfgv1.ImageDigestMirrors{
+		{Source: "source.example.com", Mirrors: []apicfgv1.ImageMirror{"mirror.example.com"}},
+	})
+	// The Image config sync does not write to the ContainerRuntimeConfigs of the pools
+	ctrcfg := newContainerRuntimeConfig("set-config", &mcfgv1.ContainerRuntimeConfiguration{LogLevel: "debug"},
+		metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/worker", ""))
+
+	f.ccLister = append(f.ccLister, cc)
+	f.mcpLister = append(f.mcpLister, mcp, mcp2)
+	f.mccrLister = append(f.mccrLister, ctrcfg)
+	f.imgLister = append(f.imgLister, imgcfg)
+	f.cvLister = append(f.cvLister, cvcfg)
+	f.idmsLister = append(f.idmsLister, idms)
+	f.objects = append(f.objects, ctrcfg)
+	f.imgObjects = append(f.imgObjects, imgcfg, idms)
+
+	f.AssertSyncIdempotent((*Controller).syncImageConfig, "cluster")
+}
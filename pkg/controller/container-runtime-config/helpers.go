@@ -3,6 +3,7 @@ package containerruntimeconfig
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,6 +11,8 @@ import (
 	"path/filepath"
 	"reflect"
 	"regexp"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -28,33 +31,59 @@ import (
 	"github.com/openshift/runtime-utils/pkg/registries"
 	runtimeutils "github.com/openshift/runtime-utils/pkg/registries"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
 
 	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
 	mcfgclientset "github.com/openshift/client-go/machineconfiguration/clientset/versioned"
 	"github.com/openshift/machine-config-operator/pkg/apihelpers"
 	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	mtmpl "github.com/openshift/machine-config-operator/pkg/controller/template"
 	"github.com/openshift/machine-config-operator/pkg/daemon/constants"
 )
 
 const (
-	minLogSize                             = 8192
-	minPidsLimit                           = 20
+	minLogSize = 8192
+	// defaultConfigFileMode is the mode of the generated config files, they are read by CRI-O and the container
+	// tools of every user but only the owner may change them
+	defaultConfigFileMode = 0o644
+	// minPidsLimit is the lowest PidsLimit accepted, lower limits prevent most containers from even starting
+	minPidsLimit = 20
+	// recommendedMinPidsLimit is the PidsLimit below which a warning is emitted, as common workloads such as JVMs
+	// or web servers with worker pools can easily go over it
+	recommendedMinPidsLimit = 1024
+	// conditionWarningsSeparator separates the warnings from the rest of the message of a condition
+	conditionWarningsSeparator             = ". Warning: "
 	managedContainerRuntimeConfigKeyPrefix = "99"
 	storageConfigPath                      = "/etc/containers/storage.conf"
 	registriesConfigPath                   = "/etc/containers/registries.conf"
 	searchRegDropInFilePath                = "/etc/containers/registries.conf.d/01-image-searchRegistries.conf"
 	policyConfigPath                       = "/etc/containers/policy.json"
+	// crioDefaultConfigPath is the path of the default CRI-O config rendered from the templates
+	crioDefaultConfigPath = "/etc/crio/crio.conf.d/00-default"
 	// CRIODropInFilePathLogLevel is the path at which changes to the crio config for log-level
 	// will be dropped in this is exported so that we can use it in the e2e-tests
 	CRIODropInFilePathLogLevel       = "/etc/crio/crio.conf.d/01-ctrcfg-logLevel"
 	crioDropInFilePathPidsLimit      = "/etc/crio/crio.conf.d/01-ctrcfg-pidsLimit"
 	crioDropInFilePathLogSizeMax     = "/etc/crio/crio.conf.d/01-ctrcfg-logSizeMax"
 	CRIODropInFilePathDefaultRuntime = "/etc/crio/crio.conf.d/01-ctrcfg-defaultRuntime"
+	crioDropInFilePathSingle         = "/etc/crio/crio.conf.d/01-ctrcfg-containerRuntimeConfig"
+	crioDropInDir                    = "/etc/crio/crio.conf.d/"
 	imagepolicyType                  = "sigstoreSigned"
 	sigstoreRegistriesConfigFilePath = "/etc/containers/registries.d/sigstore-registries.yaml"
+	// registriesMachineConfigsConfigMapName is the ConfigMap of the MCO namespace listing, for each built-in pool, the
+	// registries MachineConfig generated from the Image config under <pool>.machineConfig and the sha256 hash of the
+	// registries.conf it renders under <pool>.registriesConfigHash
+	registriesMachineConfigsConfigMapName = "registries-machineconfigs"
+	registriesMachineConfigKeySuffix      = ".machineConfig"
+	registriesConfigHashKeySuffix         = ".registriesConfigHash"
+	// mirrorRulesWarningThreshold is the soft limit on the total number of mirror rules configured across all
+	// ImageContentSourcePolicy, ImageDigestMirrorSet and ImageTagMirrorSet objects. Going over it does not fail
+	// the sync, but it produces a very large registries.conf and slows down every image config sync.
+	mirrorRulesWarningThreshold = 1000
 )
 
 var (
@@ -65,6 +94,8 @@ var (
 	errParsingReference            = errors.New("error parsing reference of release image")
 	namespacedPolicyFilePathFormat = filepath.FromSlash(constants.CrioPoliciesDir + "/%s.json")
 	reasonConflictScopes           = "ConflictScopes"
+	// configFileDirs are the directories the generated config files may be written to
+	configFileDirs = []string{"/etc/crio", "/etc/containers"}
 )
 
 // TOML-friendly explicit tables used for conversions.
@@ -125,6 +156,15 @@ type tomlConfigCRIODefaultRuntime struct {
 	} `toml:"crio"`
 }
 
+// tomlConfigCRIOTemplate is used to read the runtime tables defined in the default CRI-O config
+type tomlConfigCRIOTemplate struct {
+	Crio struct {
+		Runtime struct {
+			Runtimes map[string]interface{} `toml:"runtimes"`
+		} `toml:"runtime"`
+	} `toml:"crio"`
+}
+
 type dockerConfig struct {
 	UseSigstoreAttachments bool `json:"use-sigstore-attachments,omitempty"`
 }
@@ -138,6 +178,8 @@ type registriesConfig struct {
 type generatedConfigFile struct {
 	filePath string
 	data     []byte
+	// mode is the file mode on the node, defaultConfigFileMode when not set
+	mode int
 }
 
 type updateConfigFunc func(data []byte, internal *mcfgv1.ContainerRuntimeConfiguration) ([]byte, error)
@@ -145,7 +187,7 @@ type updateConfigFunc func(data []byte, internal *mcfgv1.ContainerRuntimeConfigu
 // createNewIgnition takes a map where the key is the path of the file, and the value is the
 // new data in the form of a byte array. The function returns the ignition config with the
 // updated data.
-func createNewIgnition(configs []generatedConfigFile) ign3types.Config {
+func createNewIgnition(configs []generatedConfigFile) (ign3types.Config, error) {
 	tempIgnConfig := ctrlcommon.NewIgnConfig()
 	// Create ignitions
 	for _, ignConf := range configs {
@@ -153,51 +195,106 @@ func createNewIgnition(configs []generatedConfigFile) ign3types.Config {
 		if ignConf.data == nil {
 			continue
 		}
+		mode := ignConf.mode
+		if mode == 0 {
+			mode = defaultConfigFileMode
+		}
+		if err := validateConfigFilePath(ignConf.filePath); err != nil {
+			return ign3types.Config{}, err
+		}
+		if err := validateConfigFileMode(ignConf.filePath, mode); err != nil {
+			return ign3types.Config{}, err
+		}
 		configTempFile := ctrlcommon.NewIgnFileBytesOverwriting(ignConf.filePath, ignConf.data)
+		configTempFile.Mode = &mode
 		tempIgnConfig.Storage.Files = append(tempIgnConfig.Storage.Files, configTempFile)
 	}
 
-	return tempIgnConfig
+	return tempIgnConfig, nil
 }
 
-func findStorageConfig(mc *mcfgv1.MachineConfig) (*ign3types.File, error) {
-	ignCfg, err := ctrlcommon.ParseAndConvertConfig(mc.Spec.Config.Raw)
-	if err != nil {
-		return nil, fmt.Errorf("parsing Storage Ignition config failed with error: %w", err)
+// validateConfigFilePath checks that a generated config file is written to a clean absolute path under the CRI-O or
+// containers config directories, as some paths are derived from user input, e.g. the namespace of an ImagePolicy
+func validateConfigFilePath(path string) error {
+	if !filepath.IsAbs(path) || filepath.Clean(path) != path {
+		return fmt.Errorf("invalid path %q for a generated config file, it must be a clean absolute path", path)
 	}
-	for _, c := range ignCfg.Storage.Files {
-		if c.Path == storageConfigPath {
-			c := c
-			return &c, nil
+	for _, dir := range configFileDirs {
+		if strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			return nil
 		}
 	}
-	return nil, fmt.Errorf("could not find Storage Config")
+	return fmt.Errorf("invalid path %q for a generated config file, it must be under one of %s", path, strings.Join(configFileDirs, ", "))
 }
 
-func findRegistriesConfig(mc *mcfgv1.MachineConfig) (*ign3types.File, error) {
+// validateConfigFileMode checks that a generated config file is readable by its owner, is not executable and cannot
+// be modified by other users than its owner, as these files decide e.g. which images are trusted
+func validateConfigFileMode(path string, mode int) error {
+	switch {
+	case mode&^0o777 != 0:
+		return fmt.Errorf("invalid mode %#o for %s, special permission bits must not be set", mode, path)
+	case mode&0o400 == 0:
+		return fmt.Errorf("invalid mode %#o for %s, the file must be readable by its owner", mode, path)
+	case mode&0o111 != 0:
+		return fmt.Errorf("invalid mode %#o for %s, config files must not be executable", mode, path)
+	case mode&0o022 != 0:
+		return fmt.Errorf("invalid mode %#o for %s, the file must not be writable by group or others", mode, path)
+	}
+	return nil
+}
+
+// findConfigFile returns the file the MachineConfig writes at path, name describes the file in the errors
+func findConfigFile(mc *mcfgv1.MachineConfig, path, name string) (*ign3types.File, error) {
 	ignCfg, err := ctrlcommon.ParseAndConvertConfig(mc.Spec.Config.Raw)
 	if err != nil {
-		return nil, fmt.Errorf("parsing Registries Ignition config failed with error: %w", err)
+		return nil, fmt.Errorf("parsing %s Ignition config failed with error: %w", name, err)
 	}
 	for _, c := range ignCfg.Storage.Files {
-		if c.Path == registriesConfigPath {
+		if c.Path == path {
+			c := c
 			return &c, nil
 		}
 	}
-	return nil, fmt.Errorf("could not find Registries Config")
+	return nil, fmt.Errorf("could not find %s", name)
+}
+
+func findStorageConfig(mc *mcfgv1.MachineConfig) (*ign3types.File, error) {
+	return findConfigFile(mc, storageConfigPath, "Storage Config")
+}
+
+func findCRIODefaultConfig(mc *mcfgv1.MachineConfig) (*ign3types.File, error) {
+	return findConfigFile(mc, crioDefaultConfigPath, "CRI-O default config")
+}
+
+func findRegistriesConfig(mc *mcfgv1.MachineConfig) (*ign3types.File, error) {
+	return findConfigFile(mc, registriesConfigPath, "Registries Config")
 }
 
 func findPolicyJSON(mc *mcfgv1.MachineConfig) (*ign3types.File, error) {
-	ignCfg, err := ctrlcommon.ParseAndConvertConfig(mc.Spec.Config.Raw)
+	return findConfigFile(mc, policyConfigPath, "Policy JSON")
+}
+
+// DecodeGeneratedConfigFile returns the decoded contents of the file the MachineConfig writes at path, e.g. the
+// storage.conf or registries.conf rendered by this controller, for tests and tooling inspecting generated MachineConfigs
+func DecodeGeneratedConfigFile(mc *mcfgv1.MachineConfig, path string) ([]byte, error) {
+	file, err := findConfigFile(mc, path, path)
 	if err != nil {
-		return nil, fmt.Errorf("parsing Policy JSON Ignition config failed with error: %w", err)
+		return nil, fmt.Errorf("MachineConfig %s: %w", mc.Name, err)
 	}
-	for _, c := range ignCfg.Storage.Files {
-		if c.Path == policyConfigPath {
-			return &c, nil
-		}
+	contents, err := ctrlcommon.DecodeIgnitionFileContents(file.Contents.Source, file.Contents.Compression)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode %s of MachineConfig %s: %w", path, mc.Name, err)
 	}
-	return nil, fmt.Errorf("could not find Policy JSON")
+	return contents, nil
+}
+
+// validateGeneratedIgnition makes sure that the raw Ignition config of a generated MachineConfig decodes back into a
+// valid Ignition config, so that a marshaling bug is caught before the MachineConfig reaches the nodes
+func validateGeneratedIgnition(raw []byte) error {
+	if _, err := ctrlcommon.ParseAndConvertConfig(raw); err != nil {
+		return fmt.Errorf("generated Ignition config does not decode: %w", err)
+	}
+	return nil
 }
 
 // Deprecated: use getManagedKeyCtrCfg
@@ -219,11 +316,11 @@ func getManagedKeyCtrCfg(pool *mcfgv1.MachineConfigPool, client mcfgclientset.In
 
 	var ctrcfgList []mcfgv1.ContainerRuntimeConfig
 	for _, ctrcfg := range ctrcfgListAll.Items {
-		selector, err := metav1.LabelSelectorAsSelector(ctrcfg.Spec.MachineConfigPoolSelector)
+		selected, err := selectsPool(&ctrcfg, pool)
 		if err != nil {
-			return "", fmt.Errorf("invalid label selector: %w", err)
+			return "", err
 		}
-		if selector.Empty() || !selector.Matches(labels.Set(pool.Labels)) {
+		if !selected {
 			continue
 		}
 		ctrcfgList = append(ctrcfgList, ctrcfg)
@@ -360,6 +457,10 @@ func wrapErrorWithCondition(err error, args ...interface{}) mcfgv1.ContainerRunt
 			"Success",
 		)
 	}
+	var fieldErr *FieldValidationError
+	if errors.As(err, &fieldErr) {
+		condition.Reason = "Invalid" + fieldErr.Field
+	}
 	if len(args) > 0 {
 		format, ok := args[0].(string)
 		if ok {
@@ -369,6 +470,63 @@ func wrapErrorWithCondition(err error, args ...interface{}) mcfgv1.ContainerRunt
 	return *condition
 }
 
+// sortedUniqueScopes returns a sorted copy of scopes without duplicates. A nil list stays nil.
+func sortedUniqueScopes(scopes []string) []string {
+	if scopes == nil {
+		return nil
+	}
+	return sets.List(sets.New(scopes...))
+}
+
+// uniqueScopes returns a copy of scopes without duplicates, keeping the first occurrence of each. A nil list stays nil.
+func uniqueScopes(scopes []string) []string {
+	if scopes == nil {
+		return nil
+	}
+	seen := sets.New[string]()
+	unique := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !seen.Has(scope) {
+			seen.Insert(scope)
+			unique = append(unique, scope)
+		}
+	}
+	return unique
+}
+
+// withWarnings appends the warnings to a status message
+func withWarnings(msg string, warnings []string) string {
+	if len(warnings) == 0 {
+		return msg
+	}
+	return msg + conditionWarningsSeparator + strings.Join(warnings, "; ")
+}
+
+// isRolloutPending returns true if the last recorded condition of the ContainerRuntimeConfig is Pending
+func isRolloutPending(cfg *mcfgv1.ContainerRuntimeConfig) bool {
+	return len(cfg.Status.Conditions) > 0 && cfg.Status.Conditions[len(cfg.Status.Conditions)-1].Type == ContainerRuntimeConfigPending
+}
+
+// getPoolsNotRolledOut returns the names of the pools whose current rendered config, as reported in their status,
+// is not generated from their managed MachineConfig yet. managedKeys holds the name of the managed MachineConfig
+// of each pool, in the same order as pools.
+func getPoolsNotRolledOut(pools []*mcfgv1.MachineConfigPool, managedKeys []string) []string {
+	var pending []string
+	for i, pool := range pools {
+		rolledOut := false
+		for _, source := range pool.Status.Configuration.Source {
+			if source.Name == managedKeys[i] {
+				rolledOut = true
+				break
+			}
+		}
+		if !rolledOut {
+			pending = append(pending, pool.Name)
+		}
+	}
+	return pending
+}
+
 // updateStorageConfig decodes the data rendered from the template, merges the changes in and encodes it
 // back into a TOML format. It returns the bytes of the encoded data
 func updateStorageConfig(data []byte, internal *mcfgv1.ContainerRuntimeConfiguration) ([]byte, error) {
@@ -396,6 +554,77 @@ func updateStorageConfig(data []byte, internal *mcfgv1.ContainerRuntimeConfigura
 	return newData.Bytes(), nil
 }
 
+// hasOverlaySize returns true if the ContainerRuntimeConfig overrides the overlay size in storage.conf
+func hasOverlaySize(cfg *mcfgv1.ContainerRuntimeConfig) bool {
+	ctrcfg := cfg.Spec.ContainerRuntimeConfig
+	return ctrcfg != nil && ctrcfg.OverlaySize != nil && !ctrcfg.OverlaySize.IsZero()
+}
+
+// isManagedMCFinalizer returns true if the finalizer is one the controller adds to a ContainerRuntimeConfig, i.e. the
+// name of a MachineConfig it generated, as opposed to a finalizer added by someone else
+func isManagedMCFinalizer(finalizer string) bool {
+	return strings.HasPrefix(finalizer, managedContainerRuntimeConfigKeyPrefix+"-") && strings.Contains(finalizer, "containerruntime")
+}
+
+// selectsPool returns true if the ContainerRuntimeConfig applies to the pool, i.e. the pool is matched by its
+// machineConfigPoolSelector
+func selectsPool(cfg *mcfgv1.ContainerRuntimeConfig, pool *mcfgv1.MachineConfigPool) (bool, error) {
+	selector, err := metav1.LabelSelectorAsSelector(cfg.Spec.MachineConfigPoolSelector)
+	if err != nil {
+		return false, fmt.Errorf("invalid label selector: %w", err)
+	}
+	// If a pool with a nil or empty selector creeps in, it should match nothing, not everything.
+	return !selector.Empty() && selector.Matches(labels.Set(pool.Labels)), nil
+}
+
+// selectOverlaySizeConfig returns the ContainerRuntimeConfig whose overlaySize applies to the pool, along with the
+// number of ContainerRuntimeConfigs selecting the pool that set one. Each of them renders the whole storage.conf, so
+// they all have to agree on the value. The most specific ContainerRuntimeConfig wins: the one selecting the fewest
+// pools, then the one with the most selector requirements, then the first one by name.
+func selectOverlaySizeConfig(pool *mcfgv1.MachineConfigPool, pools []*mcfgv1.MachineConfigPool, ctrcfgs []*mcfgv1.ContainerRuntimeConfig) (*mcfgv1.ContainerRuntimeConfig, int, error) {
+	type candidate struct {
+		cfg          *mcfgv1.ContainerRuntimeConfig
+		poolCount    int
+		requirements int
+	}
+	var candidates []candidate
+	for _, cfg := range ctrcfgs {
+		if !hasOverlaySize(cfg) {
+			continue
+		}
+		selected, err := selectsPool(cfg, pool)
+		if err != nil {
+			return nil, 0, err
+		}
+		if !selected {
+			continue
+		}
+		c := candidate{cfg: cfg}
+		if cfg.Spec.MachineConfigPoolSelector != nil {
+			c.requirements = len(cfg.Spec.MachineConfigPoolSelector.MatchLabels) + len(cfg.Spec.MachineConfigPoolSelector.MatchExpressions)
+		}
+		for _, p := range pools {
+			if selected, _ := selectsPool(cfg, p); selected {
+				c.poolCount++
+			}
+		}
+		candidates = append(candidates, c)
+	}
+	if len(candidates) == 0 {
+		return nil, 0, nil
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].poolCount != candidates[j].poolCount {
+			return candidates[i].poolCount < candidates[j].poolCount
+		}
+		if candidates[i].requirements != candidates[j].requirements {
+			return candidates[i].requirements > candidates[j].requirements
+		}
+		return candidates[i].cfg.Name < candidates[j].cfg.Name
+	})
+	return candidates[0].cfg, len(candidates), nil
+}
+
 func addTOMLgeneratedConfigFile(configFileList []generatedConfigFile, path string, tomlConf interface{}) ([]generatedConfigFile, error) {
 	var newData bytes.Buffer
 	encoder := toml.NewEncoder(&newData)
@@ -467,30 +696,287 @@ func updateSearchRegistriesConfig(searchRegs []string) []generatedConfigFile {
 	return generatedConfigFileList
 }
 
-func updateRegistriesConfig(data []byte, internalInsecure, internalBlocked []string,
-	icspRules []*apioperatorsv1alpha1.ImageContentSourcePolicy, idmsRules []*apicfgv1.ImageDigestMirrorSet, itmsRules []*apicfgv1.ImageTagMirrorSet) ([]byte, error) {
+// getContainerRuntimeConfigInputsHash returns the sha256 hash of the inputs of the MachineConfig generated for a
+// ContainerRuntimeConfig on a pool that its generation does not track: the ControllerConfig, with the release image,
+// the default configs of the pool are rendered from and the ContainerRuntimeConfig deciding its overlaySize.
+// The Ignition config rendered from them is part of the hash, so that a MachineConfig changed out of band is told apart.
+func getContainerRuntimeConfigInputsHash(controllerConfig *mcfgv1.ControllerConfig, overlaySizeCfg *mcfgv1.ContainerRuntimeConfig, rawIgn []byte) string {
+	inputs := []string{fmt.Sprintf("ControllerConfig/%s/%d/%s", controllerConfig.UID, controllerConfig.Generation, controllerConfig.Spec.ReleaseImage)}
+	if overlaySizeCfg != nil {
+		inputs = append(inputs, fmt.Sprintf("ContainerRuntimeConfig/%s/%s/%d", overlaySizeCfg.Name, overlaySizeCfg.UID, overlaySizeCfg.Generation))
+	}
+	inputs = append(inputs, fmt.Sprintf("Ignition/%x", sha256.Sum256(rawIgn)))
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(strings.Join(inputs, ","))))
+}
+
+// getRenderedRegistriesConfigHash returns the sha256 hash of the registries.conf in the Ignition config,
+// or "" if the Ignition config does not override registries.conf
+func getRenderedRegistriesConfigHash(ignCfg *ign3types.Config) (string, error) {
+	for _, file := range ignCfg.Storage.Files {
+		if file.Node.Path != registriesConfigPath || file.Contents.Source == nil {
+			continue
+		}
+		contents, err := ctrlcommon.DecodeIgnitionFileContents(file.Contents.Source, file.Contents.Compression)
+		if err != nil {
+			return "", fmt.Errorf("could not decode rendered registries config: %w", err)
+		}
+		return fmt.Sprintf("%x", sha256.Sum256(contents)), nil
+	}
+	return "", nil
+}
+
+// getIgnitionConfigHash returns the hash of the raw Ignition config of a MachineConfig
+func getIgnitionConfigHash(rawIgn []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(rawIgn))
+}
+
+// registryMirrorRules holds the mirror configuration written to registries.conf. Mirrors from
+// ImageContentSourcePolicy and ImageDigestMirrorSet objects are only used when pulling by digest,
+// mirrors from ImageTagMirrorSet objects only when pulling by tag.
+type registryMirrorRules struct {
+	icsp []*apioperatorsv1alpha1.ImageContentSourcePolicy
+	idms []*apicfgv1.ImageDigestMirrorSet
+	itms []*apicfgv1.ImageTagMirrorSet
+}
+
+// isEmpty returns true if no mirror sets are configured
+func (r registryMirrorRules) isEmpty() bool {
+	return len(r.icsp) == 0 && len(r.idms) == 0 && len(r.itms) == 0
+}
+
+// forArchitecture returns the mirror rules that apply to the pools of arch: the mirror sets without an architecture
+// and the ones for arch
+func (r registryMirrorRules) forArchitecture(arch string) registryMirrorRules {
+	return registryMirrorRules{
+		icsp: mirrorSetsForArchitecture(r.icsp, arch),
+		idms: mirrorSetsForArchitecture(r.idms, arch),
+		itms: mirrorSetsForArchitecture(r.itms, arch),
+	}
+}
+
+// mirrorSetsForArchitecture returns the mirror sets that are not specific to another architecture than arch
+func mirrorSetsForArchitecture[T metav1.Object](mirrorSets []T, arch string) []T {
+	var filtered []T
+	for _, mirrorSet := range mirrorSets {
+		if setArch := getMirrorSetArchitecture(mirrorSet); setArch == "" || setArch == arch {
+			filtered = append(filtered, mirrorSet)
+		}
+	}
+	return filtered
+}
+
+// getMirrorSetArchitecture returns the architecture the mirror set is restricted to, empty if it applies to every pool
+func getMirrorSetArchitecture(mirrorSet metav1.Object) string {
+	return mirrorSet.GetAnnotations()[ctrlcommon.MirrorSetArchitectureAnnotationKey]
+}
+
+// getNodesArchitecture returns the architecture the nodes report in their status, in the GOARCH format. It is empty
+// when there are no nodes or when they do not all run on the same architecture, as the mirror sets specific to an
+// architecture cannot be configured for all of them then.
+func getNodesArchitecture(nodes []*corev1.Node) string {
+	arch := ""
+	for i, node := range nodes {
+		if i > 0 && node.Status.NodeInfo.Architecture != arch {
+			return ""
+		}
+		arch = node.Status.NodeInfo.Architecture
+	}
+	return arch
+}
+
+func updateRegistriesConfig(data []byte, internalInsecure, internalBlocked []string, mirrorRules registryMirrorRules) ([]byte, error) {
 
 	tomlConf := sysregistriesv2.V2RegistriesConf{}
 	if _, err := toml.Decode(string(data), &tomlConf); err != nil {
 		return nil, fmt.Errorf("error unmarshalling registries config: %w", err)
 	}
 
-	if err := validateRegistriesConfScopes(internalInsecure, internalBlocked, []string{}, icspRules, idmsRules, itmsRules); err != nil {
+	icspRules, selfMirrored, err := checkICSPMirrorLoops(mirrorRules.icsp)
+	if err != nil {
+		return nil, err
+	}
+	if len(selfMirrored) > 0 {
+		klog.Warningf("imagecontentsourcepolicy sources %v list themselves as a mirror, these mirrors are ignored", selfMirrored)
+	}
+	mirrorRules.icsp = icspRules
+
+	if err := validateRegistriesConfScopes(internalInsecure, internalBlocked, []string{}, mirrorRules.icsp, mirrorRules.idms, mirrorRules.itms); err != nil {
 		return nil, err
 	}
 
-	if err := registries.EditRegistriesConfig(&tomlConf, internalInsecure, internalBlocked, icspRules, idmsRules, itmsRules); err != nil {
+	if err := registries.EditRegistriesConfig(&tomlConf, internalInsecure, internalBlocked, mirrorRules.icsp, mirrorRules.idms, mirrorRules.itms); err != nil {
 		return nil, err
 	}
+	for i := range tomlConf.Registries {
+		mergeMirrorPullScopes(&tomlConf.Registries[i])
+	}
 
 	var newData bytes.Buffer
 	encoder := toml.NewEncoder(&newData)
 	if err := encoder.Encode(tomlConf); err != nil {
 		return nil, err
 	}
+	// Make sure we never ship a registries.conf to the nodes that CRI-O would fail to load
+	if err := validateRegistriesConfig(newData.Bytes()); err != nil {
+		return nil, err
+	}
 	return newData.Bytes(), nil
 }
 
+// checkICSPMirrorLoops drops the mirrors of the ImageContentSourcePolicy rules that are the same as their source,
+// and fails if the remaining rules mirror sources into each other in a cycle, e.g. a mirrored to b and b mirrored to a.
+// It returns the remaining ICSP rules and the sources that listed themselves as a mirror.
+func checkICSPMirrorLoops(icspRules []*apioperatorsv1alpha1.ImageContentSourcePolicy) ([]*apioperatorsv1alpha1.ImageContentSourcePolicy, []string, error) {
+	selfMirrored := sets.New[string]()
+	filtered := make([]*apioperatorsv1alpha1.ImageContentSourcePolicy, 0, len(icspRules))
+	graph := map[string]sets.Set[string]{}
+	for _, icsp := range icspRules {
+		var icspCopy *apioperatorsv1alpha1.ImageContentSourcePolicy
+		for i, mirrorSet := range icsp.Spec.RepositoryDigestMirrors {
+			mirrors := make([]string, 0, len(mirrorSet.Mirrors))
+			for _, mirror := range mirrorSet.Mirrors {
+				if mirror == mirrorSet.Source {
+					selfMirrored.Insert(mirrorSet.Source)
+					continue
+				}
+				mirrors = append(mirrors, mirror)
+				if graph[mirrorSet.Source] == nil {
+					graph[mirrorSet.Source] = sets.New[string]()
+				}
+				graph[mirrorSet.Source].Insert(mirror)
+			}
+			if len(mirrors) == len(mirrorSet.Mirrors) {
+				continue
+			}
+			// Never modify the object from the lister cache
+			if icspCopy == nil {
+				icspCopy = icsp.DeepCopy()
+			}
+			icspCopy.Spec.RepositoryDigestMirrors[i].Mirrors = mirrors
+		}
+		if icspCopy == nil {
+			filtered = append(filtered, icsp)
+			continue
+		}
+		var kept []apioperatorsv1alpha1.RepositoryDigestMirrors
+		for _, mirrorSet := range icspCopy.Spec.RepositoryDigestMirrors {
+			if len(mirrorSet.Mirrors) > 0 {
+				kept = append(kept, mirrorSet)
+			}
+		}
+		if len(kept) > 0 {
+			icspCopy.Spec.RepositoryDigestMirrors = kept
+			filtered = append(filtered, icspCopy)
+		}
+	}
+
+	// Depth-first search over the source -> mirror graph; reaching a source that is still on the path is a cycle
+	const (
+		visiting = iota + 1
+		visited
+	)
+	state := map[string]int{}
+	var visit func(source string, path []string) error
+	visit = func(source string, path []string) error {
+		switch state[source] {
+		case visiting:
+			if i := slices.Index(path, source); i >= 0 {
+				path = path[i:]
+			}
+			return fmt.Errorf("imagecontentsourcepolicy mirrors form a cycle: %s", strings.Join(append(path, source), " -> "))
+		case visited:
+			return nil
+		}
+		state[source] = visiting
+		for _, mirror := range sets.List(graph[source]) {
+			if err := visit(mirror, append(path, source)); err != nil {
+				return err
+			}
+		}
+		state[source] = visited
+		return nil
+	}
+	for _, source := range sets.List(sets.KeySet(graph)) {
+		if err := visit(source, nil); err != nil {
+			return nil, nil, err
+		}
+	}
+	return filtered, sets.List(selfMirrored), nil
+}
+
+// mergeMirrorPullScopes collapses the mirrors of reg into a single "pull-from-mirror = all" list when the
+// same mirrors, in the same order, are configured both for pulls by digest and for pulls by tag.
+// Partially overlapping lists are left untouched so that the mirror order for either kind of pull is preserved.
+func mergeMirrorPullScopes(reg *sysregistriesv2.Registry) {
+	if reg.MirrorByDigestOnly {
+		return
+	}
+	var byDigest, byTag []sysregistriesv2.Endpoint
+	for _, mirror := range reg.Mirrors {
+		switch mirror.PullFromMirror {
+		case sysregistriesv2.MirrorByDigestOnly:
+			byDigest = append(byDigest, mirror)
+		case sysregistriesv2.MirrorByTagOnly:
+			byTag = append(byTag, mirror)
+		default:
+			// Mirrors that already apply to every pull cannot be merged without changing their order
+			return
+		}
+	}
+	if len(byDigest) == 0 || len(byDigest) != len(byTag) {
+		return
+	}
+	for i := range byDigest {
+		if byDigest[i].Location != byTag[i].Location || byDigest[i].Insecure != byTag[i].Insecure {
+			return
+		}
+	}
+	mirrors := make([]sysregistriesv2.Endpoint, 0, len(byDigest))
+	for _, mirror := range byDigest {
+		mirror.PullFromMirror = sysregistriesv2.MirrorAll
+		mirrors = append(mirrors, mirror)
+	}
+	reg.Mirrors = mirrors
+}
+
+// validateRegistriesConfig decodes the generated registries config again and checks its registries the way the
+// parser used by CRI-O (containers/image) does, to ensure that it round-trips cleanly
+func validateRegistriesConfig(data []byte) error {
+	tomlConf := sysregistriesv2.V2RegistriesConf{}
+	if _, err := toml.Decode(string(data), &tomlConf); err != nil {
+		return fmt.Errorf("generated registries config could not be decoded: %w", err)
+	}
+	for _, reg := range tomlConf.Registries {
+		if err := validateRegistryLocation(reg.Location); err != nil {
+			return err
+		}
+		if err := validateRegistryLocation(reg.Prefix); err != nil {
+			return err
+		}
+		if reg.Location == "" && reg.Prefix == "" {
+			return fmt.Errorf("generated registries config is invalid: a registry has neither a location nor a prefix")
+		}
+		for _, mirror := range reg.Mirrors {
+			if mirror.Location == "" {
+				return fmt.Errorf("generated registries config is invalid: a mirror of %q has no location", reg.Prefix+reg.Location)
+			}
+			if err := validateRegistryLocation(mirror.Location); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateRegistryLocation returns an error if the location or prefix of a registry or mirror has a URI scheme,
+// which containers/image rejects
+func validateRegistryLocation(location string) error {
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		return fmt.Errorf("generated registries config is invalid: location %q has a URI scheme", location)
+	}
+	return nil
+}
+
 // updatePolicyJSON decodes the data rendered from the template, merges the changes in and encodes it
 // back into a JSON format. It returns the bytes of the encoded data
 // It also returns an error if both allowed and blocked registries are set
@@ -509,6 +995,8 @@ func updatePolicyJSON(data []byte, internalBlocked, internalAllowed []string, re
 		}
 	}
 
+	// A registry that is both blocked and signed stays blocked
+	clusterScopePolicies = withoutBlockedScopes(clusterScopePolicies, internalBlocked)
 	if err := validateImagePolicyWithAllowedBlockedRegistries(clusterScopePolicies, nil, internalAllowed, internalBlocked); err != nil {
 		return nil, err
 	}
@@ -579,27 +1067,70 @@ func updatePolicyJSON(data []byte, internalBlocked, internalAllowed []string, re
 	return policyJSON, nil
 }
 
+// isSamePolicyJSON returns true if the two policy jsons hold the same policy, regardless of their formatting
+func isSamePolicyJSON(a, b []byte) (bool, error) {
+	var policyA, policyB interface{}
+	if err := json.Unmarshal(a, &policyA); err != nil {
+		return false, fmt.Errorf("error decoding policy json: %w", err)
+	}
+	if err := json.Unmarshal(b, &policyB); err != nil {
+		return false, fmt.Errorf("error decoding policy json: %w", err)
+	}
+	return reflect.DeepEqual(policyA, policyB), nil
+}
+
+// FieldValidationError is returned when a field of a ContainerRuntimeConfig has an invalid value, so that clients can
+// tell which field is invalid. The field is surfaced in the Reason of the failure condition.
+type FieldValidationError struct {
+	// Field is the name of the invalid field, e.g. PidsLimit
+	Field string
+	// Reason is the human readable explanation of why the value is invalid
+	Reason string
+}
+
+func (e *FieldValidationError) Error() string {
+	return e.Reason
+}
+
+// newFieldValidationError returns a FieldValidationError for field, explained by err
+func newFieldValidationError(field string, err error) error {
+	return &FieldValidationError{Field: field, Reason: err.Error()}
+}
+
 // validateUserContainerRuntimeConfig ensures that the values set by the user are valid
 func validateUserContainerRuntimeConfig(cfg *mcfgv1.ContainerRuntimeConfig) error {
-	if cfg.Spec.ContainerRuntimeConfig == nil {
+	return ValidateContainerRuntimeConfigSpec(cfg.Spec)
+}
+
+// ValidateContainerRuntimeConfigSpec runs the validation the controller applies to the spec of every ContainerRuntimeConfig,
+// so that an admission webhook can reject the same invalid specs before they are stored. The errors are FieldValidationErrors.
+// The checks that depend on the selected pools, such as the master pool acknowledgement, are only done by the controller.
+func ValidateContainerRuntimeConfigSpec(spec mcfgv1.ContainerRuntimeConfigSpec) error {
+	if spec.ContainerRuntimeConfig == nil {
 		return nil
 	}
-	ctrcfgValues := reflect.ValueOf(*cfg.Spec.ContainerRuntimeConfig)
+	ctrcfgValues := reflect.ValueOf(*spec.ContainerRuntimeConfig)
 	if !ctrcfgValues.IsValid() {
-		return fmt.Errorf("containerRuntimeConfig is not valid")
+		return newFieldValidationError("ContainerRuntimeConfig", fmt.Errorf("containerRuntimeConfig is not valid"))
 	}
 
-	ctrcfg := cfg.Spec.ContainerRuntimeConfig
+	ctrcfg := spec.ContainerRuntimeConfig
 	if ctrcfg.PidsLimit != nil && *ctrcfg.PidsLimit != 0 && *ctrcfg.PidsLimit < minPidsLimit {
-		return fmt.Errorf("invalid PidsLimit %v", *ctrcfg.PidsLimit)
+		return newFieldValidationError("PidsLimit", fmt.Errorf("invalid PidsLimit %v, cannot be less than %d", *ctrcfg.PidsLimit, minPidsLimit))
+	}
+
+	// A negative LogSizeMax is still accepted, with a deprecation warning, as it used to be the only way to set no
+	// size limit. See getContainerRuntimeConfigWarnings.
+	if ctrcfg.LogSizeMax != nil && ctrcfg.LogSizeMax.Value() == 0 {
+		return newFieldValidationError("LogSizeMax", fmt.Errorf("invalid LogSizeMax %q, must be greater than 0, leave it unset for no size limit", ctrcfg.LogSizeMax.String()))
 	}
 
 	if ctrcfg.LogSizeMax != nil && ctrcfg.LogSizeMax.Value() > 0 && ctrcfg.LogSizeMax.Value() <= minLogSize {
-		return fmt.Errorf("invalid LogSizeMax %q, cannot be less than 8kB", ctrcfg.LogSizeMax.String())
+		return newFieldValidationError("LogSizeMax", fmt.Errorf("invalid LogSizeMax %q, cannot be less than 8kB", ctrcfg.LogSizeMax.String()))
 	}
 
 	if ctrcfg.OverlaySize != nil && ctrcfg.OverlaySize.Value() < 0 {
-		return fmt.Errorf("invalid overlaySize %q, cannot be less than 0", ctrcfg.OverlaySize.String())
+		return newFieldValidationError("OverlaySize", fmt.Errorf("invalid overlaySize %q, cannot be less than 0", ctrcfg.OverlaySize.String()))
 	}
 
 	if ctrcfg.LogLevel != "" {
@@ -613,24 +1144,211 @@ func validateUserContainerRuntimeConfig(cfg *mcfgv1.ContainerRuntimeConfig) erro
 			"trace": true,
 		}
 		if !validLogLevels[ctrcfg.LogLevel] {
-			return fmt.Errorf("invalid LogLevel %q, must be one of error, fatal, panic, warn, info, debug, or trace", ctrcfg.LogLevel)
+			return newFieldValidationError("LogLevel", fmt.Errorf("invalid LogLevel %q, must be one of error, fatal, panic, warn, info, debug, or trace", ctrcfg.LogLevel))
 		}
 	}
 
 	switch ctrcfg.DefaultRuntime {
 	case mcfgv1.ContainerRuntimeDefaultRuntimeEmpty, mcfgv1.ContainerRuntimeDefaultRuntimeRunc, mcfgv1.ContainerRuntimeDefaultRuntimeCrun:
 	default:
-		return fmt.Errorf("invalid DefaultRuntime %q, must be one of %s, %s", ctrcfg.DefaultRuntime, mcfgv1.ContainerRuntimeDefaultRuntimeCrun, mcfgv1.ContainerRuntimeDefaultRuntimeRunc)
+		return newFieldValidationError("DefaultRuntime", fmt.Errorf("invalid DefaultRuntime %q, must be one of %s, %s", ctrcfg.DefaultRuntime, mcfgv1.ContainerRuntimeDefaultRuntimeCrun, mcfgv1.ContainerRuntimeDefaultRuntimeRunc))
+	}
+
+	return nil
+}
+
+// validateMasterPoolAcknowledged makes sure that a ContainerRuntimeConfig selecting the master pool carries the
+// annotation acknowledging that it changes the container runtime of the control plane nodes
+func validateMasterPoolAcknowledged(cfg *mcfgv1.ContainerRuntimeConfig, pools []*mcfgv1.MachineConfigPool) error {
+	if cfg.GetAnnotations()[ctrlcommon.ContainerRuntimeConfigAllowMasterChangesAnnotationKey] == "true" {
+		return nil
+	}
+	for _, pool := range pools {
+		if pool.Name == ctrlcommon.MachineConfigPoolMaster {
+			return fmt.Errorf("ContainerRuntimeConfig %s selects the %s pool, changing the container runtime of the control plane nodes requires the %s: \"true\" annotation",
+				cfg.Name, pool.Name, ctrlcommon.ContainerRuntimeConfigAllowMasterChangesAnnotationKey)
+		}
+	}
+	return nil
+}
+
+// wantsSingleCRIODropin returns true if the ContainerRuntimeConfig renders all of its CRI-O settings in a single drop-in file
+func wantsSingleCRIODropin(cfg *mcfgv1.ContainerRuntimeConfig) bool {
+	return cfg.GetAnnotations()[ctrlcommon.ContainerRuntimeConfigSingleCRIODropinAnnotationKey] == "true"
+}
+
+// mergeCRIODropinFiles merges the CRI-O drop-in files of configFileList, in the lexical order of their names, into the
+// single crioDropInFilePathSingle drop-in file. The other files, such as storage.conf, are kept first and unchanged.
+func mergeCRIODropinFiles(configFileList []generatedConfigFile) ([]generatedConfigFile, error) {
+	dropins, others := splitCRIODropinFiles(configFileList)
+	if len(dropins) == 0 {
+		return others, nil
+	}
+	configs := make([][]byte, 0, len(dropins))
+	for _, dropin := range dropins {
+		configs = append(configs, dropin.data)
+	}
+	merged, err := mergeTOMLConfigs(configs)
+	if err != nil {
+		return nil, err
+	}
+	return append(others, generatedConfigFile{filePath: crioDropInFilePathSingle, data: merged}), nil
+}
+
+// splitCRIODropinFiles returns the files of configFileList that are CRI-O drop-in files, sorted by name, and the others
+func splitCRIODropinFiles(configFileList []generatedConfigFile) (dropins, others []generatedConfigFile) {
+	for _, file := range configFileList {
+		if filepath.Dir(file.filePath) == filepath.Dir(crioDefaultConfigPath) {
+			dropins = append(dropins, file)
+		} else {
+			others = append(others, file)
+		}
+	}
+	sort.Slice(dropins, func(i, j int) bool { return dropins[i].filePath < dropins[j].filePath })
+	return dropins, others
+}
+
+// mergeTOMLConfigs merges the TOML configs in order, later ones overriding the keys set by earlier ones. The TOML encoder
+// sorts the keys, so the result only depends on the merged values.
+func mergeTOMLConfigs(configs [][]byte) ([]byte, error) {
+	merged := map[string]interface{}{}
+	for _, data := range configs {
+		conf := map[string]interface{}{}
+		if _, err := toml.Decode(string(data), &conf); err != nil {
+			return nil, fmt.Errorf("error decoding CRI-O config: %w", err)
+		}
+		mergeTOMLTables(merged, conf)
+	}
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(merged); err != nil {
+		return nil, fmt.Errorf("error encoding the merged CRI-O config: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// mergeTOMLTables sets the keys of src in dst, merging the tables present in both and overriding any other value
+func mergeTOMLTables(dst, src map[string]interface{}) {
+	for key, value := range src {
+		srcTable, srcIsTable := value.(map[string]interface{})
+		dstTable, dstIsTable := dst[key].(map[string]interface{})
+		if srcIsTable && dstIsTable {
+			mergeTOMLTables(dstTable, srcTable)
+			continue
+		}
+		dst[key] = value
+	}
+}
+
+// isDryRun returns true if the ContainerRuntimeConfig only previews the MachineConfigs it renders
+func isDryRun(cfg *mcfgv1.ContainerRuntimeConfig) bool {
+	return cfg.GetAnnotations()[ctrlcommon.ContainerRuntimeConfigDryRunAnnotationKey] == "true"
+}
+
+// getContainerRuntimeConfigWarnings returns advisory messages for values set by the user that are valid,
+// but are likely to cause problems for the workloads running on the selected pools
+func getContainerRuntimeConfigWarnings(cfg *mcfgv1.ContainerRuntimeConfig) []string {
+	var warnings []string
+	if cfg.Spec.ContainerRuntimeConfig == nil {
+		return warnings
+	}
+	ctrcfg := cfg.Spec.ContainerRuntimeConfig
+	if ctrcfg.PidsLimit != nil && *ctrcfg.PidsLimit > 0 && *ctrcfg.PidsLimit < recommendedMinPidsLimit {
+		warnings = append(warnings, fmt.Sprintf("PidsLimit %d is below the recommended minimum of %d, containers on the selected pools may fail to create new processes or threads", *ctrcfg.PidsLimit, recommendedMinPidsLimit))
+	}
+	if ctrcfg.LogSizeMax != nil && ctrcfg.LogSizeMax.Value() < 0 {
+		warnings = append(warnings, fmt.Sprintf("LogSizeMax %s is deprecated and will be rejected in a future release, leave it unset for no size limit", ctrcfg.LogSizeMax.String()))
+	}
+	if warning := getLogSizeMaxSuffixWarning(ctrcfg.LogSizeMax); warning != "" {
+		warnings = append(warnings, warning)
+	}
+	return warnings
+}
+
+// getLogSizeMaxSuffixWarning returns a warning if LogSizeMax uses a decimal suffix, e.g. 50M, as log sizes are
+// usually meant in binary units, e.g. 50Mi. It returns "" otherwise.
+func getLogSizeMaxSuffixWarning(logSizeMax *resource.Quantity) string {
+	if logSizeMax == nil || logSizeMax.Value() <= 0 || logSizeMax.Format != resource.DecimalSI {
+		return ""
+	}
+	value := logSizeMax.String()
+	suffix := value[len(value)-1:]
+	if !strings.ContainsAny(suffix, "kMGTPE") {
+		return ""
+	}
+	binary := resource.MustParse(strings.TrimSuffix(value, suffix) + strings.ToUpper(suffix) + "i")
+	return fmt.Sprintf("LogSizeMax %s uses a decimal suffix and is %d bytes, use %s for %d bytes if a binary size was intended", value, logSizeMax.Value(), binary.String(), binary.Value())
+}
+
+// getExternalLogRotationWarning returns a warning if the ContainerRuntimeConfig has conmon rotate the container logs on a
+// pool annotated as rotating them with an external tool, as the logs would then be rotated twice. It returns "" otherwise.
+func getExternalLogRotationWarning(cfg *mcfgv1.ContainerRuntimeConfig, pool *mcfgv1.MachineConfigPool) string {
+	ctrcfg := cfg.Spec.ContainerRuntimeConfig
+	if ctrcfg == nil || ctrcfg.LogSizeMax == nil || ctrcfg.LogSizeMax.Value() <= 0 {
+		return ""
+	}
+	if pool.Annotations[ctrlcommon.MachineConfigPoolExternalLogRotationAnnotationKey] != "true" {
+		return ""
+	}
+	return fmt.Sprintf("LogSizeMax %s makes conmon rotate the container logs, but MachineConfigPool %s rotates them with an external tool, so the logs may be rotated twice", ctrcfg.LogSizeMax.String(), pool.Name)
+}
+
+// getConflictingConfigWarnings returns a warning for each of PidsLimit, LogLevel and OverlaySize that the
+// ContainerRuntimeConfig sets differently than another ContainerRuntimeConfig applying to the same pool, as only one of
+// them takes effect on the nodes of the pool.
+func getConflictingConfigWarnings(cfg *mcfgv1.ContainerRuntimeConfig, pool *mcfgv1.MachineConfigPool, ctrcfgs []*mcfgv1.ContainerRuntimeConfig) ([]string, error) {
+	ctrcfg := cfg.Spec.ContainerRuntimeConfig
+	if ctrcfg == nil {
+		return nil, nil
+	}
+	others := make([]*mcfgv1.ContainerRuntimeConfig, 0, len(ctrcfgs))
+	for _, other := range ctrcfgs {
+		if other.Name == cfg.Name || other.DeletionTimestamp != nil || other.Spec.ContainerRuntimeConfig == nil {
+			continue
+		}
+		selected, err := selectsPool(other, pool)
+		if err != nil {
+			return nil, err
+		}
+		if selected {
+			others = append(others, other)
+		}
+	}
+	sort.Slice(others, func(i, j int) bool { return others[i].Name < others[j].Name })
+
+	var warnings []string
+	conflict := func(field, value, otherValue, otherName string) {
+		warnings = append(warnings, fmt.Sprintf("%s is set to %s, but ContainerRuntimeConfig %s also applying to MachineConfigPool %s sets it to %s, only one of them takes effect",
+			field, value, otherName, pool.Name, otherValue))
 	}
+	for _, other := range others {
+		otherCfg := other.Spec.ContainerRuntimeConfig
+		if ctrcfg.PidsLimit != nil && otherCfg.PidsLimit != nil && *ctrcfg.PidsLimit != *otherCfg.PidsLimit {
+			conflict("PidsLimit", strconv.FormatInt(*ctrcfg.PidsLimit, 10), strconv.FormatInt(*otherCfg.PidsLimit, 10), other.Name)
+		}
+		if ctrcfg.LogLevel != "" && otherCfg.LogLevel != "" && ctrcfg.LogLevel != otherCfg.LogLevel {
+			conflict("LogLevel", ctrcfg.LogLevel, otherCfg.LogLevel, other.Name)
+		}
+		if ctrcfg.OverlaySize != nil && otherCfg.OverlaySize != nil && ctrcfg.OverlaySize.Cmp(*otherCfg.OverlaySize) != 0 {
+			conflict("OverlaySize", ctrcfg.OverlaySize.String(), otherCfg.OverlaySize.String(), other.Name)
+		}
+	}
+	return warnings, nil
+}
 
+// validateBlockedAndAllowedRegistries makes sure that at most one of blockedRegistries and allowedRegistries is set,
+// setting both results in a policy.json with contradictory rules
+func validateBlockedAndAllowedRegistries(regSources *apicfgv1.RegistrySources) error {
+	if len(regSources.AllowedRegistries) != 0 && len(regSources.BlockedRegistries) != 0 {
+		return fmt.Errorf("invalid image config: only one of allowedRegistries %v and blockedRegistries %v may be set", regSources.AllowedRegistries, regSources.BlockedRegistries)
+	}
 	return nil
 }
 
 // getValidBlockedRegistries gets the blocked registries in the image spec and validates that the user is not adding
-// the registry being used by the payload to the list of blocked registries.
+// the registry being used by the payload or by the CRI-O pause image to the list of blocked registries.
 // If the user is, we drop that registry and continue with syncing the registries.conf with the other registry options
 // This returns the blocked list for registries.conf and policy.json separately as well as the allowed list for policy.json
-func getValidBlockedAndAllowedRegistries(releaseImage string, imgSpec *apicfgv1.ImageSpec, icspRules []*apioperatorsv1alpha1.ImageContentSourcePolicy, idmsRules []*apicfgv1.ImageDigestMirrorSet) (registriesBlocked, policyBlocked, allowed []string, retErr error) {
+func getValidBlockedAndAllowedRegistries(releaseImage, pauseImage string, imgSpec *apicfgv1.ImageSpec, icspRules []*apioperatorsv1alpha1.ImageContentSourcePolicy, idmsRules []*apicfgv1.ImageDigestMirrorSet) (registriesBlocked, policyBlocked, allowed []string, retErr error) {
 	if imgSpec == nil {
 		return nil, nil, nil, nil
 	}
@@ -641,18 +1359,51 @@ func getValidBlockedAndAllowedRegistries(releaseImage string, imgSpec *apicfgv1.
 
 	var blockErr []string
 
-	// Get the repository being used by the payload from the releaseImage
-	ref, err := getPayloadRepo(releaseImage)
-	if err != nil {
-		return nil, nil, nil, errParsingReference
+	// Get the repository being used by the payload from the releaseImage. It is not known yet while the desired
+	// release image is unset during very early bootstrap, then only the pause image repository is protected.
+	var protectedRefs []reference.Named
+	if releaseImage != "" {
+		ref, err := getPayloadRepo(releaseImage)
+		if err != nil {
+			return nil, nil, nil, errParsingReference
+		}
+		protectedRefs = append(protectedRefs, ref)
 	}
-	payloadRepo := ref.Name()
+	// Blocking the pause image breaks pod sandbox creation, so its repository is protected the same way as the payload's
+	if pauseImage != "" {
+		pauseRef, err := reference.ParseNamed(pauseImage)
+		if err != nil {
+			klog.Warningf("could not parse the pause image %q, its registry will not be excluded from the blocked registries: %v", pauseImage, err)
+		} else if findRefNestedInsideScope(protectedRefs, pauseRef.Name()) == nil {
+			protectedRefs = append(protectedRefs, pauseRef)
+		}
+	}
+	// In mirrored and disconnected clusters the payload and the pause image are pulled from their mirrors, so blocking
+	// a registry containing one of the mirrors is refused the same way as blocking the payload repository itself
+	var protectedMirrors []string
+	for _, protectedRef := range protectedRefs {
+		protectedMirrors = append(protectedMirrors, getRepoMirrors(protectedRef, idmsRules)...)
+	}
+	blockable := make([]string, 0, len(imgSpec.RegistrySources.BlockedRegistries))
 	for _, reg := range imgSpec.RegistrySources.BlockedRegistries {
+		if mirror := findScopeNestedInsideScope(protectedMirrors, reg); mirror != "" {
+			klog.V(2).Infof("%q contains %q, a mirror of the payload or pause image repository, and will not be added to the list of blocked registries", reg, mirror)
+			blockErr = append(blockErr, reg)
+			continue
+		}
+		blockable = append(blockable, reg)
+	}
+	// The mirrors are checked against the registries that are actually blocked
+	blockableSpec := imgSpec.DeepCopy()
+	blockableSpec.RegistrySources.BlockedRegistries = blockable
+
+	for _, reg := range blockable {
 		// if there is a match, return all the blocked registries except those that matched and return an error as well
-		if runtimeutils.ScopeIsNestedInsideScope(payloadRepo, reg) {
+		if protectedRef := findRefNestedInsideScope(protectedRefs, reg); protectedRef != nil {
+			payloadRepo := protectedRef.Name()
 			// If the payload registry doesn't have mirror rules configured for it, then don't add it to the blocked registries list
 			// Note that we care only about digest mirrors (and not ImageTagMirrorSet) because the OpenShift release payload only uses digest references.
-			hasMirror, err := payloadRepoHasUnblockedMirror(ref, idmsRules, imgSpec)
+			hasMirror, err := payloadRepoHasUnblockedMirror(protectedRef, idmsRules, blockableSpec)
 			if err != nil {
 				return nil, nil, nil, err
 			}
@@ -661,7 +1412,7 @@ func getValidBlockedAndAllowedRegistries(releaseImage string, imgSpec *apicfgv1.
 				continue
 			}
 			// Log a warning that we are adding the payload registries to the blocked registries list as there are mirror rules for it
-			klog.Warningf("%q matches the payload repository, but will add it to the list of blocked registries as there are mirror rules configured for it", reg)
+			klog.Warningf("%q matches the payload or pause image repository %q, but will add it to the list of blocked registries as there are mirror rules configured for it", reg, payloadRepo)
 			// Intentionally do NOT add reg to policyBlocked, to allow using payloadRepo (physically accessing the mirrors)
 			// In the future, this will be user-controlled via https://github.com/openshift/api/blob/1a6fa2913810101176a1d776f899fc4781b3fa50/config/v1/types_image_digest_mirror_set.go#L74
 			registriesBlocked = append(registriesBlocked, reg)
@@ -678,12 +1429,60 @@ func getValidBlockedAndAllowedRegistries(releaseImage string, imgSpec *apicfgv1.
 		policyBlocked = append(policyBlocked, reg)
 	}
 	if len(blockErr) > 0 {
-		retErr = fmt.Errorf("error adding %q to blocked registries, cannot block the repository being used by the payload", blockErr)
+		retErr = fmt.Errorf("error adding %q to blocked registries, cannot block the repository being used by the payload or the pause image, or one of their mirrors", blockErr)
 	}
 	allowed = append(allowed, imgSpec.RegistrySources.AllowedRegistries...)
 	return registriesBlocked, policyBlocked, allowed, retErr
 }
 
+// findRefNestedInsideScope returns the first reference whose repository is nested inside scope, or nil if there is none
+func findRefNestedInsideScope(refs []reference.Named, scope string) reference.Named {
+	for _, ref := range refs {
+		if runtimeutils.ScopeIsNestedInsideScope(ref.Name(), scope) {
+			return ref
+		}
+	}
+	return nil
+}
+
+// findScopeNestedInsideScope returns the first of scopes nested inside scope, or "" if there is none
+func findScopeNestedInsideScope(scopes []string, scope string) string {
+	for _, s := range scopes {
+		if runtimeutils.ScopeIsNestedInsideScope(s, scope) {
+			return s
+		}
+	}
+	return ""
+}
+
+// getRepoMirrors returns the repositories the digest mirror rules redirect pulls of the repository of ref to.
+// Mirror rules with a wildcard source do not map to a repository and are skipped.
+func getRepoMirrors(ref reference.Named, idmsRules []*apicfgv1.ImageDigestMirrorSet) []string {
+	var mirrors []string
+	for _, idms := range idmsRules {
+		for _, rule := range idms.Spec.ImageDigestMirrors {
+			if strings.HasPrefix(rule.Source, "*.") || !runtimeutils.ScopeIsNestedInsideScope(ref.Name(), rule.Source) {
+				continue
+			}
+			// The part of the repository below the source is kept below the mirror, e.g. quay.io/ocp/release mirrored
+			// with the rule quay.io/ocp -> mirror.io/ocp is pulled from mirror.io/ocp/release
+			for _, mirror := range rule.Mirrors {
+				mirrors = append(mirrors, string(mirror)+strings.TrimPrefix(ref.Name(), rule.Source))
+			}
+		}
+	}
+	return mirrors
+}
+
+// getPauseImage returns the pause image CRI-O is configured with through the ControllerConfig, or "" when the
+// ControllerConfig does not set one
+func getPauseImage(cc *mcfgv1.ControllerConfig) string {
+	if cc == nil {
+		return ""
+	}
+	return cc.Spec.Images[mtmpl.InfraImageKey]
+}
+
 // payloadRepoHasUnblockedMirror returns true if the payload registry has mirror rules configured for it
 func payloadRepoHasUnblockedMirror(payloadRepo reference.Named, idmsRules []*apicfgv1.ImageDigestMirrorSet, imgSpec *apicfgv1.ImageSpec) (bool, error) {
 	// Create a temp registries.conf file with all the registry inputs given
@@ -753,6 +1552,41 @@ func getPayloadRepo(releaseImage string) (reference.Named, error) {
 	return ref, nil
 }
 
+// getInsecureRegistriesNotAllowed returns the insecure registries that an allowed registries list excludes: CRI-O is
+// configured to pull from them insecurely, but policy.json rejects every image from them. Insecure registries that
+// an allowed registry is nested inside are partially allowed and are not returned. Nothing is returned when allowed
+// is empty, as every registry is allowed then.
+func getInsecureRegistriesNotAllowed(insecure, allowed []string) []string {
+	if len(allowed) == 0 {
+		return nil
+	}
+	var notAllowed []string
+	for _, scope := range insecure {
+		if findScopeContaining(allowed, scope) != "" || findScopeNestedInsideScope(allowed, scope) != "" {
+			continue
+		}
+		notAllowed = append(notAllowed, scope)
+	}
+	return notAllowed
+}
+
+// payloadRegistryInSearchRegistries returns the registry of the payload and whether it is listed in searchRegs.
+// Images from the payload are always pulled by digest from a fully qualified reference, so listing its registry
+// as a search registry does not change anything for the payload and is usually a mistake.
+func payloadRegistryInSearchRegistries(releaseImage string, searchRegs []string) (string, bool, error) {
+	ref, err := getPayloadRepo(releaseImage)
+	if err != nil {
+		return "", false, err
+	}
+	payloadRegistry := reference.Domain(ref)
+	for _, reg := range searchRegs {
+		if reg == payloadRegistry {
+			return payloadRegistry, true, nil
+		}
+	}
+	return payloadRegistry, false, nil
+}
+
 func validateRegistriesConfScopes(insecure, blocked, allowed []string, icspRules []*apioperatorsv1alpha1.ImageContentSourcePolicy, idmsRules []*apicfgv1.ImageDigestMirrorSet, itmsRules []*apicfgv1.ImageTagMirrorSet) error {
 	for _, scope := range insecure {
 		if !registries.IsValidRegistriesConfScope(scope) {
@@ -821,6 +1655,128 @@ func validateRegistriesConfScopes(insecure, blocked, allowed []string, icspRules
 	return nil
 }
 
+// countMirrorRules returns the total number of source to mirrors rules configured across all ICSP, IDMS and ITMS objects
+func countMirrorRules(icspRules []*apioperatorsv1alpha1.ImageContentSourcePolicy, idmsRules []*apicfgv1.ImageDigestMirrorSet, itmsRules []*apicfgv1.ImageTagMirrorSet) int {
+	count := 0
+	for _, icsp := range icspRules {
+		count += len(icsp.Spec.RepositoryDigestMirrors)
+	}
+	for _, idms := range idmsRules {
+		count += len(idms.Spec.ImageDigestMirrors)
+	}
+	for _, itms := range itmsRules {
+		count += len(itms.Spec.ImageTagMirrors)
+	}
+	return count
+}
+
+// preferIDMSOverICSP drops the ImageContentSourcePolicy mirror rules for sources that are also configured
+// by an ImageDigestMirrorSet, so that the newer API wins when both configure the same source.
+// It returns the remaining ICSP rules and the sources whose ICSP mirrors were not all covered by the IDMS rules.
+// IDMS rules restricted to an architecture only add mirrors on its pools, they do not replace the ICSP rules.
+func preferIDMSOverICSP(icspRules []*apioperatorsv1alpha1.ImageContentSourcePolicy, idmsRules []*apicfgv1.ImageDigestMirrorSet) ([]*apioperatorsv1alpha1.ImageContentSourcePolicy, []string) {
+	idmsMirrors := map[string]sets.Set[string]{}
+	for _, idms := range idmsRules {
+		if getMirrorSetArchitecture(idms) != "" {
+			continue
+		}
+		for _, mirrorSet := range idms.Spec.ImageDigestMirrors {
+			if _, ok := idmsMirrors[mirrorSet.Source]; !ok {
+				idmsMirrors[mirrorSet.Source] = sets.New[string]()
+			}
+			for _, mirror := range mirrorSet.Mirrors {
+				idmsMirrors[mirrorSet.Source].Insert(string(mirror))
+			}
+		}
+	}
+	if len(idmsMirrors) == 0 {
+		return icspRules, nil
+	}
+
+	conflicts := sets.New[string]()
+	filtered := make([]*apioperatorsv1alpha1.ImageContentSourcePolicy, 0, len(icspRules))
+	for _, icsp := range icspRules {
+		var kept []apioperatorsv1alpha1.RepositoryDigestMirrors
+		for _, mirrorSet := range icsp.Spec.RepositoryDigestMirrors {
+			mirrors, ok := idmsMirrors[mirrorSet.Source]
+			if !ok {
+				kept = append(kept, mirrorSet)
+				continue
+			}
+			if !mirrors.HasAll(mirrorSet.Mirrors...) {
+				conflicts.Insert(mirrorSet.Source)
+			}
+		}
+		switch {
+		case len(kept) == len(icsp.Spec.RepositoryDigestMirrors):
+			filtered = append(filtered, icsp)
+		case len(kept) > 0:
+			// Never modify the object from the lister cache
+			icspCopy := icsp.DeepCopy()
+			icspCopy.Spec.RepositoryDigestMirrors = kept
+			filtered = append(filtered, icspCopy)
+		}
+	}
+	return filtered, sets.List(conflicts)
+}
+
+// preferDigestOnlyOverTagMirrors drops the ImageTagMirrorSet mirrors that an ImageContentSourcePolicy also configures
+// for the same source. ICSP mirrors are always digest-only, so the same mirror also being used for tags would make
+// registries.conf ambiguous, the stricter digest-only setting wins.
+// It returns the remaining ITMS rules and the sources whose ITMS mirrors were dropped. ICSP rules restricted to an
+// architecture are left out, dropping the ITMS mirrors for them would also drop them on the pools of other architectures.
+func preferDigestOnlyOverTagMirrors(icspRules []*apioperatorsv1alpha1.ImageContentSourcePolicy, itmsRules []*apicfgv1.ImageTagMirrorSet) ([]*apicfgv1.ImageTagMirrorSet, []string) {
+	icspMirrors := map[string]sets.Set[string]{}
+	for _, icsp := range icspRules {
+		if getMirrorSetArchitecture(icsp) != "" {
+			continue
+		}
+		for _, mirrorSet := range icsp.Spec.RepositoryDigestMirrors {
+			if _, ok := icspMirrors[mirrorSet.Source]; !ok {
+				icspMirrors[mirrorSet.Source] = sets.New[string]()
+			}
+			icspMirrors[mirrorSet.Source].Insert(mirrorSet.Mirrors...)
+		}
+	}
+	if len(icspMirrors) == 0 {
+		return itmsRules, nil
+	}
+
+	conflicts := sets.New[string]()
+	filtered := make([]*apicfgv1.ImageTagMirrorSet, 0, len(itmsRules))
+	for _, itms := range itmsRules {
+		var (
+			kept    []apicfgv1.ImageTagMirrors
+			changed bool
+		)
+		for _, mirrorSet := range itms.Spec.ImageTagMirrors {
+			var mirrors []apicfgv1.ImageMirror
+			for _, mirror := range mirrorSet.Mirrors {
+				if icspMirrors[mirrorSet.Source].Has(string(mirror)) {
+					conflicts.Insert(mirrorSet.Source)
+					changed = true
+					continue
+				}
+				mirrors = append(mirrors, mirror)
+			}
+			if len(mirrors) > 0 {
+				mirrorSet.Mirrors = mirrors
+				kept = append(kept, mirrorSet)
+			}
+		}
+		switch {
+		case !changed:
+			filtered = append(filtered, itms)
+		case len(kept) > 0:
+			// Never modify the object from the lister cache
+			itmsCopy := itms.DeepCopy()
+			itmsCopy.Spec.ImageTagMirrors = kept
+			filtered = append(filtered, itmsCopy)
+		}
+	}
+	return filtered, sets.List(conflicts)
+}
+
 // convertICSPToIDMS converts ImageContentSourcePolicy to ImageDigestMirrorSet struct
 func convertICSPToIDMS(icsp *apioperatorsv1alpha1.ImageContentSourcePolicy) *apicfgv1.ImageDigestMirrorSet {
 	var imageDigestMirrors []apicfgv1.ImageDigestMirrors
@@ -908,6 +1864,34 @@ func policyItemFromSpec(policy apicfgv1alpha1.Policy) (signature.PolicyRequireme
 	return sigstorePolicyRequirement, nil
 }
 
+// withoutBlockedScopes returns the sigstoreSigned requirements of clusterScopePolicies without the scopes nested inside
+// a blocked registry. The requirements of such a scope would replace the reject rule of the blocked registry for the
+// images in that scope, so they are dropped to keep the images blocked.
+func withoutBlockedScopes(clusterScopePolicies map[string]signature.PolicyRequirements, blocked []string) map[string]signature.PolicyRequirements {
+	if len(blocked) == 0 {
+		return clusterScopePolicies
+	}
+	filtered := make(map[string]signature.PolicyRequirements, len(clusterScopePolicies))
+	for scope, requirements := range clusterScopePolicies {
+		if reg := findScopeContaining(blocked, scope); reg != "" {
+			klog.Warningf("clusterimagepolicy scope %s is nested inside the blocked registry %s, images from it will stay blocked", scope, reg)
+			continue
+		}
+		filtered[scope] = requirements
+	}
+	return filtered
+}
+
+// findScopeContaining returns the first of scopes that scope is nested inside, or "" if there is none
+func findScopeContaining(scopes []string, scope string) string {
+	for _, s := range scopes {
+		if runtimeutils.ScopeIsNestedInsideScope(scope, s) {
+			return s
+		}
+	}
+	return ""
+}
+
 func validateImagePolicyWithAllowedBlockedRegistries(clusterScopePolicies map[string]signature.PolicyRequirements, scopeNamespacePolicies map[string]map[string]signature.PolicyRequirements, allowedRegs, policyBlocked []string) error {
 	if len(allowedRegs) == 0 && len(policyBlocked) == 0 {
 		return nil
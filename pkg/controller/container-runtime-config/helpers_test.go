@@ -5,22 +5,29 @@ import (
 	b64 "encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
 	"reflect"
+	"slices"
 	"testing"
 
 	"github.com/BurntSushi/toml"
+	"github.com/containers/image/v5/docker/reference"
 	"github.com/containers/image/v5/pkg/sysregistriesv2"
 	signature "github.com/containers/image/v5/signature"
 	"github.com/containers/image/v5/types"
-	storageconfig "github.com/containers/storage/pkg/config"
 	apicfgv1 "github.com/openshift/api/config/v1"
 	apicfgv1alpha1 "github.com/openshift/api/config/v1alpha1"
 	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
 	apioperatorsv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+	ctrlcommon "github.com/openshift/machine-config-operator/pkg/controller/common"
+	mtmpl "github.com/openshift/machine-config-operator/pkg/controller/template"
+	"github.com/openshift/machine-config-operator/test/helpers"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/exp/maps"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/diff"
@@ -440,7 +447,7 @@ func TestUpdateRegistriesConfig(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := updateRegistriesConfig(templateBytes, tt.insecure, tt.blocked, tt.icspRules, tt.idmsRules, tt.itmsRules)
+			got, err := updateRegistriesConfig(templateBytes, tt.insecure, tt.blocked, registryMirrorRules{icsp: tt.icspRules, idms: tt.idmsRules, itms: tt.itmsRules})
 			if err != nil {
 				t.Errorf("updateRegistriesConfig() error = %v", err)
 				return
@@ -454,15 +461,550 @@ func TestUpdateRegistriesConfig(t *testing.T) {
 			// sort the two arrays before comparing, but right now hard-coding the order works well enough.
 			assert.Equal(t, tt.want, gotConf, "updateRegistriesConfig() Diff")
 			// Ensure that the generated configuration is actually valid.
-			registriesConf, err := os.CreateTemp("", "registries.conf")
+			requireRegistriesConfAcceptedByCRIO(t, got)
+		})
+	}
+}
+
+// requireRegistriesConfAcceptedByCRIO loads a generated registries.conf with the containers/image parser CRI-O uses,
+// failing the test if CRI-O would reject it, and returns the registries as CRI-O sees them. Drop-ins of the host are
+// not read.
+func requireRegistriesConfAcceptedByCRIO(t *testing.T, data []byte) []sysregistriesv2.Registry {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "registries.conf")
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+	registries, err := sysregistriesv2.GetRegistries(&types.SystemContext{
+		SystemRegistriesConfPath:    path,
+		SystemRegistriesConfDirPath: filepath.Join(dir, "registries.conf.d"),
+	})
+	require.NoError(t, err, "CRI-O would reject the generated registries.conf:\n%s", data)
+	return registries
+}
+
+func TestUpdateRegistriesConfigRoundTrip(t *testing.T) {
+	// A registry location with a URI scheme encodes to TOML just fine, but is rejected by containers/image
+	templateBytes := []byte(`unqualified-search-registries = ["registry.access.redhat.com", "docker.io"]
+
+[[registry]]
+  location = "https://malformed.example.com"
+`)
+
+	_, err := updateRegistriesConfig(templateBytes, []string{"insecure.com"}, nil, registryMirrorRules{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "generated registries config is invalid")
+
+	// The same changes on top of a well-formed template round-trip cleanly
+	validTemplateBytes := []byte(`unqualified-search-registries = ["registry.access.redhat.com", "docker.io"]
+`)
+	got, err := updateRegistriesConfig(validTemplateBytes, []string{"insecure.com"}, nil, registryMirrorRules{})
+	require.NoError(t, err)
+	require.NoError(t, validateRegistriesConfig(got))
+}
+
+func TestUpdateRegistriesConfigMirrorLoops(t *testing.T) {
+	templateBytes := []byte(`unqualified-search-registries = ["registry.access.redhat.com", "docker.io"]
+`)
+
+	t.Run("self-mirror", func(t *testing.T) {
+		icsp := &apioperatorsv1alpha1.ImageContentSourcePolicy{
+			Spec: apioperatorsv1alpha1.ImageContentSourcePolicySpec{
+				RepositoryDigestMirrors: []apioperatorsv1alpha1.RepositoryDigestMirrors{
+					{Source: "registry-a.com/ns", Mirrors: []string{"registry-a.com/ns", "mirror.registry-a.com/ns"}},
+					{Source: "registry-b.com/ns", Mirrors: []string{"registry-b.com/ns"}},
+				},
+			},
+		}
+		original := icsp.DeepCopy()
+
+		got, err := updateRegistriesConfig(templateBytes, nil, nil, registryMirrorRules{icsp: []*apioperatorsv1alpha1.ImageContentSourcePolicy{icsp}})
+		require.NoError(t, err)
+		gotConf := sysregistriesv2.V2RegistriesConf{}
+		_, err = toml.Decode(string(got), &gotConf)
+		require.NoError(t, err)
+		require.Len(t, gotConf.Registries, 1)
+		assert.Equal(t, "registry-a.com/ns", gotConf.Registries[0].Location)
+		require.Len(t, gotConf.Registries[0].Mirrors, 1)
+		assert.Equal(t, "mirror.registry-a.com/ns", gotConf.Registries[0].Mirrors[0].Location)
+		// The lister object is left untouched
+		assert.Equal(t, original, icsp)
+	})
+
+	t.Run("two-node cycle", func(t *testing.T) {
+		icspRules := []*apioperatorsv1alpha1.ImageContentSourcePolicy{
+			{
+				Spec: apioperatorsv1alpha1.ImageContentSourcePolicySpec{
+					RepositoryDigestMirrors: []apioperatorsv1alpha1.RepositoryDigestMirrors{
+						{Source: "registry-a.com/ns", Mirrors: []string{"registry-b.com/ns"}},
+					},
+				},
+			},
+			{
+				Spec: apioperatorsv1alpha1.ImageContentSourcePolicySpec{
+					RepositoryDigestMirrors: []apioperatorsv1alpha1.RepositoryDigestMirrors{
+						{Source: "registry-b.com/ns", Mirrors: []string{"registry-a.com/ns"}},
+					},
+				},
+			},
+		}
+
+		_, err := updateRegistriesConfig(templateBytes, nil, nil, registryMirrorRules{icsp: icspRules})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "registry-a.com/ns -> registry-b.com/ns -> registry-a.com/ns")
+	})
+}
+
+func TestUpdateRegistriesConfigPullFromMirror(t *testing.T) {
+	templateBytes := []byte(`unqualified-search-registries = ["registry.access.redhat.com", "docker.io"]
+`)
+	icsp := &apioperatorsv1alpha1.ImageContentSourcePolicy{
+		Spec: apioperatorsv1alpha1.ImageContentSourcePolicySpec{
+			RepositoryDigestMirrors: []apioperatorsv1alpha1.RepositoryDigestMirrors{
+				{Source: "registry-a.com", Mirrors: []string{"mirror-1.registry-a.com"}},
+			},
+		},
+	}
+	idms := &apicfgv1.ImageDigestMirrorSet{
+		Spec: apicfgv1.ImageDigestMirrorSetSpec{
+			ImageDigestMirrors: []apicfgv1.ImageDigestMirrors{
+				{Source: "registry-a.com", Mirrors: []apicfgv1.ImageMirror{"mirror-1.registry-a.com", "mirror-2.registry-a.com"}},
+			},
+		},
+	}
+	itms := &apicfgv1.ImageTagMirrorSet{
+		Spec: apicfgv1.ImageTagMirrorSetSpec{
+			ImageTagMirrors: []apicfgv1.ImageTagMirrors{
+				{Source: "registry-a.com", Mirrors: []apicfgv1.ImageMirror{"mirror-1.registry-a.com", "mirror-2.registry-a.com"}},
+			},
+		},
+	}
+	partialITMS := &apicfgv1.ImageTagMirrorSet{
+		Spec: apicfgv1.ImageTagMirrorSetSpec{
+			ImageTagMirrors: []apicfgv1.ImageTagMirrors{
+				{Source: "registry-a.com", Mirrors: []apicfgv1.ImageMirror{"mirror-2.registry-a.com"}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		mirrorRules registryMirrorRules
+		want        []sysregistriesv2.Endpoint
+	}{
+		{
+			name:        "icsp is digest only",
+			mirrorRules: registryMirrorRules{icsp: []*apioperatorsv1alpha1.ImageContentSourcePolicy{icsp}},
+			want: []sysregistriesv2.Endpoint{
+				{Location: "mirror-1.registry-a.com", PullFromMirror: sysregistriesv2.MirrorByDigestOnly},
+			},
+		},
+		{
+			name:        "idms is digest only",
+			mirrorRules: registryMirrorRules{idms: []*apicfgv1.ImageDigestMirrorSet{idms}},
+			want: []sysregistriesv2.Endpoint{
+				{Location: "mirror-1.registry-a.com", PullFromMirror: sysregistriesv2.MirrorByDigestOnly},
+				{Location: "mirror-2.registry-a.com", PullFromMirror: sysregistriesv2.MirrorByDigestOnly},
+			},
+		},
+		{
+			name:        "itms is tag only",
+			mirrorRules: registryMirrorRules{itms: []*apicfgv1.ImageTagMirrorSet{itms}},
+			want: []sysregistriesv2.Endpoint{
+				{Location: "mirror-1.registry-a.com", PullFromMirror: sysregistriesv2.MirrorByTagOnly},
+				{Location: "mirror-2.registry-a.com", PullFromMirror: sysregistriesv2.MirrorByTagOnly},
+			},
+		},
+		{
+			name:        "same mirrors for digests and tags are used for all pulls",
+			mirrorRules: registryMirrorRules{idms: []*apicfgv1.ImageDigestMirrorSet{idms}, itms: []*apicfgv1.ImageTagMirrorSet{itms}},
+			want: []sysregistriesv2.Endpoint{
+				{Location: "mirror-1.registry-a.com", PullFromMirror: sysregistriesv2.MirrorAll},
+				{Location: "mirror-2.registry-a.com", PullFromMirror: sysregistriesv2.MirrorAll},
+			},
+		},
+		{
+			name:        "partially overlapping mirrors keep their scope",
+			mirrorRules: registryMirrorRules{idms: []*apicfgv1.ImageDigestMirrorSet{idms}, itms: []*apicfgv1.ImageTagMirrorSet{partialITMS}},
+			want: []sysregistriesv2.Endpoint{
+				{Location: "mirror-1.registry-a.com", PullFromMirror: sysregistriesv2.MirrorByDigestOnly},
+				{Location: "mirror-2.registry-a.com", PullFromMirror: sysregistriesv2.MirrorByDigestOnly},
+				{Location: "mirror-2.registry-a.com", PullFromMirror: sysregistriesv2.MirrorByTagOnly},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := updateRegistriesConfig(templateBytes, nil, nil, tt.mirrorRules)
 			require.NoError(t, err)
-			_, err = registriesConf.Write(got)
+			gotConf := sysregistriesv2.V2RegistriesConf{}
+			_, err = toml.Decode(string(got), &gotConf)
 			require.NoError(t, err)
-			defer os.Remove(registriesConf.Name())
-			_, err = sysregistriesv2.GetRegistries(&types.SystemContext{
-				SystemRegistriesConfPath: registriesConf.Name(),
-			})
-			assert.NoError(t, err)
+			require.Len(t, gotConf.Registries, 1)
+			assert.Equal(t, "registry-a.com", gotConf.Registries[0].Location)
+			assert.Equal(t, tt.want, gotConf.Registries[0].Mirrors)
+		})
+	}
+}
+
+func TestPreferIDMSOverICSP(t *testing.T) {
+	icsp := &apioperatorsv1alpha1.ImageContentSourcePolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "legacy"},
+		Spec: apioperatorsv1alpha1.ImageContentSourcePolicySpec{
+			RepositoryDigestMirrors: []apioperatorsv1alpha1.RepositoryDigestMirrors{
+				{Source: "conflict.example.com", Mirrors: []string{"icsp-mirror.example.com"}},
+				{Source: "same.example.com", Mirrors: []string{"same-mirror.example.com"}},
+				{Source: "icsp-only.example.com", Mirrors: []string{"icsp-only-mirror.example.com"}},
+			},
+		},
+	}
+	covered := &apioperatorsv1alpha1.ImageContentSourcePolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "covered"},
+		Spec: apioperatorsv1alpha1.ImageContentSourcePolicySpec{
+			RepositoryDigestMirrors: []apioperatorsv1alpha1.RepositoryDigestMirrors{
+				{Source: "same.example.com", Mirrors: []string{"same-mirror.example.com"}},
+			},
+		},
+	}
+	idms := &apicfgv1.ImageDigestMirrorSet{
+		Spec: apicfgv1.ImageDigestMirrorSetSpec{
+			ImageDigestMirrors: []apicfgv1.ImageDigestMirrors{
+				{Source: "conflict.example.com", Mirrors: []apicfgv1.ImageMirror{"idms-mirror.example.com"}},
+				{Source: "same.example.com", Mirrors: []apicfgv1.ImageMirror{"same-mirror.example.com", "other-mirror.example.com"}},
+			},
+		},
+	}
+
+	t.Run("no idms", func(t *testing.T) {
+		icspRules := []*apioperatorsv1alpha1.ImageContentSourcePolicy{icsp, covered}
+		got, conflicts := preferIDMSOverICSP(icspRules, nil)
+		assert.Equal(t, icspRules, got)
+		assert.Empty(t, conflicts)
+	})
+
+	t.Run("idms wins", func(t *testing.T) {
+		got, conflicts := preferIDMSOverICSP([]*apioperatorsv1alpha1.ImageContentSourcePolicy{icsp, covered}, []*apicfgv1.ImageDigestMirrorSet{idms})
+		// Sources with ICSP mirrors missing from the IDMS are reported, fully covered ones are dropped silently
+		assert.Equal(t, []string{"conflict.example.com"}, conflicts)
+		require.Len(t, got, 1)
+		assert.Equal(t, "legacy", got[0].Name)
+		assert.Equal(t, []apioperatorsv1alpha1.RepositoryDigestMirrors{
+			{Source: "icsp-only.example.com", Mirrors: []string{"icsp-only-mirror.example.com"}},
+		}, got[0].Spec.RepositoryDigestMirrors)
+		// The original object is left untouched
+		assert.Len(t, icsp.Spec.RepositoryDigestMirrors, 3)
+	})
+
+	t.Run("architecture-specific idms only adds mirrors", func(t *testing.T) {
+		archIDMS := idms.DeepCopy()
+		archIDMS.Annotations = map[string]string{ctrlcommon.MirrorSetArchitectureAnnotationKey: "arm64"}
+		icspRules := []*apioperatorsv1alpha1.ImageContentSourcePolicy{icsp, covered}
+		got, conflicts := preferIDMSOverICSP(icspRules, []*apicfgv1.ImageDigestMirrorSet{archIDMS})
+		assert.Equal(t, icspRules, got)
+		assert.Empty(t, conflicts)
+	})
+}
+
+func TestPreferDigestOnlyOverTagMirrors(t *testing.T) {
+	icsp := &apioperatorsv1alpha1.ImageContentSourcePolicy{
+		Spec: apioperatorsv1alpha1.ImageContentSourcePolicySpec{
+			RepositoryDigestMirrors: []apioperatorsv1alpha1.RepositoryDigestMirrors{
+				{Source: "conflict.example.com", Mirrors: []string{"digest-mirror.example.com"}},
+				{Source: "covered.example.com", Mirrors: []string{"covered-mirror.example.com"}},
+			},
+		},
+	}
+	itms := &apicfgv1.ImageTagMirrorSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "tags"},
+		Spec: apicfgv1.ImageTagMirrorSetSpec{
+			ImageTagMirrors: []apicfgv1.ImageTagMirrors{
+				{Source: "conflict.example.com", Mirrors: []apicfgv1.ImageMirror{"digest-mirror.example.com", "tag-mirror.example.com"}},
+				{Source: "itms-only.example.com", Mirrors: []apicfgv1.ImageMirror{"digest-mirror.example.com"}},
+			},
+		},
+	}
+	covered := &apicfgv1.ImageTagMirrorSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "covered"},
+		Spec: apicfgv1.ImageTagMirrorSetSpec{
+			ImageTagMirrors: []apicfgv1.ImageTagMirrors{
+				{Source: "covered.example.com", Mirrors: []apicfgv1.ImageMirror{"covered-mirror.example.com"}},
+			},
+		},
+	}
+
+	t.Run("no icsp", func(t *testing.T) {
+		itmsRules := []*apicfgv1.ImageTagMirrorSet{itms, covered}
+		got, conflicts := preferDigestOnlyOverTagMirrors(nil, itmsRules)
+		assert.Equal(t, itmsRules, got)
+		assert.Empty(t, conflicts)
+	})
+
+	t.Run("digest-only wins", func(t *testing.T) {
+		got, conflicts := preferDigestOnlyOverTagMirrors([]*apioperatorsv1alpha1.ImageContentSourcePolicy{icsp}, []*apicfgv1.ImageTagMirrorSet{itms, covered})
+		assert.Equal(t, []string{"conflict.example.com", "covered.example.com"}, conflicts)
+		require.Len(t, got, 1)
+		assert.Equal(t, "tags", got[0].Name)
+		// Only the mirrors the ICSP configures for the same source are dropped
+		assert.Equal(t, []apicfgv1.ImageTagMirrors{
+			{Source: "conflict.example.com", Mirrors: []apicfgv1.ImageMirror{"tag-mirror.example.com"}},
+			{Source: "itms-only.example.com", Mirrors: []apicfgv1.ImageMirror{"digest-mirror.example.com"}},
+		}, got[0].Spec.ImageTagMirrors)
+		// The original object is left untouched
+		assert.Len(t, itms.Spec.ImageTagMirrors[0].Mirrors, 2)
+	})
+
+	t.Run("architecture-specific icsp keeps the tag mirrors", func(t *testing.T) {
+		archICSP := icsp.DeepCopy()
+		archICSP.Annotations = map[string]string{ctrlcommon.MirrorSetArchitectureAnnotationKey: "arm64"}
+		itmsRules := []*apicfgv1.ImageTagMirrorSet{itms, covered}
+		got, conflicts := preferDigestOnlyOverTagMirrors([]*apioperatorsv1alpha1.ImageContentSourcePolicy{archICSP}, itmsRules)
+		assert.Equal(t, itmsRules, got)
+		assert.Empty(t, conflicts)
+	})
+}
+
+func TestRegistryMirrorRulesForArchitecture(t *testing.T) {
+	withArch := func(obj metav1.Object, arch string) {
+		obj.SetAnnotations(map[string]string{ctrlcommon.MirrorSetArchitectureAnnotationKey: arch})
+	}
+	allICSP := &apioperatorsv1alpha1.ImageContentSourcePolicy{ObjectMeta: metav1.ObjectMeta{Name: "all"}}
+	armICSP := &apioperatorsv1alpha1.ImageContentSourcePolicy{ObjectMeta: metav1.ObjectMeta{Name: "arm"}}
+	withArch(armICSP, "arm64")
+	allIDMS := &apicfgv1.ImageDigestMirrorSet{ObjectMeta: metav1.ObjectMeta{Name: "all"}}
+	amdIDMS := &apicfgv1.ImageDigestMirrorSet{ObjectMeta: metav1.ObjectMeta{Name: "amd"}}
+	withArch(amdIDMS, "amd64")
+	armITMS := &apicfgv1.ImageTagMirrorSet{ObjectMeta: metav1.ObjectMeta{Name: "arm"}}
+	withArch(armITMS, "arm64")
+	rules := registryMirrorRules{
+		icsp: []*apioperatorsv1alpha1.ImageContentSourcePolicy{allICSP, armICSP},
+		idms: []*apicfgv1.ImageDigestMirrorSet{allIDMS, amdIDMS},
+		itms: []*apicfgv1.ImageTagMirrorSet{armITMS},
+	}
+
+	assert.Equal(t, registryMirrorRules{
+		icsp: []*apioperatorsv1alpha1.ImageContentSourcePolicy{allICSP, armICSP},
+		idms: []*apicfgv1.ImageDigestMirrorSet{allIDMS},
+		itms: []*apicfgv1.ImageTagMirrorSet{armITMS},
+	}, rules.forArchitecture("arm64"))
+	assert.Equal(t, registryMirrorRules{
+		icsp: []*apioperatorsv1alpha1.ImageContentSourcePolicy{allICSP},
+		idms: []*apicfgv1.ImageDigestMirrorSet{allIDMS, amdIDMS},
+	}, rules.forArchitecture("amd64"))
+	assert.True(t, registryMirrorRules{}.forArchitecture("amd64").isEmpty())
+
+}
+
+func TestGetNodesArchitecture(t *testing.T) {
+	node := func(arch string) *corev1.Node {
+		node := &corev1.Node{}
+		node.Status.NodeInfo.Architecture = arch
+		return node
+	}
+	assert.Equal(t, "", getNodesArchitecture(nil), "the architecture of a pool without nodes is not known")
+	assert.Equal(t, "s390x", getNodesArchitecture([]*corev1.Node{node("s390x"), node("s390x")}))
+	assert.Equal(t, "", getNodesArchitecture([]*corev1.Node{node("amd64"), node("arm64")}), "no architecture is picked for mixed nodes")
+}
+
+func TestGetConflictingConfigWarnings(t *testing.T) {
+	lowPidsLimit := int64(2048)
+	highPidsLimit := int64(4096)
+	smallOverlay := resource.MustParse("10G")
+	sameSmallOverlay := resource.MustParse("10Gi")
+	largeOverlay := resource.MustParse("20G")
+	worker := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "v0")
+	workerSelector := metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/worker", "")
+	masterSelector := metav1.AddLabelToSelector(&metav1.LabelSelector{}, "pools.operator.machineconfiguration.openshift.io/master", "")
+
+	cfg := newContainerRuntimeConfig("cfg", &mcfgv1.ContainerRuntimeConfiguration{PidsLimit: &lowPidsLimit, LogLevel: "debug", OverlaySize: &smallOverlay}, workerSelector)
+	tests := []struct {
+		name  string
+		other *mcfgv1.ContainerRuntimeConfig
+		want  []string
+	}{
+		{
+			name:  "same values",
+			other: newContainerRuntimeConfig("other", &mcfgv1.ContainerRuntimeConfiguration{PidsLimit: &lowPidsLimit, LogLevel: "debug", OverlaySize: &smallOverlay}, workerSelector),
+		},
+		{
+			name:  "unset values",
+			other: newContainerRuntimeConfig("other", &mcfgv1.ContainerRuntimeConfiguration{DefaultRuntime: mcfgv1.ContainerRuntimeDefaultRuntimeCrun}, workerSelector),
+		},
+		{
+			name:  "other pool",
+			other: newContainerRuntimeConfig("other", &mcfgv1.ContainerRuntimeConfiguration{PidsLimit: &highPidsLimit}, masterSelector),
+		},
+		{
+			name:  "conflicting values",
+			other: newContainerRuntimeConfig("other", &mcfgv1.ContainerRuntimeConfiguration{PidsLimit: &highPidsLimit, LogLevel: "info", OverlaySize: &largeOverlay}, workerSelector),
+			want: []string{
+				"PidsLimit is set to 2048, but ContainerRuntimeConfig other also applying to MachineConfigPool worker sets it to 4096, only one of them takes effect",
+				"LogLevel is set to debug, but ContainerRuntimeConfig other also applying to MachineConfigPool worker sets it to info, only one of them takes effect",
+				"OverlaySize is set to 10G, but ContainerRuntimeConfig other also applying to MachineConfigPool worker sets it to 20G, only one of them takes effect",
+			},
+		},
+		{
+			name:  "overlay sizes in different units",
+			other: newContainerRuntimeConfig("other", &mcfgv1.ContainerRuntimeConfiguration{OverlaySize: &sameSmallOverlay}, workerSelector),
+			want: []string{
+				"OverlaySize is set to 10G, but ContainerRuntimeConfig other also applying to MachineConfigPool worker sets it to 10Gi, only one of them takes effect",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			warnings, err := getConflictingConfigWarnings(cfg, worker, []*mcfgv1.ContainerRuntimeConfig{cfg, test.other})
+			require.NoError(t, err)
+			assert.Equal(t, test.want, warnings)
+		})
+	}
+}
+
+func TestCreateNewIgnitionFilePaths(t *testing.T) {
+	for _, path := range []string{
+		CRIODropInFilePathLogLevel,
+		storageConfigPath,
+		sigstoreRegistriesConfigFilePath,
+		"/etc/crio/policies/openshift.json",
+	} {
+		_, err := createNewIgnition([]generatedConfigFile{{filePath: path, data: []byte("data")}})
+		assert.NoError(t, err, path)
+	}
+
+	for _, path := range []string{
+		"etc/crio/crio.conf.d/01-ctrcfg-pidsLimit",
+		"/etc/crio/crio.conf.d/../../kubernetes/kubelet.conf",
+		"/etc/crio//crio.conf.d/01-ctrcfg-pidsLimit",
+		"/etc/kubernetes/kubelet.conf",
+		"/etc/crio",
+		"/etc/crio-other/crio.conf",
+	} {
+		_, err := createNewIgnition([]generatedConfigFile{{filePath: path, data: []byte("data")}})
+		assert.ErrorContains(t, err, "invalid path", path)
+	}
+
+	// The path of the namespaced policies is derived from the namespace of the ImagePolicy
+	_, err := createNewIgnition(imagePolicyConfigFileList(map[string][]byte{"../../kubernetes/manifests/pod": []byte("{}")}))
+	assert.ErrorContains(t, err, "must be a clean absolute path")
+}
+
+func TestCreateNewIgnitionFileModes(t *testing.T) {
+	configs := []generatedConfigFile{
+		{filePath: storageConfigPath, data: []byte("storage")},
+		{filePath: registriesConfigPath, data: []byte("registries")},
+		{filePath: policyConfigPath, data: []byte("policy")},
+		{filePath: CRIODropInFilePathLogLevel, data: []byte("log level")},
+		// Files without data are not included
+		{filePath: sigstoreRegistriesConfigFilePath},
+		{filePath: "/etc/containers/private.conf", data: []byte("private"), mode: 0o600},
+	}
+	ignCfg, err := createNewIgnition(configs)
+	require.NoError(t, err)
+
+	modes := map[string]int{}
+	for _, file := range ignCfg.Storage.Files {
+		require.NotNil(t, file.Mode, file.Path)
+		require.NotNil(t, file.Overwrite, file.Path)
+		assert.True(t, *file.Overwrite, file.Path)
+		modes[file.Path] = *file.Mode
+	}
+	assert.Equal(t, map[string]int{
+		storageConfigPath:              0o644,
+		registriesConfigPath:           0o644,
+		policyConfigPath:               0o644,
+		CRIODropInFilePathLogLevel:     0o644,
+		"/etc/containers/private.conf": 0o600,
+	}, modes)
+
+	_, err = createNewIgnition([]generatedConfigFile{{filePath: policyConfigPath, data: []byte("policy"), mode: 0o666}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), policyConfigPath)
+}
+
+func TestValidateConfigFileMode(t *testing.T) {
+	tests := []struct {
+		mode    int
+		wantErr string
+	}{
+		{mode: 0o644},
+		{mode: 0o640},
+		{mode: 0o600},
+		{mode: 0o444},
+		{mode: 0o4644, wantErr: "special permission bits"},
+		{mode: 0o1644, wantErr: "special permission bits"},
+		{mode: 0o244, wantErr: "readable by its owner"},
+		{mode: 0o755, wantErr: "must not be executable"},
+		{mode: 0o654, wantErr: "must not be executable"},
+		{mode: 0o664, wantErr: "writable by group or others"},
+		{mode: 0o646, wantErr: "writable by group or others"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%#o", tt.mode), func(t *testing.T) {
+			err := validateConfigFileMode(registriesConfigPath, tt.mode)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestValidateRegistriesConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr string
+	}{
+		{
+			name: "valid",
+			data: []byte(`[[registry]]
+  location = "registry.example.com"
+  insecure = true
+`),
+		},
+		{
+			name:    "not TOML",
+			data:    []byte(`[[registry]`),
+			wantErr: "could not be decoded",
+		},
+		{
+			name: "mirror without a location",
+			data: []byte(`[[registry]]
+  location = "registry.example.com"
+
+  [[registry.mirror]]
+    insecure = true
+`),
+			wantErr: "generated registries config is invalid",
+		},
+		{
+			name: "mirror with a URI scheme",
+			data: []byte(`[[registry]]
+  location = "registry.example.com"
+
+  [[registry.mirror]]
+    location = "https://mirror.example.com"
+`),
+			wantErr: "has a URI scheme",
+		},
+		{
+			name: "registry without a location or a prefix",
+			data: []byte(`[[registry]]
+  insecure = true
+`),
+			wantErr: "neither a location nor a prefix",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRegistriesConfig(tt.data)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
 		})
 	}
 }
@@ -795,6 +1337,50 @@ func TestUpdatePolicyJSON(t *testing.T) {
 			},
 			errorExpected: false,
 		},
+		{
+			name:               "blocked registries and ClusterImagePolicy CR for another registry",
+			blocked:            []string{"block.com"},
+			clusterimagepolicy: &testClusterImagePolicyCR,
+			want: signature.Policy{
+				Default: signature.PolicyRequirements{signature.NewPRInsecureAcceptAnything()},
+				Transports: map[string]signature.PolicyTransportScopes{
+					"atomic": map[string]signature.PolicyRequirements{
+						"block.com": {signature.NewPRReject()},
+						"test0.com": {expectSigRequirement},
+					},
+					"docker": map[string]signature.PolicyRequirements{
+						"block.com": {signature.NewPRReject()},
+						"test0.com": {expectSigRequirement},
+					},
+					"docker-daemon": map[string]signature.PolicyRequirements{
+						"": {signature.NewPRInsecureAcceptAnything()},
+					},
+				},
+			},
+			errorExpected: false,
+		},
+		{
+			name:               "registry that is both blocked and signed stays blocked",
+			blocked:            []string{"block.com", "test0.com"},
+			clusterimagepolicy: &testClusterImagePolicyCR,
+			want: signature.Policy{
+				Default: signature.PolicyRequirements{signature.NewPRInsecureAcceptAnything()},
+				Transports: map[string]signature.PolicyTransportScopes{
+					"atomic": map[string]signature.PolicyRequirements{
+						"block.com": {signature.NewPRReject()},
+						"test0.com": {signature.NewPRReject()},
+					},
+					"docker": map[string]signature.PolicyRequirements{
+						"block.com": {signature.NewPRReject()},
+						"test0.com": {signature.NewPRReject()},
+					},
+					"docker-daemon": map[string]signature.PolicyRequirements{
+						"": {signature.NewPRInsecureAcceptAnything()},
+					},
+				},
+			},
+			errorExpected: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -1028,7 +1614,7 @@ func TestValidateRegistriesConfScopes(t *testing.T) {
 
 func TestGetValidBlockAndAllowedRegistries(t *testing.T) {
 	tests := []struct {
-		name, releaseImg                                                  string
+		name, releaseImg, pauseImg                                        string
 		imgSpec                                                           *apicfgv1.ImageSpec
 		idmsRules                                                         []*apicfgv1.ImageDigestMirrorSet
 		expectedRegistriesBlocked, expectedPolicyBlocked, expectedAllowed []string
@@ -1111,7 +1697,7 @@ func TestGetValidBlockAndAllowedRegistries(t *testing.T) {
 			expectedErr:               false,
 		},
 		{
-			name:       "payload is blocked; all of mirror is not blocked, but the mirror of the payload is blocked",
+			name:       "payload is blocked; the mirror of the payload cannot be blocked",
 			releaseImg: "quay.io/openshift-release-dev@sha256:4207ba569ff014931f1b5d125fe3751936a768e119546683c899eb09f3cdceb0",
 			imgSpec: &apicfgv1.ImageSpec{
 				RegistrySources: apicfgv1.RegistrySources{
@@ -1127,12 +1713,13 @@ func TestGetValidBlockAndAllowedRegistries(t *testing.T) {
 					},
 				},
 			},
-			expectedRegistriesBlocked: []string{"block.io/openshift-release-dev"},
-			expectedPolicyBlocked:     []string{"block.io/openshift-release-dev"},
+			expectedRegistriesBlocked: []string{"quay.io"},
+			expectedPolicyBlocked:     []string{"quay.io"},
+			expectedAllowed:           []string{"quay.io/openshift-release-dev"},
 			expectedErr:               true,
 		},
 		{
-			name:       "payload is blocked; parent of the mirror of the payload is blocked",
+			name:       "payload is blocked; parent of the mirror of the payload cannot be blocked",
 			releaseImg: "quay.io/openshift-release-dev@sha256:4207ba569ff014931f1b5d125fe3751936a768e119546683c899eb09f3cdceb0",
 			imgSpec: &apicfgv1.ImageSpec{
 				RegistrySources: apicfgv1.RegistrySources{
@@ -1148,6 +1735,90 @@ func TestGetValidBlockAndAllowedRegistries(t *testing.T) {
 					},
 				},
 			},
+			expectedRegistriesBlocked: []string{"quay.io"},
+			expectedPolicyBlocked:     []string{"quay.io"},
+			expectedAllowed:           []string{"quay.io/openshift-release-dev"},
+			expectedErr:               true,
+		},
+		{
+			name:       "pause image registry is blocked",
+			releaseImg: "payload-reg.io/release-image@sha256:4207ba569ff014931f1b5d125fe3751936a768e119546683c899eb09f3cdceb0",
+			pauseImg:   "pause-reg.io/pause:3.9",
+			imgSpec: &apicfgv1.ImageSpec{
+				RegistrySources: apicfgv1.RegistrySources{
+					BlockedRegistries: []string{"pause-reg.io", "block.io"},
+				},
+			},
+			expectedRegistriesBlocked: []string{"block.io"},
+			expectedPolicyBlocked:     []string{"block.io"},
+			expectedErr:               true,
+		},
+		{
+			name:       "pause image repository is blocked",
+			releaseImg: "payload-reg.io/release-image@sha256:4207ba569ff014931f1b5d125fe3751936a768e119546683c899eb09f3cdceb0",
+			pauseImg:   "pause-reg.io/pause:3.9",
+			imgSpec: &apicfgv1.ImageSpec{
+				RegistrySources: apicfgv1.RegistrySources{
+					BlockedRegistries: []string{"pause-reg.io/pause"},
+				},
+			},
+			expectedErr: true,
+		},
+		{
+			name:       "other repositories of the pause image registry can be blocked",
+			releaseImg: "payload-reg.io/release-image@sha256:4207ba569ff014931f1b5d125fe3751936a768e119546683c899eb09f3cdceb0",
+			pauseImg:   "pause-reg.io/pause:3.9",
+			imgSpec: &apicfgv1.ImageSpec{
+				RegistrySources: apicfgv1.RegistrySources{
+					BlockedRegistries: []string{"pause-reg.io/other"},
+				},
+			},
+			expectedRegistriesBlocked: []string{"pause-reg.io/other"},
+			expectedPolicyBlocked:     []string{"pause-reg.io/other"},
+		},
+		{
+			name:       "pause image registry is blocked; mirror of the pause image is not blocked",
+			releaseImg: "payload-reg.io/release-image@sha256:4207ba569ff014931f1b5d125fe3751936a768e119546683c899eb09f3cdceb0",
+			pauseImg:   "pause-reg.io/pause@sha256:4207ba569ff014931f1b5d125fe3751936a768e119546683c899eb09f3cdceb0",
+			imgSpec: &apicfgv1.ImageSpec{
+				RegistrySources: apicfgv1.RegistrySources{
+					BlockedRegistries: []string{"pause-reg.io"},
+				},
+			},
+			idmsRules: []*apicfgv1.ImageDigestMirrorSet{
+				{
+					Spec: apicfgv1.ImageDigestMirrorSetSpec{
+						ImageDigestMirrors: []apicfgv1.ImageDigestMirrors{
+							{Source: "pause-reg.io/pause", Mirrors: []apicfgv1.ImageMirror{"mirror.io/pause"}},
+						},
+					},
+				},
+			},
+			expectedRegistriesBlocked: []string{"pause-reg.io"},
+			expectedPolicyBlocked:     []string{"pause-reg.io"},
+			expectedAllowed:           []string{"pause-reg.io/pause"},
+		},
+		{
+			name:       "no pause image set by the ControllerConfig, no registry is kept for it",
+			releaseImg: "payload-reg.io/release-image@sha256:4207ba569ff014931f1b5d125fe3751936a768e119546683c899eb09f3cdceb0",
+			pauseImg:   "",
+			imgSpec: &apicfgv1.ImageSpec{
+				RegistrySources: apicfgv1.RegistrySources{
+					BlockedRegistries: []string{"registry.k8s.io", "block.io"},
+				},
+			},
+			expectedRegistriesBlocked: []string{"registry.k8s.io", "block.io"},
+			expectedPolicyBlocked:     []string{"registry.k8s.io", "block.io"},
+		},
+		{
+			name:       "pause image registry is blocked without mirrors",
+			releaseImg: "payload-reg.io/release-image@sha256:4207ba569ff014931f1b5d125fe3751936a768e119546683c899eb09f3cdceb0",
+			pauseImg:   "pause-reg.io/pause:3.9",
+			imgSpec: &apicfgv1.ImageSpec{
+				RegistrySources: apicfgv1.RegistrySources{
+					BlockedRegistries: []string{"pause-reg.io", "block.io"},
+				},
+			},
 			expectedRegistriesBlocked: []string{"block.io"},
 			expectedPolicyBlocked:     []string{"block.io"},
 			expectedErr:               true,
@@ -1155,7 +1826,7 @@ func TestGetValidBlockAndAllowedRegistries(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotRegistries, gotPolicy, gotAllowed, err := getValidBlockedAndAllowedRegistries(tt.releaseImg, tt.imgSpec, nil, tt.idmsRules)
+			gotRegistries, gotPolicy, gotAllowed, err := getValidBlockedAndAllowedRegistries(tt.releaseImg, tt.pauseImg, tt.imgSpec, nil, tt.idmsRules)
 			if (err != nil && !tt.expectedErr) || (err == nil && tt.expectedErr) {
 				t.Errorf("getValidBlockedRegistries() error = %v", err)
 				return
@@ -1167,9 +1838,81 @@ func TestGetValidBlockAndAllowedRegistries(t *testing.T) {
 	}
 }
 
+func TestGetRepoMirrors(t *testing.T) {
+	// A typical disconnected install, the release and its content are mirrored to two registries
+	icsp := newICSP("release", []apioperatorsv1alpha1.RepositoryDigestMirrors{
+		{Source: "quay.io/openshift-release-dev/ocp-release", Mirrors: []string{"mirror-1.io/ocp4/release", "mirror-2.io/ocp4/release"}},
+		{Source: "quay.io/openshift-release-dev/ocp-v4.0-art-dev", Mirrors: []string{"mirror-1.io/ocp4/art-dev", "mirror-2.io/ocp4/art-dev"}},
+		{Source: "quay.io/openshift-release-dev", Mirrors: []string{"mirror-3.io/release-dev"}},
+		{Source: "registry.redhat.io", Mirrors: []string{"mirror-1.io/redhat"}},
+	})
+	idmsRules := []*apicfgv1.ImageDigestMirrorSet{
+		convertICSPToIDMS(icsp),
+		newIDMS("wildcard", []apicfgv1.ImageDigestMirrors{{Source: "*.quay.io", Mirrors: []apicfgv1.ImageMirror{"mirror-4.io"}}}),
+	}
+
+	tests := []struct {
+		image string
+		want  []string
+	}{
+		{
+			image: "quay.io/openshift-release-dev/ocp-release@sha256:4207ba569ff014931f1b5d125fe3751936a768e119546683c899eb09f3cdceb0",
+			want:  []string{"mirror-1.io/ocp4/release", "mirror-2.io/ocp4/release", "mirror-3.io/release-dev/ocp-release"},
+		},
+		{
+			image: "quay.io/openshift-release-dev/ocp-v4.0-art-dev@sha256:4207ba569ff014931f1b5d125fe3751936a768e119546683c899eb09f3cdceb0",
+			want:  []string{"mirror-1.io/ocp4/art-dev", "mirror-2.io/ocp4/art-dev", "mirror-3.io/release-dev/ocp-v4.0-art-dev"},
+		},
+		{
+			// Sources only match whole path components
+			image: "quay.io/openshift-release-dev-other/ocp-release@sha256:4207ba569ff014931f1b5d125fe3751936a768e119546683c899eb09f3cdceb0",
+		},
+		{
+			image: "payload-reg.io/release-image@sha256:4207ba569ff014931f1b5d125fe3751936a768e119546683c899eb09f3cdceb0",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.image, func(t *testing.T) {
+			ref, err := reference.ParseNamed(test.image)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, test.want, getRepoMirrors(ref, idmsRules))
+		})
+	}
+}
+
+func TestGetValidBlockAndAllowedRegistriesReleaseMirrors(t *testing.T) {
+	releaseImage := "quay.io/openshift-release-dev/ocp-release@sha256:4207ba569ff014931f1b5d125fe3751936a768e119546683c899eb09f3cdceb0"
+	icsp := newICSP("release", []apioperatorsv1alpha1.RepositoryDigestMirrors{
+		{Source: "quay.io/openshift-release-dev/ocp-release", Mirrors: []string{"mirror-1.io/ocp4/release", "mirror-2.io/ocp4/release"}},
+	})
+	imgSpec := &apicfgv1.ImageSpec{
+		RegistrySources: apicfgv1.RegistrySources{
+			BlockedRegistries: []string{"quay.io", "mirror-1.io", "mirror-2.io/ocp4", "mirror-2.io/other", "block.io"},
+		},
+	}
+
+	// None of the mirrors the release image is pulled from is blocked, so the payload registry itself can be
+	registriesBlocked, policyBlocked, allowed, err := getValidBlockedAndAllowedRegistries(releaseImage, "", imgSpec, []*apioperatorsv1alpha1.ImageContentSourcePolicy{icsp}, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "mirror-1.io")
+	assert.Contains(t, err.Error(), "mirror-2.io/ocp4")
+	assert.Equal(t, []string{"quay.io", "mirror-2.io/other", "block.io"}, registriesBlocked)
+	assert.Equal(t, []string{"quay.io", "mirror-2.io/other", "block.io"}, policyBlocked)
+	assert.Equal(t, []string{"quay.io/openshift-release-dev/ocp-release"}, allowed)
+}
+
+func TestGetPauseImage(t *testing.T) {
+	cc := &mcfgv1.ControllerConfig{}
+	assert.Empty(t, getPauseImage(nil))
+	assert.Empty(t, getPauseImage(cc))
+	cc.Spec.Images = map[string]string{mtmpl.InfraImageKey: "pause-reg.io/pause:3.9"}
+	assert.Equal(t, "pause-reg.io/pause:3.9", getPauseImage(cc))
+}
+
 func TestCreateCRIODropinFiles(t *testing.T) {
 	zeroLogSizeMax := resource.MustParse("0k")
 	validLogSizeMax := resource.MustParse("10G")
+	noLimitLogSizeMax := resource.MustParse("-1")
 
 	// Test zero value of logSizeMax will not be applied
 	zeroValueTests := []struct {
@@ -1178,12 +1921,17 @@ func TestCreateCRIODropinFiles(t *testing.T) {
 		filepath string
 	}{
 		{
-			name: "01-ctrcfg-logSizeMax will not be created if logSizeMax is zero",
+			name: "no drop-in will be created if logSizeMax is zero",
 			cfg: &mcfgv1.ContainerRuntimeConfiguration{
 				LogSizeMax: &zeroLogSizeMax,
 			},
 			filepath: crioDropInFilePathLogSizeMax,
 		},
+		{
+			name:     "no drop-in will be created if defaultRuntime is unset",
+			cfg:      &mcfgv1.ContainerRuntimeConfiguration{},
+			filepath: CRIODropInFilePathDefaultRuntime,
+		},
 	}
 
 	// Test valid value of logSizeMax will be applied to the drop-in file
@@ -1194,7 +1942,7 @@ func TestCreateCRIODropinFiles(t *testing.T) {
 		want     []byte
 	}{
 		{
-			name: "01-ctrcfg-logSizeMax created for valid logSizeMax",
+			name: "drop-in created for valid logSizeMax",
 			cfg: &mcfgv1.ContainerRuntimeConfiguration{
 				LogSizeMax: &validLogSizeMax,
 			},
@@ -1202,6 +1950,39 @@ func TestCreateCRIODropinFiles(t *testing.T) {
 			want: []byte(`[crio]
   [crio.runtime]
     log_size_max = 10000000000
+`),
+		},
+		{
+			name: "drop-in created for negative logSizeMax",
+			cfg: &mcfgv1.ContainerRuntimeConfiguration{
+				LogSizeMax: &noLimitLogSizeMax,
+			},
+			filepath: crioDropInFilePathLogSizeMax,
+			want: []byte(`[crio]
+  [crio.runtime]
+    log_size_max = -1
+`),
+		},
+		{
+			name: "01-ctrcfg-defaultRuntime created for crun",
+			cfg: &mcfgv1.ContainerRuntimeConfiguration{
+				DefaultRuntime: mcfgv1.ContainerRuntimeDefaultRuntimeCrun,
+			},
+			filepath: CRIODropInFilePathDefaultRuntime,
+			want: []byte(`[crio]
+  [crio.runtime]
+    default_runtime = "crun"
+`),
+		},
+		{
+			name: "01-ctrcfg-defaultRuntime created for runc",
+			cfg: &mcfgv1.ContainerRuntimeConfiguration{
+				DefaultRuntime: mcfgv1.ContainerRuntimeDefaultRuntimeRunc,
+			},
+			filepath: CRIODropInFilePathDefaultRuntime,
+			want: []byte(`[crio]
+  [crio.runtime]
+    default_runtime = "runc"
 `),
 		},
 	}
@@ -1219,11 +2000,229 @@ func TestCreateCRIODropinFiles(t *testing.T) {
 	for _, test := range validValueTests {
 		ctrcfg := newContainerRuntimeConfig(test.name, test.cfg, metav1.AddLabelToSelector(&metav1.LabelSelector{}, "", ""))
 		files := createCRIODropinFiles(ctrcfg)
+		found := false
 		for _, file := range files {
 			if file.filePath == test.filepath {
+				found = true
 				require.Equal(t, test.want, file.data, "createCRIODropinFiles() Diff, want %v, got %v", test.want, string(file.data))
 			}
 		}
+		require.True(t, found, "%s: failed. drop-in file %s was not created", test.name, test.filepath)
+	}
+}
+
+// getEffectiveCRIOConfig applies the CRI-O drop-in files of configFileList on top of the default CRI-O config in the
+// lexical order of their names, the same way CRI-O loads them, and returns the resulting config in TOML format
+func getEffectiveCRIOConfig(defaultCRIOConfig []byte, configFileList []generatedConfigFile) (string, error) {
+	configs := [][]byte{defaultCRIOConfig}
+	dropins, _ := splitCRIODropinFiles(configFileList)
+	for _, dropin := range dropins {
+		configs = append(configs, dropin.data)
+	}
+	merged, err := mergeTOMLConfigs(configs)
+	if err != nil {
+		return "", err
+	}
+	return string(merged), nil
+}
+
+func TestMergeCRIODropinFiles(t *testing.T) {
+	defaultCRIOConfig := []byte(`[crio]
+  [crio.runtime]
+    log_level = "info"
+`)
+	var pidsLimit int64 = 2048
+	cfg := &mcfgv1.ContainerRuntimeConfig{
+		Spec: mcfgv1.ContainerRuntimeConfigSpec{
+			ContainerRuntimeConfig: &mcfgv1.ContainerRuntimeConfiguration{
+				LogLevel:  "debug",
+				PidsLimit: &pidsLimit,
+			},
+		},
+	}
+	storageConf := generatedConfigFile{filePath: storageConfigPath, data: []byte("[storage]\ndriver = \"overlay\"\n")}
+	configFileList := append([]generatedConfigFile{storageConf}, createCRIODropinFiles(cfg)...)
+	require.Greater(t, len(configFileList), 2)
+
+	merged, err := mergeCRIODropinFiles(configFileList)
+	require.NoError(t, err)
+	require.Len(t, merged, 2)
+	assert.Equal(t, storageConf, merged[0], "files outside of the CRI-O config directory should be kept as they are")
+	assert.Equal(t, crioDropInFilePathSingle, merged[1].filePath)
+	assert.Equal(t, `[crio]
+  [crio.runtime]
+    log_level = "debug"
+    pids_limit = 2048
+`, string(merged[1].data))
+
+	// The order of the drop-in files does not change the merged file
+	reversed := slices.Clone(configFileList)
+	slices.Reverse(reversed)
+	mergedReversed, err := mergeCRIODropinFiles(reversed)
+	require.NoError(t, err)
+	assert.Equal(t, merged[1], mergedReversed[len(mergedReversed)-1])
+
+	// CRI-O ends up with the same config either way
+	wantEffective, err := getEffectiveCRIOConfig(defaultCRIOConfig, configFileList)
+	require.NoError(t, err)
+	gotEffective, err := getEffectiveCRIOConfig(defaultCRIOConfig, merged)
+	require.NoError(t, err)
+	assert.Equal(t, wantEffective, gotEffective)
+
+	noDropins, err := mergeCRIODropinFiles([]generatedConfigFile{storageConf})
+	require.NoError(t, err)
+	assert.Equal(t, []generatedConfigFile{storageConf}, noDropins)
+}
+
+func TestGetExternalLogRotationWarning(t *testing.T) {
+	logSizeMax := resource.MustParse("10k")
+	unlimited := resource.MustParse("-1")
+	zero := resource.MustParse("0")
+
+	rotatingPool := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "v0")
+	metav1.SetMetaDataAnnotation(&rotatingPool.ObjectMeta, ctrlcommon.MachineConfigPoolExternalLogRotationAnnotationKey, "true")
+	pool := helpers.NewMachineConfigPool("worker", nil, helpers.WorkerSelector, "v0")
+
+	tests := []struct {
+		name        string
+		cfg         *mcfgv1.ContainerRuntimeConfiguration
+		pool        *mcfgv1.MachineConfigPool
+		wantWarning bool
+	}{
+		{
+			name:        "logSizeMax set on a pool rotating the logs externally",
+			cfg:         &mcfgv1.ContainerRuntimeConfiguration{LogSizeMax: &logSizeMax},
+			pool:        rotatingPool,
+			wantWarning: true,
+		},
+		{
+			name: "logSizeMax set on a pool without external rotation",
+			cfg:  &mcfgv1.ContainerRuntimeConfiguration{LogSizeMax: &logSizeMax},
+			pool: pool,
+		},
+		{
+			name: "logSizeMax unset",
+			cfg:  &mcfgv1.ContainerRuntimeConfiguration{LogLevel: "debug"},
+			pool: rotatingPool,
+		},
+		{
+			name: "logSizeMax unlimited",
+			cfg:  &mcfgv1.ContainerRuntimeConfiguration{LogSizeMax: &unlimited},
+			pool: rotatingPool,
+		},
+		{
+			name: "logSizeMax zero",
+			cfg:  &mcfgv1.ContainerRuntimeConfiguration{LogSizeMax: &zero},
+			pool: rotatingPool,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := &mcfgv1.ContainerRuntimeConfig{Spec: mcfgv1.ContainerRuntimeConfigSpec{ContainerRuntimeConfig: test.cfg}}
+			warning := getExternalLogRotationWarning(cfg, test.pool)
+			if test.wantWarning {
+				assert.Contains(t, warning, "MachineConfigPool worker rotates them with an external tool")
+			} else {
+				assert.Empty(t, warning)
+			}
+		})
+	}
+}
+
+func TestGetContainerRuntimeConfigWarnings(t *testing.T) {
+	var (
+		unlimitedPidsLimit   int64
+		minPids              int64 = minPidsLimit
+		lowPidsLimit         int64 = recommendedMinPidsLimit - 1
+		recommendedPidsLimit int64 = recommendedMinPidsLimit
+		binaryLogSizeMax           = resource.MustParse("50Mi")
+		decimalLogSizeMax          = resource.MustParse("50M")
+		negativeLogSizeMax         = resource.MustParse("-1")
+	)
+
+	tests := []struct {
+		name         string
+		cfg          *mcfgv1.ContainerRuntimeConfiguration
+		wantWarnings int
+	}{
+		{
+			name:         "nothing set",
+			cfg:          &mcfgv1.ContainerRuntimeConfiguration{},
+			wantWarnings: 0,
+		},
+		{
+			name:         "pidsLimit unlimited",
+			cfg:          &mcfgv1.ContainerRuntimeConfiguration{PidsLimit: &unlimitedPidsLimit},
+			wantWarnings: 0,
+		},
+		{
+			name:         "pidsLimit at the minimum",
+			cfg:          &mcfgv1.ContainerRuntimeConfiguration{PidsLimit: &minPids},
+			wantWarnings: 1,
+		},
+		{
+			name:         "pidsLimit below the recommended minimum",
+			cfg:          &mcfgv1.ContainerRuntimeConfiguration{PidsLimit: &lowPidsLimit},
+			wantWarnings: 1,
+		},
+		{
+			name:         "pidsLimit at the recommended minimum",
+			cfg:          &mcfgv1.ContainerRuntimeConfiguration{PidsLimit: &recommendedPidsLimit},
+			wantWarnings: 0,
+		},
+		{
+			name:         "logSizeMax with a binary suffix",
+			cfg:          &mcfgv1.ContainerRuntimeConfiguration{LogSizeMax: &binaryLogSizeMax},
+			wantWarnings: 0,
+		},
+		{
+			name:         "logSizeMax with a decimal suffix",
+			cfg:          &mcfgv1.ContainerRuntimeConfiguration{LogSizeMax: &decimalLogSizeMax},
+			wantWarnings: 1,
+		},
+		{
+			name:         "negative logSizeMax",
+			cfg:          &mcfgv1.ContainerRuntimeConfiguration{LogSizeMax: &negativeLogSizeMax},
+			wantWarnings: 1,
+		},
+	}
+
+	for _, test := range tests {
+		ctrcfg := newContainerRuntimeConfig(test.name, test.cfg, metav1.AddLabelToSelector(&metav1.LabelSelector{}, "", ""))
+		require.NoError(t, validateUserContainerRuntimeConfig(ctrcfg), test.name)
+		require.Len(t, getContainerRuntimeConfigWarnings(ctrcfg), test.wantWarnings, test.name)
+	}
+}
+
+func TestGetLogSizeMaxSuffixWarning(t *testing.T) {
+	tests := []struct {
+		logSizeMax string
+		want       string
+	}{
+		{logSizeMax: "50Mi", want: ""},
+		{logSizeMax: "52428800", want: ""},
+		{logSizeMax: "50M", want: "LogSizeMax 50M uses a decimal suffix and is 50000000 bytes, use 50Mi for 52428800 bytes if a binary size was intended"},
+		{logSizeMax: "10k", want: "LogSizeMax 10k uses a decimal suffix and is 10000 bytes, use 10Ki for 10240 bytes if a binary size was intended"},
+	}
+
+	for _, test := range tests {
+		logSizeMax := resource.MustParse(test.logSizeMax)
+		assert.Equal(t, test.want, getLogSizeMaxSuffixWarning(&logSizeMax), test.logSizeMax)
+	}
+	assert.Equal(t, "", getLogSizeMaxSuffixWarning(nil))
+}
+
+func TestCreateCRIODropinFilesLogSizeMaxBytes(t *testing.T) {
+	for logSizeMax, want := range map[string]int64{"50Mi": 52428800, "50M": 50000000} {
+		quantity := resource.MustParse(logSizeMax)
+		files := createCRIODropinFiles(newContainerRuntimeConfig(logSizeMax, &mcfgv1.ContainerRuntimeConfiguration{LogSizeMax: &quantity}, metav1.AddLabelToSelector(&metav1.LabelSelector{}, "", "")))
+		require.Len(t, files, 1, logSizeMax)
+		assert.Equal(t, crioDropInFilePathLogSizeMax, files[0].filePath, logSizeMax)
+
+		tomlConf := tomlConfigCRIOLogSizeMax{}
+		_, err := toml.Decode(string(files[0].data), &tomlConf)
+		require.NoError(t, err, logSizeMax)
+		assert.Equal(t, want, tomlConf.Crio.Runtime.LogSizeMax, logSizeMax)
 	}
 }
 
@@ -1254,20 +2253,7 @@ func TestUpdateStorageConfig(t *testing.T) {
 			cfg: &mcfgv1.ContainerRuntimeConfiguration{
 				OverlaySize: &validOverLaySize,
 			},
-			want: tomlConfigStorage{
-				Storage: struct {
-					Driver    string                                "toml:\"driver\""
-					RunRoot   string                                "toml:\"runroot\""
-					GraphRoot string                                "toml:\"graphroot\""
-					Options   struct{ storageconfig.OptionsConfig } "toml:\"options\""
-				}{
-					Options: struct{ storageconfig.OptionsConfig }{
-						storageconfig.OptionsConfig{
-							Size: "10G",
-						},
-					},
-				},
-			},
+			want: storageConfigWithSize("10G"),
 		},
 	}
 
@@ -1285,6 +2271,13 @@ func TestUpdateStorageConfig(t *testing.T) {
 	}
 }
 
+// storageConfigWithSize returns the storage.conf setting the given size in its options table
+func storageConfigWithSize(size string) tomlConfigStorage {
+	conf := tomlConfigStorage{}
+	conf.Storage.Options.Size = size
+	return conf
+}
+
 func TestGetValidScopePolicies(t *testing.T) {
 	type testcase struct {
 		name                   string
@@ -1493,7 +2486,7 @@ func TestGenerateSigstoreRegistriesConfig(t *testing.T) {
 
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
-			registriesTOML, err := updateRegistriesConfig(templateRegistriesConfig, nil, nil, tc.icspRules, tc.idmsRules, tc.itmsRules)
+			registriesTOML, err := updateRegistriesConfig(templateRegistriesConfig, nil, nil, registryMirrorRules{icsp: tc.icspRules, idms: tc.idmsRules, itms: tc.itmsRules})
 			require.NoError(t, err)
 			got, err := generateSigstoreRegistriesdConfig(tc.clusterScopePolicies, tc.scopeNamespacePolicies, registriesTOML)
 			require.NoError(t, err)
@@ -1587,6 +2580,21 @@ func TestGeneratePolicyJSON(t *testing.T) {
 
 }
 
+func TestWithoutBlockedScopes(t *testing.T) {
+	requirements := signature.PolicyRequirements{signature.NewPRInsecureAcceptAnything()}
+	clusterScopePolicies := map[string]signature.PolicyRequirements{
+		"quay.io/signed":      requirements,
+		"example.com/ns/repo": requirements,
+		"*.example.org":       requirements,
+	}
+
+	assert.Equal(t, clusterScopePolicies, withoutBlockedScopes(clusterScopePolicies, nil))
+	got := withoutBlockedScopes(clusterScopePolicies, []string{"quay.io", "*.example.org", "example.com/other"})
+	assert.Equal(t, map[string]signature.PolicyRequirements{"example.com/ns/repo": requirements}, got)
+	// The input is left untouched
+	assert.Len(t, clusterScopePolicies, 3)
+}
+
 func TestValidateClusterImagePolicyWithAllowedBlockedRegistries(t *testing.T) {
 	tests := []struct {
 		name                   string
@@ -1959,3 +2967,54 @@ func TestUpdateNamespacedPolicyJSONs(t *testing.T) {
 		require.JSONEq(t, string(expectRet[namespace]), string(v))
 	}
 }
+
+func TestDecodeGeneratedConfigFile(t *testing.T) {
+	cc := newControllerConfig(ctrlcommon.ControllerConfigName, apicfgv1.NonePlatformType)
+
+	t.Run("registries.conf", func(t *testing.T) {
+		ignCfg, err := registriesConfigIgnition(templateDir, cc, "worker", "amd64", "", []string{"insecure.example.com"}, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+		require.NoError(t, err)
+		mc, err := ctrlcommon.MachineConfigFromIgnConfig("worker", "99-worker-generated-registries", ignCfg)
+		require.NoError(t, err)
+
+		data, err := DecodeGeneratedConfigFile(mc, registriesConfigPath)
+		require.NoError(t, err)
+		registries := requireRegistriesConfAcceptedByCRIO(t, data)
+		require.Len(t, registries, 1)
+		assert.Equal(t, "insecure.example.com", registries[0].Location)
+		assert.True(t, registries[0].Insecure)
+	})
+
+	t.Run("storage.conf", func(t *testing.T) {
+		overlaySize := resource.MustParse("9G")
+		ctrcfg := newContainerRuntimeConfig("storage", &mcfgv1.ContainerRuntimeConfiguration{OverlaySize: &overlaySize}, metav1.AddLabelToSelector(&metav1.LabelSelector{}, "", ""))
+		originalStorageIgn, _, err := generateOriginalStorageAndCRIOConfigs(templateDir, cc, "worker")
+		require.NoError(t, err)
+		configFileList, err := generateContainerRuntimeConfigFiles(originalStorageIgn, ctrcfg, nil)
+		require.NoError(t, err)
+		ignCfg, err := createNewIgnition(configFileList)
+		require.NoError(t, err)
+		mc, err := ctrlcommon.MachineConfigFromIgnConfig("worker", "99-worker-generated-containerruntime", ignCfg)
+		require.NoError(t, err)
+
+		data, err := DecodeGeneratedConfigFile(mc, storageConfigPath)
+		require.NoError(t, err)
+		tomlConf := tomlConfigStorage{}
+		_, err = toml.Decode(string(data), &tomlConf)
+		require.NoError(t, err)
+		assert.Equal(t, "9G", tomlConf.Storage.Options.Size)
+
+		// The MachineConfig does not write registries.conf
+		_, err = DecodeGeneratedConfigFile(mc, registriesConfigPath)
+		assert.ErrorContains(t, err, registriesConfigPath)
+	})
+}
+
+func TestGetInsecureRegistriesNotAllowed(t *testing.T) {
+	insecure := []string{"insecure.example.com", "ns.example.com/insecure", "*.wildcard.example.com", "partial.example.com"}
+
+	assert.Empty(t, getInsecureRegistriesNotAllowed(insecure, nil), "every registry is allowed without an allowed list")
+	assert.Empty(t, getInsecureRegistriesNotAllowed(insecure, []string{"insecure.example.com", "ns.example.com", "*.example.com"}))
+	assert.Equal(t, []string{"insecure.example.com", "*.wildcard.example.com"},
+		getInsecureRegistriesNotAllowed(insecure, []string{"ns.example.com/insecure", "partial.example.com/ns"}))
+}